@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	coreHttp "github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+type (
+	// LogLevel is both the request and response body of the log level endpoints
+	LogLevel struct {
+		Level string `json:"level"`
+	}
+	// ResetRequest is the request body of the ResetMetrics endpoint
+	ResetRequest struct {
+		Route string `query:"route"`
+	}
+)
+
+var (
+	// Config handles GET /admin/config
+	Config = coreHttp.Get("/admin/config", configDump).Admin()
+	// GetLogLevel handles GET /admin/log/level
+	GetLogLevel = coreHttp.Get("/admin/log/level", getLogLevel).Admin()
+	// SetLogLevel handles PUT /admin/log/level
+	SetLogLevel = coreHttp.Put("/admin/log/level", setLogLevel).Admin()
+	// ResetMetrics handles DELETE /admin/metrics/request
+	ResetMetrics = coreHttp.Delete("/admin/metrics/request", resetMetrics).Admin()
+	// Prometheus handles GET /metrics
+	Prometheus = coreHttp.EchoHandler("/metrics", http.MethodGet, prometheus).Admin()
+)
+
+// Handlers returns every operational endpoint.
+// Profiling endpoints are only returned if enabled through configuration, and if the admin listener is set.
+func Handlers() []coreHttp.Handler {
+	handlers := []coreHttp.Handler{
+		Config,
+		GetLogLevel,
+		SetLogLevel,
+		ResetMetrics,
+		Prometheus,
+	}
+	if config.Config.ProfilingEnabled {
+		if config.Config.AdminEnabled() {
+			handlers = append(handlers, profilingHandlers()...)
+		} else {
+			logger.New().Warn("profiling endpoints require an admin listener, they have been disabled")
+		}
+	}
+	return handlers
+}
+
+func configDump(_ context.Context, _ coreHttp.Empty) (*config.Manifest, error) {
+	manifest := config.Config.Redacted()
+	return &manifest, nil
+}
+
+func getLogLevel(_ context.Context, _ coreHttp.Empty) (*LogLevel, error) {
+	return &LogLevel{Level: logger.Level().String()}, nil
+}
+
+func setLogLevel(_ context.Context, request LogLevel) (*LogLevel, error) {
+	lvl, err := zapcore.ParseLevel(request.Level)
+	if err != nil {
+		return nil, errors.BadRequest(err, "invalid log level '%s'", request.Level)
+	}
+	logger.SetLevel(lvl)
+	return &LogLevel{Level: lvl.String()}, nil
+}
+
+func resetMetrics(_ context.Context, request ResetRequest) (*coreHttp.Empty, error) {
+	var routes []string
+	if request.Route != "" {
+		routes = append(routes, request.Route)
+	}
+	if err := metrics.Reset(routes...); err != nil {
+		return nil, err
+	}
+	return &coreHttp.Empty{}, nil
+}
+
+func prometheus(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, metrics.PrometheusContentType)
+	c.Response().WriteHeader(http.StatusOK)
+	return metrics.WritePrometheus(c.Response())
+}
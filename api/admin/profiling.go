@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	coreHttp "github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/profiling"
+)
+
+type (
+	// CaptureRequest is the request body of the CaptureProfile endpoint
+	CaptureRequest struct {
+		Kind string `json:"kind"`
+		// Duration of a cpu profile, using golang duration notation such as "30s"
+		Duration string `json:"duration"`
+	}
+	// CaptureResponse is returned by the CaptureProfile endpoint, while the capture runs in the background
+	CaptureResponse struct {
+		profiling.Profile
+	}
+	// ProfilesResponse is returned by the ListProfiles endpoint
+	ProfilesResponse []profiling.Profile
+)
+
+var (
+	// Pprof handles GET /debug/pprof/*, exposing net/http/pprof handlers
+	Pprof = coreHttp.EchoHandler("/debug/pprof/*", http.MethodGet, pprofHandler).
+		Admin().
+		WithTimeout(-1).
+		WithoutCompression()
+	// Goroutines handles GET /admin/debug/goroutines, dumping every goroutine stack
+	Goroutines = coreHttp.EchoHandler("/admin/debug/goroutines", http.MethodGet, goroutines).Admin()
+	// CaptureProfile handles POST /admin/profiles
+	CaptureProfile = coreHttp.Post("/admin/profiles", captureProfile).Admin()
+	// ListProfiles handles GET /admin/profiles
+	ListProfiles = coreHttp.Get("/admin/profiles", listProfiles).Admin()
+)
+
+func profilingHandlers() []coreHttp.Handler {
+	return []coreHttp.Handler{
+		Pprof,
+		Goroutines,
+		CaptureProfile,
+		ListProfiles,
+	}
+}
+
+func pprofHandler(c echo.Context) error {
+	w, r := c.Response(), c.Request()
+	profile := c.Param("*")
+	if profile == "profile" || profile == "trace" {
+		if seconds := c.QueryParam("seconds"); seconds != "" {
+			duration, err := time.ParseDuration(seconds + "s")
+			if err != nil {
+				return errors.BadRequest(err, "invalid duration '%s'", seconds)
+			}
+			if duration <= 0 || duration > config.Config.ProfilingMaxDuration {
+				return errors.BadRequest(nil, "profile duration must be within ]0, %s]", config.Config.ProfilingMaxDuration)
+			}
+		}
+	}
+	switch profile {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Index(w, r)
+	}
+	return nil
+}
+
+func goroutines(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return profiling.WriteGoroutines(c.Response())
+}
+
+func captureProfile(_ context.Context, request CaptureRequest) (*CaptureResponse, error) {
+	var duration time.Duration
+	if request.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(request.Duration); err != nil {
+			return nil, errors.BadRequest(err, "invalid duration '%s'", request.Duration)
+		}
+	}
+
+	profile, _, err := profiling.Capture(profiling.Kind(request.Kind), duration)
+	if err != nil {
+		return nil, err
+	}
+	return &CaptureResponse{Profile: profile}, nil
+}
+
+func listProfiles(ctx context.Context, _ coreHttp.Empty) (*ProfilesResponse, error) {
+	profiles, err := profiling.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := ProfilesResponse(profiles)
+	return &response, nil
+}
+
+// StatusCode returns 202, as the capture completes in the background
+func (CaptureResponse) StatusCode() int {
+	return http.StatusAccepted
+}
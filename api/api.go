@@ -1,19 +1,36 @@
 package api
 
 import (
+	"github.com/Raphy42/industrial-fizz-buzz/api/admin"
 	"github.com/Raphy42/industrial-fizz-buzz/api/fizzbuzz"
 	"github.com/Raphy42/industrial-fizz-buzz/api/health"
 	"github.com/Raphy42/industrial-fizz-buzz/api/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/api/quota"
+	"github.com/Raphy42/industrial-fizz-buzz/api/webhooks"
 
 	"github.com/Raphy42/industrial-fizz-buzz/core/http"
 )
 
 // Handlers returns a list of http.Handler ready to be used through `server.New()`
+// Operational endpoints are flagged as admin handlers, and served by the admin listener when configured.
 func Handlers() []http.Handler {
-	return []http.Handler{
+	handlers := []http.Handler{
 		health.Handler,
+		health.Liveness,
+		health.Readiness,
 		fizzbuzz.FizzBuzz,
+		fizzbuzz.FizzBuzzRange,
+		fizzbuzz.FizzBuzzRules,
+		fizzbuzz.FizzBuzzBatch,
+		fizzbuzz.FizzBuzzStream,
+		fizzbuzz.FizzBuzzSession,
 		metrics.FizzBuzzMetrics,
 		metrics.AllMetrics,
+		metrics.TopRequestFeed,
+		quota.Quota,
 	}
+	handlers = append(handlers, fizzbuzz.RulesetHandlers()...)
+	handlers = append(handlers, fizzbuzz.JobHandlers()...)
+	handlers = append(handlers, webhooks.Handlers()...)
+	return append(handlers, admin.Handlers()...)
 }
@@ -0,0 +1,121 @@
+package fizzbuzz
+
+import (
+	"context"
+	nethttp "net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+type (
+	// BatchItem is a single request of a batch, with the same semantics as the FizzBuzz endpoint query parameters
+	BatchItem struct {
+		Int1    int64  `json:"int1"`
+		Int2    int64  `json:"int2"`
+		Limit   int64  `json:"limit" minimum:"0"`
+		Str1    string `json:"str1"`
+		Str2    string `json:"str2"`
+		Ruleset string `json:"ruleset,omitempty"`
+		Version int    `json:"version,omitempty" minimum:"0"`
+	}
+	// BatchRequest is the request body for the FizzBuzzBatch endpoint
+	BatchRequest struct {
+		Requests []BatchItem `json:"requests" required:"true" minItems:"1"`
+	}
+	// BatchResult is the outcome of a single request of a batch, either Items or Error being set
+	BatchResult struct {
+		Status int       `json:"status"`
+		Items  *Response `json:"items,omitempty"`
+		Error  string    `json:"error,omitempty"`
+	}
+	// BatchResponse is returned by the FizzBuzzBatch endpoint, results being in the same order as the requests
+	BatchResponse struct {
+		Succeeded int           `json:"succeeded"`
+		Failed    int           `json:"failed"`
+		Results   []BatchResult `json:"results"`
+	}
+)
+
+var (
+	// FizzBuzzBatch handles POST /api/v1/fizzbuzz/batch
+	FizzBuzzBatch = http.Post("/api/v1/fizzbuzz/batch", fizzBuzzBatch).WithScopes("fizzbuzz:read")
+)
+
+func (i BatchItem) request() Request {
+	return Request{
+		Int1:    i.Int1,
+		Int2:    i.Int2,
+		Limit:   i.Limit,
+		Str1:    i.Str1,
+		Str2:    i.Str2,
+		Ruleset: i.Ruleset,
+		Version: i.Version,
+	}
+}
+
+// Cost is the sum of the cost of every request of the batch.
+// This is used by http.GenericHandler for admission control, before the request is even validated.
+func (r BatchRequest) Cost() uint64 {
+	var cost uint64
+	for _, item := range r.Requests {
+		cost += item.request().Cost()
+	}
+	return cost
+}
+
+// batchResult converts the outcome of a single request, only *errors.Error messages are returned to the client
+func batchResult(ctx context.Context, index int, response *Response, err error) BatchResult {
+	if err == nil {
+		return BatchResult{Status: nethttp.StatusOK, Items: response}
+	}
+	if httpErr, ok := err.(*errors.Error); ok {
+		return BatchResult{Status: httpErr.HttpCode, Error: httpErr.Message}
+	}
+	logger.FromContext(ctx).Error("batch request error", zap.Int("batch.index", index), zap.Error(err))
+	return BatchResult{Status: nethttp.StatusInternalServerError, Error: "internal server error"}
+}
+
+func fizzBuzzBatch(ctx context.Context, request BatchRequest) (*BatchResponse, error) {
+	log := logger.FromContext(ctx)
+
+	log.Debug("new fizzbuzz batch request", zap.Int("requests", len(request.Requests)))
+
+	if len(request.Requests) == 0 {
+		return nil, errors.BadRequest(nil, "at least one request is required")
+	}
+	if len(request.Requests) > config.Config.MaxBatchSize {
+		return nil, errors.BadRequest(nil, "`requests` cannot have more than %d entries", config.Config.MaxBatchSize)
+	}
+	// the budget is checked up front, so that a batch is either rejected as a whole or fully processed
+	var items int64
+	for _, item := range request.Requests {
+		if item.Limit > 0 {
+			items += item.Limit
+		}
+		if items > config.Config.MaxBatchItems {
+			return nil, errors.BadRequest(nil, "the sum of `limit` of every request cannot exceed %d", config.Config.MaxBatchItems)
+		}
+	}
+
+	response := &BatchResponse{Results: make([]BatchResult, len(request.Requests))}
+	for i, item := range request.Requests {
+		itemRequest := item.request()
+		if err := fizzBuzzRequests.Count(ctx, itemRequest); err != nil {
+			log.Warn("could not count batch request", zap.Int("batch.index", i), zap.Error(err))
+		}
+
+		results, err := fizzBuzz(ctx, itemRequest)
+		response.Results[i] = batchResult(ctx, i, results, err)
+		if err != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+	return response, nil
+}
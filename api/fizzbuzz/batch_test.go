@@ -0,0 +1,53 @@
+package fizzbuzz
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+)
+
+func TestBatch(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	metrics.StartAggregator(ctx)
+	defer func() { a.NoError(metrics.StopAggregator(ctx)) }()
+	a.NoError(metrics.Reset(fizzBuzzRoute))
+
+	response, err := fizzBuzzBatch(ctx, BatchRequest{Requests: []BatchItem{
+		{Int1: 3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"},
+		{Int1: -1, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"},
+		{Int1: 3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"},
+	}})
+	if a.NoError(err) {
+		a.Equal(2, response.Succeeded)
+		a.Equal(1, response.Failed)
+		if a.Len(response.Results, 3) {
+			a.Equal(http.StatusOK, response.Results[0].Status)
+			a.Equal(&Response{"1", "2", "fizz", "4", "buzz"}, response.Results[0].Items)
+			a.Equal(http.StatusBadRequest, response.Results[1].Status)
+			a.Nil(response.Results[1].Items)
+			a.NotEmpty(response.Results[1].Error)
+		}
+	}
+
+	// every item is counted as a request of the fizzbuzz endpoint
+	a.Eventually(func() bool {
+		stats := metrics.Stats()[fizzBuzzRoute]
+		return stats.Requests == 3 && stats.Distinct == 2 && stats.TopHits == 2
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = fizzBuzzBatch(ctx, BatchRequest{})
+	a.Equal(http.StatusBadRequest, statusOf(err))
+	_, err = fizzBuzzBatch(ctx, BatchRequest{Requests: []BatchItem{
+		{Int1: 3, Int2: 5, Limit: config.Config.MaxBatchItems, Str1: "fizz", Str2: "buzz"},
+		{Int1: 3, Int2: 5, Limit: 1, Str1: "fizz", Str2: "buzz"},
+	}})
+	a.Equal(http.StatusBadRequest, statusOf(err), "the item budget is shared by every request")
+}
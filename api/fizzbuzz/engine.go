@@ -0,0 +1,269 @@
+package fizzbuzz
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+// maxTemplatePeriod is the longest period for which a template is precomputed, longer periods fall back to modulo
+// arithmetic
+const maxTemplatePeriod = 1 << 16
+
+// kind identifies an item, either a number or one of the words
+type kind uint8
+
+const (
+	kindNumber kind = 0
+	kindStr1   kind = 1
+	kindStr2   kind = 2
+	kindBoth        = kindStr1 | kindStr2
+)
+
+type (
+	// engine generates fizzbuzz sequences.
+	// Multiples of Int1*Int2 are replaced by Str1+Str2, other multiples of Int1 by Str1, and other multiples of Int2
+	// by Str2. Words repeat with period Int1*Int2, so their kinds are precomputed once in a template, while numbers
+	// are formatted into a single buffer shared by every item.
+	// Numbers are int64, unless the engine has an origin, in which case numbers are origin + n, n being an int64.
+	engine struct {
+		// both is Int1*Int2, or 0 if it overflows, as no int64 number is then a multiple of it
+		int1, int2, both int64
+		words            [4]string
+		period           int64
+		template         []kind
+		origin           *big.Int
+		big1, big2       *big.Int
+		bigBoth          *big.Int
+	}
+	// cursor iterates over the kinds of consecutive numbers
+	cursor struct {
+		engine                      *engine
+		number                      int64
+		offset                      int64
+		residue1, residue2, residue *big.Int
+	}
+	// counter is an ASCII decimal counter, incremented in amortized constant time
+	counter struct {
+		buf   []byte
+		start int
+	}
+)
+
+var bigOne = big.NewInt(1)
+
+func words(str1, str2 string) [4]string {
+	return [4]string{kindStr1: str1, kindStr2: str2, kindBoth: str1 + str2}
+}
+
+// newEngine precomputes the template of a validated request.
+func newEngine(request Request) *engine {
+	e := &engine{
+		int1:  request.Int1,
+		int2:  request.Int2,
+		words: words(request.Str1, request.Str2),
+	}
+	if request.Int1 <= math.MaxInt64/request.Int2 {
+		e.both = request.Int1 * request.Int2
+	}
+
+	// larger periods are not worth a template
+	if e.both == 0 || e.both > maxTemplatePeriod {
+		return e
+	}
+	e.period = e.both
+
+	// numbers never exceed the limit, so neither do template offsets
+	size := e.period
+	if request.Limit < e.period {
+		size = request.Limit + 1
+	}
+	e.template = make([]kind, size)
+	for offset := range e.template {
+		e.template[offset] = e.kindOf(int64(offset))
+	}
+	return e
+}
+
+// newBigEngine precomputes the template of a validated big integer request, generating numbers from start.
+func newBigEngine(request bigRequest) *engine {
+	e := &engine{
+		origin:  new(big.Int).Sub(request.start, bigOne),
+		big1:    request.int1,
+		big2:    request.int2,
+		bigBoth: new(big.Int).Mul(request.int1, request.int2),
+		words:   words(request.str1, request.str2),
+	}
+
+	if !e.bigBoth.IsInt64() || e.bigBoth.Int64() > maxTemplatePeriod {
+		return e
+	}
+	// both divisors are smaller than their product
+	e.int1, e.int2, e.both = request.int1.Int64(), request.int2.Int64(), e.bigBoth.Int64()
+	e.period = e.both
+	e.template = make([]kind, e.period)
+	for offset := range e.template {
+		e.template[offset] = e.kindOf(int64(offset))
+	}
+	return e
+}
+
+// kindOf computes the kind of a number using modulo arithmetic
+func (e *engine) kindOf(number int64) kind {
+	switch {
+	case e.both != 0 && number%e.both == 0:
+		return kindBoth
+	case number%e.int1 == 0:
+		return kindStr1
+	case number%e.int2 == 0:
+		return kindStr2
+	default:
+		return kindNumber
+	}
+}
+
+// decimal formats a number
+func (e *engine) decimal(number int64) string {
+	if e.origin == nil {
+		return strconv.FormatInt(number, 10)
+	}
+	return new(big.Int).Add(e.origin, big.NewInt(number)).String()
+}
+
+func (e *engine) cursor(first int64) cursor {
+	c := cursor{engine: e, number: first}
+	if e.origin == nil {
+		if e.template != nil {
+			c.offset = first % e.period
+		}
+		return c
+	}
+
+	number := new(big.Int).Add(e.origin, big.NewInt(first))
+	if e.template != nil {
+		c.offset = new(big.Int).Mod(number, big.NewInt(e.period)).Int64()
+		return c
+	}
+	c.residue1 = new(big.Int).Mod(number, e.big1)
+	c.residue2 = new(big.Int).Mod(number, e.big2)
+	c.residue = new(big.Int).Mod(number, e.bigBoth)
+	return c
+}
+
+func (c *cursor) next() kind {
+	e := c.engine
+	if e.template != nil {
+		k := e.template[c.offset]
+		if c.offset++; c.offset == e.period {
+			c.offset = 0
+		}
+		return k
+	}
+	if e.origin != nil {
+		return c.nextBig()
+	}
+	c.number++
+	return e.kindOf(c.number - 1)
+}
+
+// nextBig tracks the residues of big integer numbers, as divisions are much more expensive than additions
+func (c *cursor) nextBig() kind {
+	k := kindNumber
+	switch {
+	case c.residue.Sign() == 0:
+		k = kindBoth
+	case c.residue1.Sign() == 0:
+		k = kindStr1
+	case c.residue2.Sign() == 0:
+		k = kindStr2
+	}
+	if c.residue1.Add(c.residue1, bigOne).Cmp(c.engine.big1) == 0 {
+		c.residue1.SetInt64(0)
+	}
+	if c.residue2.Add(c.residue2, bigOne).Cmp(c.engine.big2) == 0 {
+		c.residue2.SetInt64(0)
+	}
+	if c.residue.Add(c.residue, bigOne).Cmp(c.engine.bigBoth) == 0 {
+		c.residue.SetInt64(0)
+	}
+	return k
+}
+
+// fill generates the items starting at number first into dst, checking ctx every cancellationInterval items.
+func (e *engine) fill(ctx context.Context, dst []string, first int64) error {
+	if len(dst) == 0 {
+		return nil
+	}
+
+	// numbers are formatted back to back in a single buffer, sized for the worst case
+	start := e.decimal(first)
+	last := e.decimal(first + int64(len(dst)) - 1)
+	var buf strings.Builder
+	buf.Grow(len(dst) * len(last))
+	var digits counter
+	digits.set(start, len(last))
+	kinds := e.cursor(first)
+	for i := range dst {
+		if i%cancellationInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return errors.Unavailable(err, "fizzbuzz generation aborted after %d items", first+int64(i)-1)
+			}
+		}
+		if k := kinds.next(); k == kindNumber {
+			buf.Write(digits.bytes())
+		} else {
+			dst[i] = e.words[k]
+		}
+		digits.increment()
+	}
+
+	// numbers are substrings of a single string, instead of one allocation per number
+	numbers := buf.String()
+	pos := 0
+	digits.set(start, len(last))
+	kinds = e.cursor(first)
+	for i := range dst {
+		if kinds.next() == kindNumber {
+			length := digits.len()
+			dst[i] = numbers[pos : pos+length]
+			pos += length
+		}
+		digits.increment()
+	}
+	return nil
+}
+
+// set resets the counter to a decimal number, with enough room to grow up to the given number of digits
+func (c *counter) set(decimal string, width int) {
+	// the last increment can overflow the last number
+	width++
+	if len(c.buf) < width {
+		c.buf = make([]byte, width)
+	}
+	c.start = len(c.buf) - len(decimal)
+	copy(c.buf[c.start:], decimal)
+}
+
+func (c *counter) increment() {
+	for i := len(c.buf) - 1; i >= c.start; i-- {
+		if c.buf[i] != '9' {
+			c.buf[i]++
+			return
+		}
+		c.buf[i] = '0'
+	}
+	c.start--
+	c.buf[c.start] = '1'
+}
+
+func (c *counter) bytes() []byte {
+	return c.buf[c.start:]
+}
+
+func (c *counter) len() int {
+	return len(c.buf) - c.start
+}
@@ -0,0 +1,175 @@
+package fizzbuzz
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reference is the straightforward fizzbuzz implementation the engine is checked against
+func reference(request Request) Response {
+	results := make(Response, request.Limit)
+	for number := int64(1); number <= request.Limit; number++ {
+		switch {
+		case number%(request.Int1*request.Int2) == 0:
+			results[number-1] = request.Str1 + request.Str2
+		case number%request.Int1 == 0:
+			results[number-1] = request.Str1
+		case number%request.Int2 == 0:
+			results[number-1] = request.Str2
+		default:
+			results[number-1] = strconv.FormatInt(number, 10)
+		}
+	}
+	return results
+}
+
+func TestEngine(t *testing.T) {
+	a := assert.New(t)
+
+	requests := []Request{
+		{Int1: 3, Int2: 5, Limit: 100, Str1: "fizz", Str2: "buzz"},
+		{Int1: 2, Int2: 4, Limit: 100, Str1: "fizz", Str2: "buzz"},
+		{Int1: 1, Int2: 1, Limit: 10, Str1: "fizz", Str2: "buzz"},
+		{Int1: 7, Int2: 11, Limit: 10, Str1: "fizz", Str2: "buzz"},
+		{Int1: 3, Int2: 5, Limit: 1000, Str1: "", Str2: ""},
+		{Int1: 65537, Int2: 65539, Limit: 200000, Str1: "fizz", Str2: "buzz"},
+		{Int1: 6, Int2: 4, Limit: 100, Str1: "fizz", Str2: "buzz"},
+	}
+	for _, request := range requests {
+		name := fmt.Sprintf("%+v", request)
+		results := make(Response, request.Limit)
+		if a.NoError(newEngine(request).fill(context.Background(), results, 1), name) {
+			a.Equal(reference(request), results, name)
+		}
+
+		// windows must match the complete sequence
+		window := make(Response, request.Limit/3)
+		if a.NoError(newEngine(request).fill(context.Background(), window, request.Limit/2), name) {
+			a.Equal(results[request.Limit/2-1:request.Limit/2-1+int64(len(window))], window, name)
+		}
+	}
+}
+
+func TestEngineCommonDivisors(t *testing.T) {
+	a := assert.New(t)
+	request := Request{Int1: 2, Int2: 4, Limit: 8, Str1: "fizz", Str2: "buzz"}
+
+	// only multiples of int1*int2 are replaced by both words, even if int1 and int2 are not coprime
+	results := make(Response, request.Limit)
+	if a.NoError(newEngine(request).fill(context.Background(), results, 1)) {
+		a.Equal(Response{"1", "fizz", "3", "fizz", "5", "fizz", "7", "fizzbuzz"}, results)
+	}
+	rangeRequest, err := RangeRequest{Int1: "2", Int2: "4", Start: "1", End: "8", Str1: "fizz", Str2: "buzz"}.parse()
+	if a.NoError(err) {
+		bigResults := make(Response, 8)
+		if a.NoError(newBigEngine(rangeRequest).fill(context.Background(), bigResults, 1)) {
+			a.Equal(results, bigResults)
+		}
+	}
+}
+
+// bigReference is the straightforward arbitrary precision fizzbuzz implementation
+func bigReference(request bigRequest) Response {
+	var results Response
+	zero := new(big.Int)
+	for number := new(big.Int).Set(request.start); number.Cmp(request.end) <= 0; number.Add(number, bigOne) {
+		divisible1 := new(big.Int).Mod(number, request.int1).Cmp(zero) == 0
+		divisible2 := new(big.Int).Mod(number, request.int2).Cmp(zero) == 0
+		divisibleBoth := new(big.Int).Mod(number, new(big.Int).Mul(request.int1, request.int2)).Cmp(zero) == 0
+		switch {
+		case divisibleBoth:
+			results = append(results, request.str1+request.str2)
+		case divisible1:
+			results = append(results, request.str1)
+		case divisible2:
+			results = append(results, request.str2)
+		default:
+			results = append(results, number.String())
+		}
+	}
+	return results
+}
+
+func TestBigEngine(t *testing.T) {
+	a := assert.New(t)
+
+	requests := []RangeRequest{
+		{Int1: "3", Int2: "5", Start: "1", End: "100", Str1: "fizz", Str2: "buzz"},
+		{Int1: "3", Int2: "5", Start: "999999999999999999999999999990", End: "1000000000000000000000000000010", Str1: "fizz", Str2: "buzz"},
+		{Int1: "9223372036854775783", Int2: "9223372036854775643", Start: "9223372036854775000", End: "9223372036854776000", Str1: "fizz", Str2: "buzz"},
+		{Int1: "100000000000000000000000000007", Int2: "7", Start: "99999999999999999999999999990", End: "100000000000000000000000000030", Str1: "fizz", Str2: "buzz"},
+		{Int1: "6", Int2: "4", Start: "999999999999999999999999999990", End: "1000000000000000000000000000100", Str1: "fizz", Str2: "buzz"},
+		{Int1: "600000000000000000000", Int2: "4", Start: "2399999999999999999990", End: "2400000000000000000010", Str1: "fizz", Str2: "buzz"},
+	}
+	for _, rangeRequest := range requests {
+		name := fmt.Sprintf("%+v", rangeRequest)
+		request, err := rangeRequest.parse()
+		if !a.NoError(err, name) {
+			continue
+		}
+		results := make(Response, request.count().Int64())
+		if a.NoError(newWorkerPool(4, 4, 7).fill(context.Background(), newBigEngine(request), results, 1), name) {
+			a.Equal(bigReference(request), results, name)
+		}
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	a := assert.New(t)
+	request := Request{Int1: 3, Int2: 7, Limit: 100_000, Str1: "fizz", Str2: "buzz"}
+	pool := newWorkerPool(4, 3, 1000)
+
+	results := make(Response, request.Limit)
+	if a.NoError(pool.fill(context.Background(), newEngine(request), results, 1)) {
+		a.Equal(reference(request), results)
+	}
+	a.Len(pool.slots, 0, "workers have not been released")
+
+	// sequences can be generated from any number, such as the chunks of a job
+	if a.NoError(pool.fill(context.Background(), newEngine(request), results[:5000], 95_001)) {
+		a.Equal(reference(request)[95_000:], results[:5000])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.Error(pool.fill(ctx, newEngine(request), make(Response, 10*cancellationInterval), 1))
+	a.Len(pool.slots, 0, "workers have not been released")
+}
+
+func BenchmarkEngine(b *testing.B) {
+	request := Request{Int1: 3, Int2: 5, Str1: "fizz", Str2: "buzz"}
+	for limit := int64(10); limit <= 10_000_000; limit *= 10 {
+		request.Limit = limit
+		b.Run(fmt.Sprintf("reference/limit=%d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				reference(request)
+			}
+			b.ReportMetric(float64(int64(b.N)*limit)/b.Elapsed().Seconds(), "items/s")
+		})
+		b.Run(fmt.Sprintf("engine/limit=%d", limit), func(b *testing.B) {
+			ctx := context.Background()
+			for i := 0; i < b.N; i++ {
+				results := make(Response, limit)
+				if err := newEngine(request).fill(ctx, results, 1); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(int64(b.N)*limit)/b.Elapsed().Seconds(), "items/s")
+		})
+		b.Run(fmt.Sprintf("parallel/limit=%d", limit), func(b *testing.B) {
+			ctx := context.Background()
+			for i := 0; i < b.N; i++ {
+				results := make(Response, limit)
+				if err := generationPool.fill(ctx, newEngine(request), results, 1); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(int64(b.N)*limit)/b.Elapsed().Seconds(), "items/s")
+		})
+	}
+}
@@ -10,35 +10,98 @@ import (
 	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
 	"github.com/Raphy42/industrial-fizz-buzz/core/http"
 	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/quota"
 )
 
 type (
-	// Request is the request body for the FizzBuzz endpoint
+	// Request is the request body for the FizzBuzz endpoint.
+	// Numbers are replaced according to a saved Ruleset instead of Int1, Int2, Str1 and Str2 if Ruleset is set.
 	Request struct {
-		Int1  int    `query:"int1"`
-		Int2  int    `query:"int2"`
-		Limit int    `query:"limit"`
-		Str1  string `query:"str1"`
-		Str2  string `query:"str2"`
+		Int1    int64  `query:"int1"`
+		Int2    int64  `query:"int2"`
+		Limit   int64  `query:"limit"`
+		Str1    string `query:"str1"`
+		Str2    string `query:"str2"`
+		Ruleset string `query:"ruleset" json:",omitempty" description:"Identifier of a saved rule set"`
+		Version int    `query:"version" json:",omitempty" description:"Version of the rule set, the latest one if omitted"`
 	}
 	// Response returned by the FizzBuzz endpoint
 	Response []string
 )
 
-// FizzBuzz handles GET /api/v1/fizzbuzz
-var FizzBuzz = http.Get("/api/v1/fizzbuzz", fizzBuzz)
-
-func fizzBuzzImpl(number int, request Request) string {
-	switch 0 {
-	case number % (request.Int1 * request.Int2):
-		return request.Str1 + request.Str2
-	case number % request.Int1:
-		return request.Str1
-	case number % request.Int2:
-		return request.Str2
-	default:
-		return strconv.FormatInt(int64(number), 10)
+// Cost estimates the response size, the number of items times the length of the longest possible item.
+// This is used by http.GenericHandler for admission control, before the request is even validated.
+func (r Request) Cost() uint64 {
+	if r.Limit <= 0 {
+		return 0
 	}
+	itemLength := len(r.Str1) + len(r.Str2)
+	if digits := len(strconv.FormatInt(r.Limit, 10)); digits > itemLength {
+		itemLength = digits
+	}
+	return uint64(r.Limit) * uint64(itemLength)
+}
+
+// Cacheable reports whether the response only depends on the request, which is not the case of saved rule sets as
+// they can be updated or deleted.
+func (r Request) Cacheable() bool {
+	return r.Ruleset == ""
+}
+
+const (
+	// fizzBuzzRoute is the route of the FizzBuzz endpoint, whose metrics also count batched requests
+	fizzBuzzRoute = "/api/v1/fizzbuzz"
+	// cancellationInterval is the number of items generated between two context cancellation checks
+	cancellationInterval = 4096
+)
+
+var (
+	// FizzBuzz handles GET /api/v1/fizzbuzz
+	FizzBuzz = http.Get(fizzBuzzRoute, fizzBuzz).
+			WithScopes("fizzbuzz:read").
+			WithCache(0).
+			WithRequestETag().
+			WithCacheControl("public, max-age=3600")
+	// fizzBuzzRequests counts batched requests in the metrics of the FizzBuzz endpoint
+	fizzBuzzRequests = http.NewRequestCounter(fizzBuzzRoute)
+	// ItemsQuota is the daily number of items each client can generate
+	ItemsQuota = quota.New("fizzbuzz.items", config.Config.DailyItemQuota, config.Config.DailyItemQuotas)
+)
+
+func validateWords(str1, str2 string) error {
+	if !config.Config.AllowEmptyStr && (str1 == "" || str2 == "") {
+		msg := "both `str1` and `str2` query parameters must be set, empty words have been disallowed through configuration"
+		return errors.BadRequest(nil, msg)
+	}
+	return nil
+}
+
+// validate checks a Request whose limit cannot exceed maxItems, saved rule sets being checked once resolved
+func (r Request) validate(maxItems int64) error {
+	if r.Limit < 0 {
+		return errors.BadRequest(nil, "`limit` query parameter cannot be negative")
+	}
+	if r.Limit > maxItems {
+		return errors.BadRequest(nil, "`limit` query parameter cannot exceed %d", maxItems)
+	}
+	if r.Ruleset != "" {
+		if r.Int1 != 0 || r.Int2 != 0 || r.Str1 != "" || r.Str2 != "" {
+			return errors.BadRequest(nil, "`ruleset` query parameter cannot be combined with `int1`, `int2`, `str1` or `str2`")
+		}
+		return nil
+	}
+	if r.Version != 0 {
+		return errors.BadRequest(nil, "`version` query parameter requires a `ruleset`")
+	}
+	if r.Int1 <= 0 || r.Int2 <= 0 {
+		return errors.BadRequest(nil, "both `int1` and `int2` query parameters must be valid positive non-zero integer")
+	}
+	return validateWords(r.Str1, r.Str2)
+}
+
+// Charge consumes the ItemsQuota of the client, whether the response is generated or cached
+func (r Request) Charge(ctx context.Context) error {
+	return ItemsQuota.Consume(ctx, uint64(r.Limit))
 }
 
 func fizzBuzz(ctx context.Context, request Request) (*Response, error) {
@@ -46,26 +109,33 @@ func fizzBuzz(ctx context.Context, request Request) (*Response, error) {
 
 	log.Debug("new fizzbuzz request",
 		zap.Strings("words", []string{request.Str1, request.Str2}),
-		zap.Ints("ints", []int{request.Int1, request.Int2}),
-		zap.Int("limit", request.Limit),
+		zap.Int64s("ints", []int64{request.Int1, request.Int2}),
+		zap.Int64("limit", request.Limit),
+		zap.String("ruleset", request.Ruleset),
 	)
 
-	if request.Limit < 0 {
-		return nil, errors.BadRequest(nil, "`limit` query parameter cannot be negative")
+	if err := request.validate(config.Config.MaxItems); err != nil {
+		return nil, err
 	}
-	if request.Int1 <= 0 || request.Int2 <= 0 {
-		return nil, errors.BadRequest(nil, "both `int1` and `int2` query parameters must be valid positive non-zero integer")
+	if request.Ruleset != "" {
+		return fizzBuzzRuleset(ctx, request)
 	}
 
-	if !config.Config.AllowEmptyStr && (request.Str1 == "" || request.Str2 == "") {
-		msg := "both `str1` and `str2` query parameters must be set, empty words have been disallowed through configuration"
-		return nil, errors.BadRequest(nil, msg)
+	if err := request.Charge(ctx); err != nil {
+		return nil, err
 	}
 
 	results := make(Response, request.Limit)
-	for i := 1; i < request.Limit+1; i++ {
-		results[i-1] = fizzBuzzImpl(i, request)
+	if err := generationPool.fill(ctx, newEngine(request), results, 1); err != nil {
+		return nil, err
 	}
-
 	return &results, nil
 }
+
+func fizzBuzzRuleset(ctx context.Context, request Request) (*Response, error) {
+	ruleset, err := Rulesets.Get(ctx, request.Ruleset, request.Version)
+	if err != nil {
+		return nil, err
+	}
+	return generateRules(ctx, ruleset.Rules, request.Limit)
+}
@@ -79,7 +79,7 @@ func TestFizzBuzzHandler(t *testing.T) {
 				return false
 			}
 			if test.response != nil {
-				if !a.Len(*test.response, test.request.Limit, "there are less items in the response than in the request limit") {
+				if !a.Len(*test.response, int(test.request.Limit), "there are less items in the response than in the request limit") {
 					return false
 				}
 			}
@@ -99,3 +99,23 @@ func TestFizzBuzzHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestFizzBuzzHandlerCancellation(t *testing.T) {
+	a := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	response, err := fizzBuzz(ctx, Request{
+		Int1:  3,
+		Int2:  5,
+		Limit: cancellationInterval * 2,
+		Str1:  "Fizz",
+		Str2:  "Buzz",
+	})
+	a.Nil(response, "cancelled request returned a response")
+	httpErr, ok := err.(*errors.Error)
+	if a.Truef(ok, "error is not a valid *errors.Error") {
+		a.Equal(http.StatusServiceUnavailable, httpErr.HttpCode, "invalid http status code")
+	}
+}
@@ -0,0 +1,245 @@
+package fizzbuzz
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	nethttp "net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/jobs"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	jobKind = "fizzbuzz"
+	// jobChunkSize is the number of items a job generates at once, bounding its memory usage
+	jobChunkSize = 1 << 20
+	// jobFlushSize is the size of the encoded items buffered before being written to the job result
+	jobFlushSize = 64 * 1024
+	// maxEncodedWords bounds the number of distinct words whose JSON encoding is cached by a job
+	maxEncodedWords = 1024
+)
+
+type (
+	// JobRequest is the request body for the SubmitJob endpoint, with the same fields as a BatchItem.
+	// Its limit is bounded by config.Manifest JobMaxItems instead of MaxItems.
+	JobRequest BatchItem
+	// JobPath identifies a job through the request path
+	JobPath struct {
+		ID string `param:"id" path:"id"`
+	}
+	// JobResponse describes a job, its result being a JSON array of items served by ResultURL once it succeeded.
+	// Results can be downloaded in chunks using `Range` requests.
+	JobResponse struct {
+		jobs.Job
+		ResultURL string `json:"resultUrl,omitempty"`
+	}
+	// SubmittedJob is returned by the SubmitJob endpoint
+	SubmittedJob struct {
+		JobResponse
+	}
+	// itemEncoder encodes items as JSON strings, numbers never needing to be escaped
+	itemEncoder struct {
+		words map[string][]byte
+	}
+)
+
+var (
+	// SubmitJob handles POST /api/v1/fizzbuzz/jobs
+	SubmitJob = http.Post("/api/v1/fizzbuzz/jobs", submitJob).WithScopes("jobs:write")
+	// GetJob handles GET /api/v1/fizzbuzz/jobs/:id
+	GetJob = http.Get("/api/v1/fizzbuzz/jobs/:id", getJob).WithScopes("jobs:read")
+	// CancelJob handles DELETE /api/v1/fizzbuzz/jobs/:id
+	CancelJob = http.Delete("/api/v1/fizzbuzz/jobs/:id", cancelJob).WithScopes("jobs:write")
+	// JobResult handles GET /api/v1/fizzbuzz/jobs/:id/result, serving results as is so that they support `Range`
+	// requests
+	JobResult = http.EchoHandler("/api/v1/fizzbuzz/jobs/:id/result", nethttp.MethodGet, jobResult).
+			WithScopes("jobs:read").
+			WithTimeout(-1).
+			WithoutCompression()
+)
+
+func init() {
+	jobs.Register(jobKind, runJob)
+}
+
+// JobHandlers returns every job endpoint
+func JobHandlers() []http.Handler {
+	return []http.Handler{
+		SubmitJob,
+		GetJob,
+		CancelJob,
+		JobResult,
+	}
+}
+
+// StatusCode returns 202, as the job runs in the background
+func (SubmittedJob) StatusCode() int {
+	return nethttp.StatusAccepted
+}
+
+func newJobResponse(job jobs.Job) JobResponse {
+	response := JobResponse{Job: job}
+	if job.State == jobs.Succeeded {
+		response.ResultURL = "/api/v1/fizzbuzz/jobs/" + job.ID + "/result"
+	}
+	return response
+}
+
+func (e *itemEncoder) append(buf []byte, item string) []byte {
+	number := item != ""
+	for i := 0; i < len(item) && number; i++ {
+		number = item[i] >= '0' && item[i] <= '9'
+	}
+	if number {
+		buf = append(buf, '"')
+		buf = append(buf, item...)
+		return append(buf, '"')
+	}
+
+	encoded, ok := e.words[item]
+	if !ok {
+		encoded, _ = json.Marshal(item)
+		if len(e.words) < maxEncodedWords {
+			e.words[item] = encoded
+		}
+	}
+	return append(buf, encoded...)
+}
+
+// generator returns the generator of a validated Request, resolving its rule set if any
+func (r Request) generator(ctx context.Context) (generator, error) {
+	if r.Ruleset == "" {
+		return newEngine(r), nil
+	}
+	ruleset, err := Rulesets.Get(ctx, r.Ruleset, r.Version)
+	if err != nil {
+		return nil, err
+	}
+	return compileRules(ruleset.Rules)
+}
+
+// runJob writes the sequence of a job as a JSON array, generating it chunk by chunk
+func runJob(ctx context.Context, job jobs.Job, w io.Writer, progress func(done int64)) error {
+	var params JobRequest
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return err
+	}
+	request := BatchItem(params).request()
+	g, err := request.generator(ctx)
+	if err != nil {
+		return err
+	}
+
+	chunk := make(Response, jobChunkSize)
+	if request.Limit < jobChunkSize {
+		chunk = chunk[:request.Limit]
+	}
+	encoder := &itemEncoder{words: make(map[string][]byte)}
+	buf := append(make([]byte, 0, 2*jobFlushSize), '[')
+	for first := int64(1); first <= request.Limit; {
+		items := chunk
+		if remaining := request.Limit - first + 1; remaining < int64(len(items)) {
+			items = items[:remaining]
+		}
+		// expression budgets are bound to requests, every chunk of a job gets its own
+		renewBudget(g)
+		if err := generationPool.fill(ctx, g, items, first); err != nil {
+			return err
+		}
+
+		for i, item := range items {
+			if first+int64(i) > 1 {
+				buf = append(buf, ',')
+			}
+			buf = encoder.append(buf, item)
+			if len(buf) >= jobFlushSize {
+				if _, err := w.Write(buf); err != nil {
+					return err
+				}
+				buf = buf[:0]
+			}
+		}
+		first += int64(len(items))
+		progress(first - 1)
+	}
+	_, err = w.Write(append(buf, ']'))
+	return err
+}
+
+func submitJob(ctx context.Context, params JobRequest) (*SubmittedJob, error) {
+	log := logger.FromContext(ctx)
+
+	request := BatchItem(params).request()
+	if err := request.validate(config.Config.JobMaxItems); err != nil {
+		return nil, err
+	}
+	// rule sets are pinned to their current version, so that resumed jobs generate the same sequence
+	if request.Ruleset != "" {
+		ruleset, err := Rulesets.Get(ctx, request.Ruleset, request.Version)
+		if err != nil {
+			return nil, err
+		}
+		params.Version = ruleset.Version
+	}
+	if err := ItemsQuota.Consume(ctx, uint64(request.Limit)); err != nil {
+		return nil, err
+	}
+
+	job, err := jobs.Submit(ctx, jobKind, params, request.Limit)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("fizzbuzz job submitted", zap.String("job.id", job.ID), zap.Int64("limit", request.Limit))
+	return &SubmittedJob{JobResponse: newJobResponse(job)}, nil
+}
+
+func getJob(ctx context.Context, request JobPath) (*JobResponse, error) {
+	job, err := jobs.Get(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	response := newJobResponse(job)
+	return &response, nil
+}
+
+// cancelJob cancels queued and running jobs, and deletes finished ones along with their result
+func cancelJob(ctx context.Context, request JobPath) (*JobResponse, error) {
+	job, err := jobs.Get(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if job.State.Finished() {
+		if err := jobs.Delete(ctx, request.ID); err != nil {
+			return nil, err
+		}
+	} else if job, err = jobs.Cancel(ctx, request.ID); err != nil {
+		return nil, err
+	}
+	response := newJobResponse(job)
+	return &response, nil
+}
+
+func jobResult(c echo.Context) error {
+	f, job, err := jobs.Open(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			logger.FromContext(c.Request().Context()).Warn("could not close job result", zap.Error(err))
+		}
+	}()
+
+	// results are immutable, the job identifier is a strong validator for conditional and range requests
+	c.Response().Header().Set("ETag", `"`+job.ID+`"`)
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	// results can be large, the write timeout is applied to every write instead of the whole download
+	http.ServeContent(c, job.ID+".json", *job.FinishedAt, f)
+	return nil
+}
@@ -0,0 +1,37 @@
+package fizzbuzz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/jobs"
+)
+
+func TestRunJob(t *testing.T) {
+	a := assert.New(t)
+
+	// the sequence spans several chunks, and words must be escaped
+	request := Request{Int1: 3, Int2: 5, Limit: jobChunkSize + 5, Str1: `"fizz"`, Str2: "<buzz>"}
+	params, err := json.Marshal(JobRequest{Int1: request.Int1, Int2: request.Int2, Limit: request.Limit, Str1: request.Str1, Str2: request.Str2})
+	a.NoError(err)
+
+	var buf bytes.Buffer
+	var done int64
+	err = runJob(context.Background(), jobs.Job{Params: params, Total: request.Limit}, &buf, func(d int64) { done = d })
+	if a.NoError(err) {
+		a.Equal(request.Limit, done)
+		var results Response
+		a.NoError(json.Unmarshal(buf.Bytes(), &results))
+		a.Equal(reference(request), results)
+	}
+
+	buf.Reset()
+	params, _ = json.Marshal(JobRequest{Int1: 3, Int2: 5, Str1: "fizz", Str2: "buzz"})
+	if a.NoError(runJob(context.Background(), jobs.Job{Params: params}, &buf, func(int64) {})) {
+		a.Equal("[]", buf.String())
+	}
+}
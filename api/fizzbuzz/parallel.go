@@ -0,0 +1,116 @@
+package fizzbuzz
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+// generator is implemented by fizzbuzz engines, filling dst with the items starting at number first
+type generator interface {
+	fill(ctx context.Context, dst []string, first int64) error
+}
+
+// workerPool bounds the number of goroutines generating chunks on behalf of requests, across the whole instance
+type workerPool struct {
+	slots             chan struct{}
+	maxRequestWorkers int
+	chunkSize         int
+}
+
+var generationPool *workerPool
+
+func init() {
+	workers := config.Config.GenerationWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	generationPool = newWorkerPool(workers, config.Config.GenerationRequestWorkers, config.Config.GenerationChunkSize)
+
+	metrics.RegisterGauge(semconv.MetricName("generation", "workers"),
+		"Number of goroutines which can generate fizzbuzz chunks concurrently.",
+		func() float64 { return float64(cap(generationPool.slots)) })
+	metrics.RegisterGauge(semconv.MetricName("generation", "workers", "busy"),
+		"Number of goroutines currently generating fizzbuzz chunks.",
+		func() float64 { return float64(len(generationPool.slots)) })
+}
+
+func newWorkerPool(workers, maxRequestWorkers, chunkSize int) *workerPool {
+	return &workerPool{
+		slots:             make(chan struct{}, workers),
+		maxRequestWorkers: maxRequestWorkers,
+		chunkSize:         chunkSize,
+	}
+}
+
+// fill generates the items starting at number first into dst, split into chunks generated concurrently and written
+// in place.
+// The calling goroutine always takes part in the generation, while helpers are only started if the pool has idle
+// workers, so requests never wait for each other.
+func (p *workerPool) fill(ctx context.Context, e generator, dst []string, first int64) error {
+	chunks := 1
+	if p.chunkSize > 0 {
+		chunks = (len(dst) + p.chunkSize - 1) / p.chunkSize
+	}
+	if chunks <= 1 || p.maxRequestWorkers <= 1 {
+		return e.fill(ctx, dst, first)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var next atomic.Int64
+	work := func() error {
+		for {
+			chunk := int(next.Add(1) - 1)
+			if chunk >= chunks {
+				return nil
+			}
+			from := chunk * p.chunkSize
+			to := from + p.chunkSize
+			if to > len(dst) {
+				to = len(dst)
+			}
+			if err := e.fill(ctx, dst[from:to], first+int64(from)); err != nil {
+				// stop the other workers early
+				cancel()
+				return err
+			}
+		}
+	}
+
+	helpers := p.maxRequestWorkers - 1
+	if helpers > chunks-1 {
+		helpers = chunks - 1
+	}
+	errs := make([]error, helpers+1)
+	var wg sync.WaitGroup
+spawn:
+	for i := 1; i <= helpers; i++ {
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			break spawn
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-p.slots }()
+			errs[i] = work()
+		}(i)
+	}
+	errs[0] = work()
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
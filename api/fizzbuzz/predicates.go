@@ -0,0 +1,366 @@
+package fizzbuzz
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/pkg/errors"
+	"github.com/swaggest/jsonschema-go"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/expr"
+)
+
+// PredicateKind selects which Predicate fields are used
+type PredicateKind string
+
+// Predicate kinds, see the associated *Predicate types for their fields
+const (
+	DivisibleBy         PredicateKind = "divisibleBy"
+	ContainsDigit       PredicateKind = "containsDigit"
+	DigitSumDivisibleBy PredicateKind = "digitSumDivisibleBy"
+	Prime               PredicateKind = "prime"
+	PerfectSquare       PredicateKind = "perfectSquare"
+	InRange             PredicateKind = "inRange"
+	RemainderEquals     PredicateKind = "remainderEquals"
+	And                 PredicateKind = "and"
+	Or                  PredicateKind = "or"
+	Not                 PredicateKind = "not"
+	Expression          PredicateKind = "expression"
+)
+
+const (
+	// maxPredicateDepth bounds the nesting of and, or, not predicates
+	maxPredicateDepth = 16
+	// maxPredicateNodes bounds the total number of predicates of a request
+	maxPredicateNodes = 256
+	// maxSquareRoot is the square root of math.MaxInt64, rounded down
+	maxSquareRoot = 3037000499
+)
+
+type (
+	// Predicate is a condition on a number, its Kind selecting which other fields are used.
+	// See the OpenAPI schemas for the fields required by each kind.
+	Predicate struct {
+		Kind       PredicateKind `json:"kind"`
+		Divisor    int64         `json:"divisor,omitempty"`
+		Remainder  int64         `json:"remainder,omitempty"`
+		Digit      int64         `json:"digit,omitempty"`
+		Min        *int64        `json:"min,omitempty"`
+		Max        *int64        `json:"max,omitempty"`
+		Predicates []Predicate   `json:"predicates,omitempty"`
+		Predicate  *Predicate    `json:"predicate,omitempty"`
+		Expression string        `json:"expression,omitempty"`
+	}
+	// predicateFunc is a compiled Predicate, failures being reported through evaluation
+	predicateFunc func(n int64, e *evaluation) bool
+	// evaluation holds the state of the predicates evaluated by a single goroutine
+	evaluation struct {
+		meter *expr.Meter
+		err   error
+	}
+
+	// DivisibleByPredicate matches multiples of Divisor, it is only used to document Predicate through OpenAPI
+	DivisibleByPredicate struct {
+		Kind    string `json:"kind" required:"true" enum:"divisibleBy"`
+		Divisor int64  `json:"divisor" required:"true" minimum:"1"`
+	}
+	// ContainsDigitPredicate matches numbers whose decimal representation contains Digit
+	ContainsDigitPredicate struct {
+		Kind  string `json:"kind" required:"true" enum:"containsDigit"`
+		Digit int64  `json:"digit" required:"true" minimum:"0" maximum:"9"`
+	}
+	// DigitSumDivisibleByPredicate matches numbers whose sum of decimal digits is a multiple of Divisor
+	DigitSumDivisibleByPredicate struct {
+		Kind    string `json:"kind" required:"true" enum:"digitSumDivisibleBy"`
+		Divisor int64  `json:"divisor" required:"true" minimum:"1"`
+	}
+	// PrimePredicate matches prime numbers
+	PrimePredicate struct {
+		Kind string `json:"kind" required:"true" enum:"prime"`
+	}
+	// PerfectSquarePredicate matches squares of integers
+	PerfectSquarePredicate struct {
+		Kind string `json:"kind" required:"true" enum:"perfectSquare"`
+	}
+	// InRangePredicate matches numbers between Min and Max
+	InRangePredicate struct {
+		Kind string `json:"kind" required:"true" enum:"inRange"`
+		Min  *int64 `json:"min,omitempty" description:"Inclusive lower bound, unbounded if omitted"`
+		Max  *int64 `json:"max,omitempty" description:"Inclusive upper bound, unbounded if omitted"`
+	}
+	// RemainderEqualsPredicate matches numbers whose remainder of the division by Divisor is Remainder
+	RemainderEqualsPredicate struct {
+		Kind      string `json:"kind" required:"true" enum:"remainderEquals"`
+		Divisor   int64  `json:"divisor" required:"true" minimum:"1"`
+		Remainder int64  `json:"remainder" required:"true" minimum:"0"`
+	}
+	// AndPredicate matches numbers matching every one of Predicates
+	AndPredicate struct {
+		Kind       string      `json:"kind" required:"true" enum:"and"`
+		Predicates []Predicate `json:"predicates" required:"true" minItems:"1"`
+	}
+	// OrPredicate matches numbers matching at least one of Predicates
+	OrPredicate struct {
+		Kind       string      `json:"kind" required:"true" enum:"or"`
+		Predicates []Predicate `json:"predicates" required:"true" minItems:"1"`
+	}
+	// NotPredicate matches numbers not matching Predicate
+	NotPredicate struct {
+		Kind      string    `json:"kind" required:"true" enum:"not"`
+		Predicate Predicate `json:"predicate" required:"true"`
+	}
+	// ExpressionPredicate matches numbers for which Expression is true
+	ExpressionPredicate struct {
+		Kind       string `json:"kind" required:"true" enum:"expression"`
+		Expression string `json:"expression" required:"true" maxLength:"1024" example:"n % 3 == 0 && n > 10" description:"Boolean expression of the number n, supporting integer arithmetic, comparisons, logical operators, abs, min and max"`
+	}
+)
+
+var (
+	_ jsonschema.OneOfExposer = Predicate{}
+	_ jsonschema.Preparer     = Predicate{}
+	_ jsonschema.Enum         = PredicateKind("")
+)
+
+// Enum lists every PredicateKind for OpenAPI.
+func (PredicateKind) Enum() []interface{} {
+	return []interface{}{
+		DivisibleBy, ContainsDigit, DigitSumDivisibleBy, Prime, PerfectSquare, InRange, RemainderEquals, And, Or, Not,
+		Expression,
+	}
+}
+
+// JSONSchemaOneOf documents every Predicate kind as a distinct schema.
+func (Predicate) JSONSchemaOneOf() []interface{} {
+	return []interface{}{
+		DivisibleByPredicate{}, ContainsDigitPredicate{}, DigitSumDivisibleByPredicate{}, PrimePredicate{},
+		PerfectSquarePredicate{}, InRangePredicate{}, RemainderEqualsPredicate{}, AndPredicate{}, OrPredicate{},
+		NotPredicate{}, ExpressionPredicate{},
+	}
+}
+
+// PrepareJSONSchema removes the Predicate fields, which are described by each kind schema.
+func (Predicate) PrepareJSONSchema(schema *jsonschema.Schema) error {
+	schema.Properties = nil
+	schema.Type = nil
+	return nil
+}
+
+// compile validates a Predicate and converts it to a predicateFunc.
+// Errors are prefixed with the given path, nodes counting the predicates compiled so far.
+func (p Predicate) compile(path string, depth int, nodes *int) (predicateFunc, error) {
+	if *nodes++; *nodes > maxPredicateNodes {
+		return nil, fmt.Errorf("%s: rules cannot have more than %d predicates", path, maxPredicateNodes)
+	}
+	if depth > maxPredicateDepth {
+		return nil, fmt.Errorf("%s: predicates cannot be nested more than %d times", path, maxPredicateDepth)
+	}
+
+	switch p.Kind {
+	case DivisibleBy:
+		if p.Divisor <= 0 {
+			return nil, fmt.Errorf("%s.divisor: must be a positive non-zero integer", path)
+		}
+		divisor := p.Divisor
+		return func(n int64, _ *evaluation) bool { return n%divisor == 0 }, nil
+	case ContainsDigit:
+		if p.Digit < 0 || p.Digit > 9 {
+			return nil, fmt.Errorf("%s.digit: must be between 0 and 9", path)
+		}
+		digit := p.Digit
+		return func(n int64, _ *evaluation) bool { return containsDigit(n, digit) }, nil
+	case DigitSumDivisibleBy:
+		if p.Divisor <= 0 {
+			return nil, fmt.Errorf("%s.divisor: must be a positive non-zero integer", path)
+		}
+		divisor := p.Divisor
+		return func(n int64, _ *evaluation) bool { return digitSum(n)%divisor == 0 }, nil
+	case Prime:
+		return func(n int64, _ *evaluation) bool { return isPrime(n) }, nil
+	case PerfectSquare:
+		return func(n int64, _ *evaluation) bool { return isPerfectSquare(n) }, nil
+	case InRange:
+		low, high := int64(math.MinInt64), int64(math.MaxInt64)
+		if p.Min != nil {
+			low = *p.Min
+		}
+		if p.Max != nil {
+			high = *p.Max
+		}
+		if low > high {
+			return nil, fmt.Errorf("%s: min cannot be greater than max", path)
+		}
+		return func(n int64, _ *evaluation) bool { return n >= low && n <= high }, nil
+	case RemainderEquals:
+		if p.Divisor <= 0 {
+			return nil, fmt.Errorf("%s.divisor: must be a positive non-zero integer", path)
+		}
+		if p.Remainder < 0 || p.Remainder >= p.Divisor {
+			return nil, fmt.Errorf("%s.remainder: must be between 0 and divisor - 1", path)
+		}
+		divisor, remainder := p.Divisor, p.Remainder
+		return func(n int64, _ *evaluation) bool { return n%divisor == remainder }, nil
+	case And, Or:
+		if len(p.Predicates) == 0 {
+			return nil, fmt.Errorf("%s.predicates: at least one predicate is required", path)
+		}
+		predicates := make([]predicateFunc, len(p.Predicates))
+		for i, predicate := range p.Predicates {
+			compiled, err := predicate.compile(fmt.Sprintf("%s.predicates[%d]", path, i), depth+1, nodes)
+			if err != nil {
+				return nil, err
+			}
+			predicates[i] = compiled
+		}
+		if p.Kind == And {
+			return func(n int64, e *evaluation) bool {
+				for _, predicate := range predicates {
+					if !predicate(n, e) {
+						return false
+					}
+				}
+				return true
+			}, nil
+		}
+		return func(n int64, e *evaluation) bool {
+			for _, predicate := range predicates {
+				if predicate(n, e) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case Not:
+		if p.Predicate == nil {
+			return nil, fmt.Errorf("%s.predicate: is required", path)
+		}
+		predicate, err := p.Predicate.compile(path+".predicate", depth+1, nodes)
+		if err != nil {
+			return nil, err
+		}
+		return func(n int64, e *evaluation) bool { return !predicate(n, e) }, nil
+	case Expression:
+		program, err := expr.Compile(p.Expression, "n")
+		if err != nil {
+			var syntaxErr *expr.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				err = syntaxErr
+			}
+			return nil, fmt.Errorf("%s.expression: %w", path, err)
+		}
+		if program.Type() != expr.Bool {
+			return nil, fmt.Errorf("%s.expression: must be a boolean expression, got %s", path, program.Type())
+		}
+		return func(n int64, e *evaluation) bool {
+			if e.err != nil {
+				return false
+			}
+			ok, err := program.EvalBool(e.meter, n)
+			if err != nil {
+				e.err = fmt.Errorf("%s.expression with n = %d: %w", path, n, err)
+			}
+			return ok
+		}, nil
+	default:
+		return nil, fmt.Errorf("%s.kind: unknown predicate kind '%s'", path, p.Kind)
+	}
+}
+
+func abs(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+func containsDigit(n, digit int64) bool {
+	u := abs(n)
+	for {
+		if int64(u%10) == digit {
+			return true
+		}
+		if u /= 10; u == 0 {
+			return false
+		}
+	}
+}
+
+func digitSum(n int64) int64 {
+	var sum int64
+	for u := abs(n); u > 0; u /= 10 {
+		sum += int64(u % 10)
+	}
+	return sum
+}
+
+func isPerfectSquare(n int64) bool {
+	if n < 0 {
+		return false
+	}
+	root := int64(math.Sqrt(float64(n)))
+	// float64 rounding can be off by one for large numbers
+	if root > maxSquareRoot {
+		root = maxSquareRoot
+	}
+	for root*root > n {
+		root--
+	}
+	for root < maxSquareRoot && (root+1)*(root+1) <= n {
+		root++
+	}
+	return root*root == n
+}
+
+// millerRabinBases are enough for a deterministic Miller-Rabin test of every 64-bit integer
+var millerRabinBases = []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37}
+
+func isPrime(n int64) bool {
+	if n < 2 {
+		return false
+	}
+	u := uint64(n)
+	for _, p := range millerRabinBases {
+		if u%p == 0 {
+			return u == p
+		}
+	}
+
+	d, r := u-1, 0
+	for d%2 == 0 {
+		d /= 2
+		r++
+	}
+witness:
+	for _, a := range millerRabinBases {
+		x := powMod(a, d, u)
+		if x == 1 || x == u-1 {
+			continue
+		}
+		for i := 1; i < r; i++ {
+			if x = mulMod(x, x, u); x == u-1 {
+				continue witness
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	_, rem := bits.Div64(hi%m, lo, m)
+	return rem
+}
+
+func powMod(base, exp, m uint64) uint64 {
+	result := uint64(1)
+	base %= m
+	for ; exp > 0; exp >>= 1 {
+		if exp&1 == 1 {
+			result = mulMod(result, base, m)
+		}
+		base = mulMod(base, base, m)
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package fizzbuzz
+
+import (
+	"context"
+	"math/big"
+
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+type (
+	// RangeRequest is the request body for the FizzBuzzRange endpoint.
+	// Numbers are decimal strings of arbitrary precision, such as windows near 10^30.
+	RangeRequest struct {
+		Int1  string `query:"int1"`
+		Int2  string `query:"int2"`
+		Start string `query:"start"`
+		End   string `query:"end"`
+		Str1  string `query:"str1"`
+		Str2  string `query:"str2"`
+	}
+	// bigRequest is a parsed RangeRequest
+	bigRequest struct {
+		int1, int2 *big.Int
+		start, end *big.Int
+		str1, str2 string
+	}
+)
+
+var (
+	// FizzBuzzRange handles GET /api/v1/fizzbuzz/range
+	FizzBuzzRange = http.Get("/api/v1/fizzbuzz/range", fizzBuzzRange).
+		WithScopes("fizzbuzz:read").
+		WithCache(0).
+		WithRequestETag().
+		WithCacheControl("public, max-age=3600")
+)
+
+func parseBig(name, value string) (*big.Int, error) {
+	number, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, errors.BadRequest(nil, "`%s` query parameter must be a decimal integer", name)
+	}
+	return number, nil
+}
+
+// parse converts every number of the request, without validating them
+func (r RangeRequest) parse() (request bigRequest, err error) {
+	request.str1, request.str2 = r.Str1, r.Str2
+	if request.int1, err = parseBig("int1", r.Int1); err != nil {
+		return request, err
+	}
+	if request.int2, err = parseBig("int2", r.Int2); err != nil {
+		return request, err
+	}
+	if request.start, err = parseBig("start", r.Start); err != nil {
+		return request, err
+	}
+	if request.end, err = parseBig("end", r.End); err != nil {
+		return request, err
+	}
+	return request, nil
+}
+
+// count returns the number of items of the range, which might not fit in an int64
+func (r bigRequest) count() *big.Int {
+	count := new(big.Int).Sub(r.end, r.start)
+	return count.Add(count, bigOne)
+}
+
+// Cost estimates the response size, the number of items times the length of the longest possible item.
+// This is used by http.GenericHandler for admission control, before the request is even validated.
+func (r RangeRequest) Cost() uint64 {
+	request, err := r.parse()
+	if err != nil {
+		return 0
+	}
+	count := request.count()
+	if count.Sign() <= 0 || !count.IsUint64() {
+		return 0
+	}
+	itemLength := len(r.Str1) + len(r.Str2)
+	if digits := len(request.end.String()); digits > itemLength {
+		itemLength = digits
+	}
+	return count.Uint64() * uint64(itemLength)
+}
+
+// Charge consumes the ItemsQuota of the client, whether the response is generated or cached
+func (r RangeRequest) Charge(ctx context.Context) error {
+	request, err := r.parse()
+	if err != nil {
+		return err
+	}
+	return ItemsQuota.Consume(ctx, request.count().Uint64())
+}
+
+func fizzBuzzRange(ctx context.Context, rangeRequest RangeRequest) (*Response, error) {
+	log := logger.FromContext(ctx)
+
+	log.Debug("new fizzbuzz range request",
+		zap.Strings("words", []string{rangeRequest.Str1, rangeRequest.Str2}),
+		zap.Strings("ints", []string{rangeRequest.Int1, rangeRequest.Int2}),
+		zap.Strings("range", []string{rangeRequest.Start, rangeRequest.End}),
+	)
+
+	request, err := rangeRequest.parse()
+	if err != nil {
+		return nil, err
+	}
+	if request.int1.Sign() <= 0 || request.int2.Sign() <= 0 {
+		return nil, errors.BadRequest(nil, "both `int1` and `int2` query parameters must be valid positive non-zero integer")
+	}
+	if request.start.Sign() <= 0 {
+		return nil, errors.BadRequest(nil, "`start` query parameter must be a positive non-zero integer")
+	}
+	if request.end.Cmp(request.start) < 0 {
+		return nil, errors.BadRequest(nil, "`end` query parameter cannot be lower than `start`")
+	}
+	count := request.count()
+	if !count.IsInt64() || count.Int64() > config.Config.MaxItems {
+		return nil, errors.BadRequest(nil, "ranges cannot exceed %d items", config.Config.MaxItems)
+	}
+	if err := validateWords(request.str1, request.str2); err != nil {
+		return nil, err
+	}
+
+	if err := ItemsQuota.Consume(ctx, count.Uint64()); err != nil {
+		return nil, err
+	}
+
+	results := make(Response, count.Int64())
+	if err := generationPool.fill(ctx, newBigEngine(request), results, 1); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
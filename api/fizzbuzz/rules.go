@@ -0,0 +1,180 @@
+package fizzbuzz
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	pkgErrors "github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/expr"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+// maxRules is the maximum number of rules of a request
+const maxRules = 32
+
+type (
+	// Rule replaces numbers matching When with Word.
+	// Words of every matching rule are concatenated in order, such as "FizzBuzz".
+	Rule struct {
+		Word string    `json:"word" required:"true"`
+		When Predicate `json:"when" required:"true"`
+	}
+	// RulesRequest is the request body for the FizzBuzzRules endpoint
+	RulesRequest struct {
+		Limit int64  `json:"limit" minimum:"0"`
+		Rules []Rule `json:"rules" required:"true" minItems:"1"`
+	}
+	// ruleEngine generates sequences from compiled rules
+	ruleEngine struct {
+		words      []string
+		predicates []predicateFunc
+		// budget bounds the evaluation of expression predicates over the whole request
+		budget *expr.Budget
+	}
+)
+
+var (
+	// FizzBuzzRules handles POST /api/v1/fizzbuzz/rules
+	FizzBuzzRules = http.Post("/api/v1/fizzbuzz/rules", fizzBuzzRules).WithScopes("fizzbuzz:read")
+)
+
+// Cost estimates the response size, the number of items times the length of the longest possible item.
+// This is used by http.GenericHandler for admission control, before the request is even validated.
+func (r RulesRequest) Cost() uint64 {
+	if r.Limit <= 0 {
+		return 0
+	}
+	itemLength := 0
+	for _, rule := range r.Rules {
+		itemLength += len(rule.Word)
+	}
+	if digits := len(strconv.FormatInt(r.Limit, 10)); digits > itemLength {
+		itemLength = digits
+	}
+	return uint64(r.Limit) * uint64(itemLength)
+}
+
+// newRulesBudget returns the budget of the expression predicates of a single request
+func newRulesBudget() *expr.Budget {
+	return expr.NewBudget(config.Config.ExpressionMaxSteps, config.Config.ExpressionTimeout)
+}
+
+// renewBudget gives a fresh expression budget to rule engines, as the generators of jobs and streams outlive requests
+// and generate their sequence chunk by chunk
+func renewBudget(g generator) {
+	if rules, ok := g.(*ruleEngine); ok {
+		rules.budget = newRulesBudget()
+	}
+}
+
+// compileRules validates rules and compiles their predicates, errors are returned with the path of the invalid field.
+func compileRules(rules []Rule) (*ruleEngine, error) {
+	if len(rules) == 0 {
+		return nil, errors.BadRequest(nil, "at least one rule is required")
+	}
+	if len(rules) > maxRules {
+		return nil, errors.BadRequest(nil, "rules cannot have more than %d entries", maxRules)
+	}
+
+	e := &ruleEngine{
+		words:      make([]string, len(rules)),
+		predicates: make([]predicateFunc, len(rules)),
+		budget:     newRulesBudget(),
+	}
+	nodes := 0
+	for i, rule := range rules {
+		if rule.Word == "" && !config.Config.AllowEmptyStr {
+			return nil, errors.BadRequest(nil, "rules[%d].word: empty words have been disallowed through configuration", i)
+		}
+		predicate, err := rule.When.compile(fmt.Sprintf("rules[%d].when", i), 0, &nodes)
+		if err != nil {
+			return nil, errors.BadRequest(err, "%s", err.Error())
+		}
+		e.words[i] = rule.Word
+		e.predicates[i] = predicate
+	}
+	return e, nil
+}
+
+// fill generates the items starting at number first into dst, checking ctx every cancellationInterval items.
+func (e *ruleEngine) fill(ctx context.Context, dst []string, first int64) error {
+	state := &evaluation{meter: e.budget.Meter()}
+	for i := range dst {
+		if i%cancellationInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return errors.Unavailable(err, "fizzbuzz generation aborted after %d items", first+int64(i)-1)
+			}
+		}
+
+		number := first + int64(i)
+		matched := false
+		for j, predicate := range e.predicates {
+			if !predicate(number, state) {
+				continue
+			}
+			if matched {
+				dst[i] += e.words[j]
+			} else {
+				dst[i] = e.words[j]
+				matched = true
+			}
+		}
+		if state.err != nil {
+			return evaluationError(state.err)
+		}
+		if !matched {
+			dst[i] = strconv.FormatInt(number, 10)
+		}
+	}
+	return nil
+}
+
+// evaluationError maps expression evaluation failures to http errors, exceeding the time budget being
+// a server-side limit while every other failure is caused by the request itself
+func evaluationError(err error) error {
+	if pkgErrors.Is(err, expr.ErrTimeBudgetExceeded) {
+		return errors.Unavailable(err, "%s", err.Error())
+	}
+	return errors.BadRequest(err, "%s", err.Error())
+}
+
+func fizzBuzzRules(ctx context.Context, request RulesRequest) (*Response, error) {
+	log := logger.FromContext(ctx)
+
+	log.Debug("new fizzbuzz rules request",
+		zap.Int("rules", len(request.Rules)),
+		zap.Int64("limit", request.Limit),
+	)
+
+	if request.Limit < 0 {
+		return nil, errors.BadRequest(nil, "`limit` cannot be negative")
+	}
+	if request.Limit > config.Config.MaxItems {
+		return nil, errors.BadRequest(nil, "`limit` cannot exceed %d", config.Config.MaxItems)
+	}
+	return generateRules(ctx, request.Rules, request.Limit)
+}
+
+// generateRules compiles rules and generates limit items, consuming the ItemsQuota of the client
+func generateRules(ctx context.Context, rules []Rule, limit int64) (*Response, error) {
+	engine, err := compileRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ItemsQuota.Consume(ctx, uint64(limit)); err != nil {
+		return nil, err
+	}
+
+	results := make(Response, limit)
+	if err := generationPool.fill(ctx, engine, results, 1); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
@@ -0,0 +1,146 @@
+package fizzbuzz
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/expr"
+)
+
+func TestPredicates(t *testing.T) {
+	a := assert.New(t)
+
+	// a sieve of Eratosthenes is the reference for small numbers
+	composite := make([]bool, 10_000)
+	for i := 2; i < len(composite); i++ {
+		if !composite[i] {
+			for j := i * i; j < len(composite); j += i {
+				composite[j] = true
+			}
+		}
+	}
+	for i := int64(-1); i < int64(len(composite)); i++ {
+		a.Equal(i >= 2 && !composite[i], isPrime(i), "isPrime(%d)", i)
+	}
+	a.True(isPrime(9223372036854775783), "largest int64 prime")
+	a.False(isPrime(3215031751), "strong pseudoprime to bases 2, 3, 5, 7")
+
+	for _, n := range []int64{0, 1, 4, 9, 1 << 62, maxSquareRoot * maxSquareRoot} {
+		a.True(isPerfectSquare(n), "isPerfectSquare(%d)", n)
+	}
+	for _, n := range []int64{-4, 2, 3, 8, 1<<62 - 1, math.MaxInt64} {
+		a.False(isPerfectSquare(n), "isPerfectSquare(%d)", n)
+	}
+
+	a.True(containsDigit(1234, 3))
+	a.True(containsDigit(0, 0))
+	a.False(containsDigit(1234, 5))
+	a.Equal(int64(10), digitSum(1234))
+	a.Equal(int64(10), digitSum(-1234))
+}
+
+func TestRules(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	// the default fizzbuzz can be expressed with rules
+	request := Request{Int1: 3, Int2: 5, Limit: 1000, Str1: "fizz", Str2: "buzz"}
+	engine, err := compileRules([]Rule{
+		{Word: "fizz", When: Predicate{Kind: DivisibleBy, Divisor: 3}},
+		{Word: "buzz", When: Predicate{Kind: DivisibleBy, Divisor: 5}},
+	})
+	if a.NoError(err) {
+		results := make(Response, request.Limit)
+		a.NoError(engine.fill(ctx, results, 1))
+		a.Equal(reference(request), results)
+	}
+
+	low, high := int64(10), int64(20)
+	engine, err = compileRules([]Rule{
+		{Word: "fizz", When: Predicate{Kind: And, Predicates: []Predicate{
+			{Kind: InRange, Min: &low, Max: &high},
+			{Kind: Not, Predicate: &Predicate{Kind: RemainderEquals, Divisor: 2, Remainder: 1}},
+		}}},
+		{Word: "buzz", When: Predicate{Kind: Or, Predicates: []Predicate{
+			{Kind: Prime},
+			{Kind: DigitSumDivisibleBy, Divisor: 9},
+		}}},
+	})
+	if a.NoError(err) {
+		results := make(Response, 20)
+		a.NoError(engine.fill(ctx, results, 1))
+		a.Equal(Response{
+			"1", "buzz", "buzz", "4", "buzz", "6", "buzz", "8", "buzz", "fizz",
+			"buzz", "fizz", "buzz", "fizz", "15", "fizz", "buzz", "fizzbuzz", "buzz", "fizz",
+		}, results)
+	}
+
+	// expressions can be mixed with other predicates
+	engine, err = compileRules([]Rule{
+		{Word: "fizz", When: Predicate{Kind: Expression, Expression: "n % 3 == 0"}},
+		{Word: "buzz", When: Predicate{Kind: And, Predicates: []Predicate{
+			{Kind: DivisibleBy, Divisor: 5},
+			{Kind: Expression, Expression: "max(n, 10) == n"},
+		}}},
+	})
+	if a.NoError(err) {
+		results := make(Response, 15)
+		a.NoError(engine.fill(ctx, results, 1))
+		a.Equal(Response{
+			"1", "2", "fizz", "4", "5", "fizz", "7", "8", "fizz", "buzz", "11", "fizz", "13", "14", "fizzbuzz",
+		}, results)
+	}
+
+	// runtime failures and exhausted budgets abort the generation
+	engine, err = compileRules([]Rule{{Word: "fizz", When: Predicate{Kind: Expression, Expression: "10 / (n - 5) > 1"}}})
+	if a.NoError(err) {
+		err = engine.fill(ctx, make(Response, 10), 1)
+		httpErr, ok := err.(*errors.Error)
+		if a.True(ok, "error is not a valid *errors.Error") {
+			a.Equal(http.StatusBadRequest, httpErr.HttpCode)
+			a.Contains(httpErr.Error(), "rules[0].when.expression with n = 5")
+		}
+		engine.budget = expr.NewBudget(10, time.Minute)
+		err = engine.fill(ctx, make(Response, 10), 1)
+		a.ErrorIs(err, expr.ErrStepBudgetExceeded)
+		engine.budget = expr.NewBudget(1000, -time.Second)
+		err = engine.fill(ctx, make(Response, 10), 1)
+		httpErr, ok = err.(*errors.Error)
+		if a.True(ok, "error is not a valid *errors.Error") {
+			a.Equal(http.StatusServiceUnavailable, httpErr.HttpCode)
+		}
+	}
+
+	invalid := [][]Rule{
+		nil,
+		{{Word: "fizz", When: Predicate{Kind: "unknown"}}},
+		{{Word: "fizz", When: Predicate{Kind: DivisibleBy}}},
+		{{Word: "fizz", When: Predicate{Kind: ContainsDigit, Digit: 10}}},
+		{{Word: "fizz", When: Predicate{Kind: InRange, Min: &high, Max: &low}}},
+		{{Word: "fizz", When: Predicate{Kind: RemainderEquals, Divisor: 3, Remainder: 3}}},
+		{{Word: "fizz", When: Predicate{Kind: And}}},
+		{{Word: "fizz", When: Predicate{Kind: Not}}},
+		{{Word: "fizz", When: Predicate{Kind: Expression, Expression: "n +"}}},
+		{{Word: "fizz", When: Predicate{Kind: Expression, Expression: "n * 2"}}},
+	}
+	for _, rules := range invalid {
+		_, err := compileRules(rules)
+		httpErr, ok := err.(*errors.Error)
+		if a.Truef(ok, "%+v: error is not a valid *errors.Error", rules) {
+			a.Equal(http.StatusBadRequest, httpErr.HttpCode)
+		}
+	}
+
+	nested := Predicate{Kind: Prime}
+	for i := 0; i <= maxPredicateDepth; i++ {
+		nested = Predicate{Kind: Not, Predicate: &nested}
+	}
+	_, err = compileRules([]Rule{{Word: "fizz", When: nested}})
+	a.Error(err, "deeply nested predicates are rejected")
+}
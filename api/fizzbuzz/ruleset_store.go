@@ -0,0 +1,282 @@
+package fizzbuzz
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+// maxRulesetIDLength bounds rule set identifiers, which are also used as file names
+const maxRulesetIDLength = 64
+
+type (
+	// RulesetStore persists rule sets along with their version history, versions being immutable and numbered from 1.
+	// Rule sets can be read by every client, but only updated or deleted by the client which created them.
+	// Implementations must be safe for concurrent use.
+	RulesetStore interface {
+		// Save stores a new version of a rule set, which must directly follow its latest stored version, or be 1 for
+		// new rule sets. Versions saved concurrently are rejected with a 409 error, and versions of rule sets created
+		// by another client with a 403 error.
+		Save(ctx context.Context, ruleset Ruleset) error
+		// Get returns the given version of a rule set, or its latest version if version is 0.
+		Get(ctx context.Context, id string, version int) (Ruleset, error)
+		// History returns every kept version of a rule set, from oldest to newest.
+		History(ctx context.Context, id string) ([]Ruleset, error)
+		// List returns the latest version of every rule set, sorted by identifier.
+		List(ctx context.Context) ([]Ruleset, error)
+		// Delete removes a rule set along with its history, rule sets created by another client are rejected with a
+		// 403 error.
+		Delete(ctx context.Context, id string) error
+		// Check reports whether rule sets can be read and saved, see health.Check.
+		Check(ctx context.Context) error
+	}
+	// fileRulesetStore is a RulesetStore serving rule sets from memory, the history of each rule set being persisted
+	// to its own JSON file. Files are loaded on first use, and replaced atomically on every change. Only the latest
+	// config.Manifest RulesetMaxVersions versions are kept.
+	fileRulesetStore struct {
+		dir      string
+		lock     sync.RWMutex
+		loadOnce sync.Once
+		loadErr  error
+		rulesets map[string][]Ruleset
+	}
+)
+
+// Rulesets is the RulesetStore used by the rule set endpoints, persisted in config.Manifest RulesetsDir
+var Rulesets RulesetStore = newFileRulesetStore(config.Config.RulesetsDir)
+
+func init() {
+	health.Register("rulesets", health.Readiness, time.Second, func(ctx context.Context) error {
+		return Rulesets.Check(ctx)
+	})
+}
+
+func newFileRulesetStore(dir string) *fileRulesetStore {
+	return &fileRulesetStore{
+		dir:      dir,
+		rulesets: make(map[string][]Ruleset),
+	}
+}
+
+// validRulesetID checks that an identifier is safe to use as a file name
+func validRulesetID(id string) bool {
+	if id == "" || len(id) > maxRulesetIDLength {
+		return false
+	}
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// load reads every rule set file once, unreadable files are logged and skipped
+func (s *fileRulesetStore) load() error {
+	s.loadOnce.Do(func() {
+		entries, err := os.ReadDir(s.dir)
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		if err != nil {
+			s.loadErr = errors.Wrapf(err, "could not read rule sets directory '%s'", s.dir)
+			return
+		}
+
+		log := logger.New().With(zap.String("rulesets.dir", s.dir))
+		for _, entry := range entries {
+			id, ok := strings.CutSuffix(entry.Name(), ".json")
+			if entry.IsDir() || !ok || !validRulesetID(id) {
+				continue
+			}
+			buf, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+			if err != nil {
+				log.Warn("could not read rule set file", zap.String("file", entry.Name()), zap.Error(err))
+				continue
+			}
+			var history []Ruleset
+			if err := json.Unmarshal(buf, &history); err != nil || len(history) == 0 {
+				log.Warn("invalid rule set file", zap.String("file", entry.Name()), zap.Error(err))
+				continue
+			}
+			s.rulesets[id] = history
+		}
+		log.Info("rule sets loaded", zap.Int("rulesets", len(s.rulesets)))
+	})
+	return s.loadErr
+}
+
+// persist atomically replaces the file of a rule set, it expects the lock to be held
+func (s *fileRulesetStore) persist(id string, history []Ruleset) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return errors.Wrapf(err, "could not create rule sets directory '%s'", s.dir)
+	}
+	buf, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, id+"-*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "could not create rule set file")
+	}
+	_, err = f.Write(buf)
+	if syncErr := f.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(s.dir, id+".json"))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return errors.Wrapf(err, "could not write rule set '%s'", id)
+	}
+	return nil
+}
+
+// owned checks that a rule set was created by the client of the current context
+func owned(ctx context.Context, history []Ruleset) error {
+	if owner := history[0].CreatedBy; owner != identity.ClientId(ctx) {
+		return coreErrors.Forbidden(nil, "rule set '%s' belongs to client '%s'", history[0].ID, owner)
+	}
+	return nil
+}
+
+// latestVersion returns the version of the last element of a rule set history, 0 if it is empty
+func latestVersion(history []Ruleset) int {
+	if len(history) == 0 {
+		return 0
+	}
+	return history[len(history)-1].Version
+}
+
+func (s *fileRulesetStore) Save(ctx context.Context, ruleset Ruleset) error {
+	if !validRulesetID(ruleset.ID) {
+		return coreErrors.BadRequest(nil, "invalid rule set identifier '%s'", ruleset.ID)
+	}
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	history := s.rulesets[ruleset.ID]
+	if len(history) > 0 {
+		if err := owned(ctx, history); err != nil {
+			return err
+		}
+	}
+	if latest := latestVersion(history); ruleset.Version != latest+1 {
+		return coreErrors.Conflict(nil, "rule set '%s' is at version %d, version %d cannot be saved", ruleset.ID, latest, ruleset.Version)
+	}
+	if len(history) == 0 && len(s.rulesets) >= config.Config.MaxRulesets {
+		return coreErrors.BadRequest(nil, "no more than %d rule sets can be saved", config.Config.MaxRulesets)
+	}
+
+	// the stored history is never mutated in place, as it is shared with readers
+	if keep := config.Config.RulesetMaxVersions; keep > 0 && len(history) >= keep {
+		history = history[len(history)-keep+1:]
+	}
+	updated := append(history[:len(history):len(history)], ruleset)
+	if err := s.persist(ruleset.ID, updated); err != nil {
+		return err
+	}
+	s.rulesets[ruleset.ID] = updated
+	return nil
+}
+
+func (s *fileRulesetStore) Get(ctx context.Context, id string, version int) (Ruleset, error) {
+	history, err := s.History(ctx, id)
+	if err != nil {
+		return Ruleset{}, err
+	}
+	if version == 0 {
+		return history[len(history)-1], nil
+	}
+	// older versions may have been dropped, see config.Manifest RulesetMaxVersions
+	idx := version - history[0].Version
+	if idx < 0 || idx >= len(history) {
+		return Ruleset{}, coreErrors.NotFound()
+	}
+	return history[idx], nil
+}
+
+func (s *fileRulesetStore) History(_ context.Context, id string) ([]Ruleset, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	history, ok := s.rulesets[id]
+	if !ok {
+		return nil, coreErrors.NotFound()
+	}
+	return history, nil
+}
+
+func (s *fileRulesetStore) List(_ context.Context) ([]Ruleset, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	rulesets := make([]Ruleset, 0, len(s.rulesets))
+	for _, history := range s.rulesets {
+		rulesets = append(rulesets, history[len(history)-1])
+	}
+	s.lock.RUnlock()
+
+	sort.Slice(rulesets, func(i, j int) bool {
+		return rulesets[i].ID < rulesets[j].ID
+	})
+	return rulesets, nil
+}
+
+func (s *fileRulesetStore) Delete(ctx context.Context, id string) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	history, ok := s.rulesets[id]
+	if !ok {
+		return coreErrors.NotFound()
+	}
+	if err := owned(ctx, history); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.dir, id+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrapf(err, "could not delete rule set '%s'", id)
+	}
+	delete(s.rulesets, id)
+	return nil
+}
+
+func (s *fileRulesetStore) Check(ctx context.Context) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	return health.WritableDir(s.dir)(ctx)
+}
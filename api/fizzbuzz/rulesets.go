@@ -0,0 +1,199 @@
+package fizzbuzz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	nethttp "net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	// rulesetIDBytes is the number of random bytes of generated rule set identifiers
+	rulesetIDBytes = 8
+	// maxRulesetNameLength bounds the name of rule sets
+	maxRulesetNameLength = 128
+)
+
+type (
+	// Ruleset is a named list of rules, saved so that clients don't have to send them on every request.
+	// Each update creates a new immutable version.
+	Ruleset struct {
+		ID      string `json:"id"`
+		Version int    `json:"version"`
+		Name    string `json:"name"`
+		Rules   []Rule `json:"rules"`
+		// CreatedAt is the creation date of this version
+		CreatedAt time.Time `json:"createdAt"`
+		// CreatedBy is the identifier of the client which created this version
+		CreatedBy string `json:"createdBy"`
+	}
+	// RulesetRequest is the request body for the CreateRuleset endpoint
+	RulesetRequest struct {
+		Name  string `json:"name" required:"true" minLength:"1" maxLength:"128"`
+		Rules []Rule `json:"rules" required:"true" minItems:"1"`
+	}
+	// UpdateRulesetRequest is the request body for the UpdateRuleset endpoint
+	UpdateRulesetRequest struct {
+		// ID is not bound from the request body, which would otherwise override the path parameter
+		ID string `json:"-" param:"id" path:"id"`
+		// Version is the latest version known by the client, the update is rejected if the rule set has been updated
+		// since. The update is unconditional if omitted.
+		Version int    `json:"version,omitempty" minimum:"0"`
+		Name    string `json:"name" required:"true" minLength:"1" maxLength:"128"`
+		Rules   []Rule `json:"rules" required:"true" minItems:"1"`
+	}
+	// RulesetPath identifies a rule set through the request path
+	RulesetPath struct {
+		ID string `param:"id" path:"id"`
+	}
+	// RulesetIDRequest identifies a rule set, and optionally one of its versions
+	RulesetIDRequest struct {
+		ID      string `param:"id" path:"id"`
+		Version int    `query:"version" minimum:"0" description:"Version of the rule set, the latest one if omitted"`
+	}
+	// CreatedRuleset is returned by the CreateRuleset endpoint
+	CreatedRuleset struct {
+		Ruleset
+	}
+	// RulesetsResponse is returned by the ListRulesets and RulesetHistory endpoints
+	RulesetsResponse []Ruleset
+)
+
+var (
+	// CreateRuleset handles POST /api/v1/rulesets
+	CreateRuleset = http.Post("/api/v1/rulesets", createRuleset).WithScopes("rulesets:write")
+	// ListRulesets handles GET /api/v1/rulesets
+	ListRulesets = http.Get("/api/v1/rulesets", listRulesets).WithScopes("rulesets:read")
+	// GetRuleset handles GET /api/v1/rulesets/:id
+	GetRuleset = http.Get("/api/v1/rulesets/:id", getRuleset).WithScopes("rulesets:read")
+	// RulesetHistory handles GET /api/v1/rulesets/:id/versions
+	RulesetHistory = http.Get("/api/v1/rulesets/:id/versions", rulesetHistory).WithScopes("rulesets:read")
+	// UpdateRuleset handles PUT /api/v1/rulesets/:id
+	UpdateRuleset = http.Put("/api/v1/rulesets/:id", updateRuleset).WithScopes("rulesets:write")
+	// DeleteRuleset handles DELETE /api/v1/rulesets/:id
+	DeleteRuleset = http.Delete("/api/v1/rulesets/:id", deleteRuleset).WithScopes("rulesets:write")
+)
+
+// RulesetHandlers returns every rule set endpoint
+func RulesetHandlers() []http.Handler {
+	return []http.Handler{
+		CreateRuleset,
+		ListRulesets,
+		GetRuleset,
+		RulesetHistory,
+		UpdateRuleset,
+		DeleteRuleset,
+	}
+}
+
+// StatusCode returns 201, as a new rule set has been saved
+func (CreatedRuleset) StatusCode() int {
+	return nethttp.StatusCreated
+}
+
+func newRulesetID() (string, error) {
+	buf := make([]byte, rulesetIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newRuleset validates a new version of a rule set, its rules being compiled once to report errors up front
+func newRuleset(ctx context.Context, id string, version int, name string, rules []Rule) (Ruleset, error) {
+	if name == "" || len(name) > maxRulesetNameLength {
+		return Ruleset{}, errors.BadRequest(nil, "`name` must be between 1 and %d bytes long", maxRulesetNameLength)
+	}
+	if _, err := compileRules(rules); err != nil {
+		return Ruleset{}, err
+	}
+	return Ruleset{
+		ID:        id,
+		Version:   version,
+		Name:      name,
+		Rules:     rules,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: identity.ClientId(ctx),
+	}, nil
+}
+
+func createRuleset(ctx context.Context, request RulesetRequest) (*CreatedRuleset, error) {
+	id, err := newRulesetID()
+	if err != nil {
+		return nil, err
+	}
+	ruleset, err := newRuleset(ctx, id, 1, request.Name, request.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if err := Rulesets.Save(ctx, ruleset); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("rule set created", zap.String("ruleset.id", id))
+	return &CreatedRuleset{Ruleset: ruleset}, nil
+}
+
+func listRulesets(ctx context.Context, _ http.Empty) (*RulesetsResponse, error) {
+	rulesets, err := Rulesets.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := RulesetsResponse(rulesets)
+	return &response, nil
+}
+
+func getRuleset(ctx context.Context, request RulesetIDRequest) (*Ruleset, error) {
+	ruleset, err := Rulesets.Get(ctx, request.ID, request.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &ruleset, nil
+}
+
+func rulesetHistory(ctx context.Context, request RulesetPath) (*RulesetsResponse, error) {
+	history, err := Rulesets.History(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	response := RulesetsResponse(history)
+	return &response, nil
+}
+
+func updateRuleset(ctx context.Context, request UpdateRulesetRequest) (*Ruleset, error) {
+	current, err := Rulesets.Get(ctx, request.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if request.Version != 0 && request.Version != current.Version {
+		return nil, errors.Conflict(nil, "rule set '%s' is at version %d, not %d", request.ID, current.Version, request.Version)
+	}
+
+	ruleset, err := newRuleset(ctx, request.ID, current.Version+1, request.Name, request.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if err := Rulesets.Save(ctx, ruleset); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("rule set updated", zap.String("ruleset.id", ruleset.ID), zap.Int("ruleset.version", ruleset.Version))
+	return &ruleset, nil
+}
+
+func deleteRuleset(ctx context.Context, request RulesetPath) (*http.Empty, error) {
+	if err := Rulesets.Delete(ctx, request.ID); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("rule set deleted", zap.String("ruleset.id", request.ID))
+	return &http.Empty{}, nil
+}
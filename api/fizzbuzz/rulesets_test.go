@@ -0,0 +1,160 @@
+package fizzbuzz
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+func statusOf(err error) int {
+	if httpErr, ok := err.(*errors.Error); ok {
+		return httpErr.HttpCode
+	}
+	return 0
+}
+
+func TestFileRulesetStore(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newFileRulesetStore(dir)
+
+	fizz := []Rule{{Word: "fizz", When: Predicate{Kind: DivisibleBy, Divisor: 3}}}
+	buzz := []Rule{{Word: "buzz", When: Predicate{Kind: DivisibleBy, Divisor: 5}}}
+	a.NoError(store.Save(ctx, Ruleset{ID: "b", Version: 1, Name: "fizz", Rules: fizz, CreatedBy: identity.Anonymous}))
+	a.NoError(store.Save(ctx, Ruleset{ID: "b", Version: 2, Name: "buzz", Rules: buzz, CreatedBy: identity.Anonymous}))
+	a.NoError(store.Save(ctx, Ruleset{ID: "a", Version: 1, Name: "fizz", Rules: fizz, CreatedBy: identity.Anonymous}))
+
+	a.Equal(http.StatusConflict, statusOf(store.Save(ctx, Ruleset{ID: "b", Version: 2, Name: "stale"})))
+	a.Equal(http.StatusConflict, statusOf(store.Save(ctx, Ruleset{ID: "c", Version: 2, Name: "missing"})))
+	a.Equal(http.StatusBadRequest, statusOf(store.Save(ctx, Ruleset{ID: "../c", Version: 1})))
+
+	// rule sets survive a restart
+	store = newFileRulesetStore(dir)
+	latest, err := store.Get(ctx, "b", 0)
+	if a.NoError(err) {
+		a.Equal(2, latest.Version)
+		a.Equal(buzz, latest.Rules)
+	}
+	first, err := store.Get(ctx, "b", 1)
+	if a.NoError(err) {
+		a.Equal("fizz", first.Name)
+	}
+	_, err = store.Get(ctx, "b", 3)
+	a.Equal(http.StatusNotFound, statusOf(err))
+
+	history, err := store.History(ctx, "b")
+	if a.NoError(err) && a.Len(history, 2) {
+		a.Equal(1, history[0].Version)
+		a.Equal(2, history[1].Version)
+	}
+	rulesets, err := store.List(ctx)
+	if a.NoError(err) && a.Len(rulesets, 2) {
+		a.Equal("a", rulesets[0].ID)
+		a.Equal(2, rulesets[1].Version)
+	}
+
+	other := identity.Inject(ctx, identity.Identity{ClientId: "other"})
+	a.Equal(http.StatusForbidden, statusOf(store.Save(other, Ruleset{ID: "b", Version: 3, Name: "fizz", CreatedBy: "other"})))
+	a.Equal(http.StatusForbidden, statusOf(store.Delete(other, "b")))
+
+	a.NoError(store.Check(ctx))
+	a.Error(newFileRulesetStore(filepath.Join(dir, "b.json")).Check(ctx), "rule sets cannot be stored in a file")
+
+	a.NoError(store.Delete(ctx, "b"))
+	a.Equal(http.StatusNotFound, statusOf(store.Delete(ctx, "b")))
+	_, err = newFileRulesetStore(dir).Get(ctx, "b", 0)
+	a.Equal(http.StatusNotFound, statusOf(err))
+}
+
+func TestFileRulesetStoreMaxVersions(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newFileRulesetStore(dir)
+
+	maxVersions := config.Config.RulesetMaxVersions
+	config.Config.RulesetMaxVersions = 2
+	defer func() { config.Config.RulesetMaxVersions = maxVersions }()
+
+	for version := 1; version <= 4; version++ {
+		a.NoError(store.Save(ctx, Ruleset{ID: "a", Version: version, CreatedBy: identity.Anonymous}))
+	}
+	history, err := newFileRulesetStore(dir).History(ctx, "a")
+	if a.NoError(err) && a.Len(history, 2, "older versions are dropped") {
+		a.Equal(3, history[0].Version)
+		a.Equal(4, history[1].Version)
+	}
+	_, err = store.Get(ctx, "a", 2)
+	a.Equal(http.StatusNotFound, statusOf(err))
+	third, err := store.Get(ctx, "a", 3)
+	if a.NoError(err) {
+		a.Equal(3, third.Version)
+	}
+	a.Equal(http.StatusConflict, statusOf(store.Save(ctx, Ruleset{ID: "a", Version: 3, CreatedBy: identity.Anonymous})))
+	a.NoError(store.Save(ctx, Ruleset{ID: "a", Version: 5, CreatedBy: identity.Anonymous}))
+}
+
+func TestRulesets(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	defaultStore := Rulesets
+	Rulesets = newFileRulesetStore(t.TempDir())
+	defer func() { Rulesets = defaultStore }()
+
+	_, err := createRuleset(ctx, RulesetRequest{Name: "invalid", Rules: []Rule{{Word: "fizz"}}})
+	a.Equal(http.StatusBadRequest, statusOf(err))
+
+	created, err := createRuleset(ctx, RulesetRequest{Name: "fizz", Rules: []Rule{
+		{Word: "fizz", When: Predicate{Kind: DivisibleBy, Divisor: 3}},
+	}})
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(1, created.Version)
+
+	updated, err := updateRuleset(ctx, UpdateRulesetRequest{ID: created.ID, Version: 1, Name: "fizzbuzz", Rules: []Rule{
+		{Word: "fizz", When: Predicate{Kind: DivisibleBy, Divisor: 3}},
+		{Word: "buzz", When: Predicate{Kind: DivisibleBy, Divisor: 5}},
+	}})
+	if a.NoError(err) {
+		a.Equal(2, updated.Version)
+	}
+	_, err = updateRuleset(ctx, UpdateRulesetRequest{ID: created.ID, Version: 1, Name: "stale", Rules: created.Rules})
+	a.Equal(http.StatusConflict, statusOf(err))
+
+	response, err := fizzBuzz(ctx, Request{Ruleset: created.ID, Limit: 15})
+	if a.NoError(err) {
+		a.Equal(reference(Request{Int1: 3, Int2: 5, Limit: 15, Str1: "fizz", Str2: "buzz"}), *response)
+	}
+	response, err = fizzBuzz(ctx, Request{Ruleset: created.ID, Version: 1, Limit: 5})
+	if a.NoError(err) {
+		a.Equal(Response{"1", "2", "fizz", "4", "5"}, *response)
+	}
+	_, err = fizzBuzz(ctx, Request{Ruleset: created.ID, Int1: 3, Limit: 5})
+	a.Equal(http.StatusBadRequest, statusOf(err))
+	_, err = fizzBuzz(ctx, Request{Int1: 3, Int2: 5, Str1: "fizz", Str2: "buzz", Version: 1, Limit: 5})
+	a.Equal(http.StatusBadRequest, statusOf(err))
+
+	// rule sets are shared, but only their creator can change them
+	other := identity.Inject(ctx, identity.Identity{ClientId: "other"})
+	_, err = getRuleset(other, RulesetIDRequest{ID: created.ID})
+	a.NoError(err)
+	_, err = updateRuleset(other, UpdateRulesetRequest{ID: created.ID, Name: "hijacked", Rules: created.Rules})
+	a.Equal(http.StatusForbidden, statusOf(err))
+	_, err = deleteRuleset(other, RulesetPath{ID: created.ID})
+	a.Equal(http.StatusForbidden, statusOf(err))
+
+	_, err = deleteRuleset(ctx, RulesetPath{ID: created.ID})
+	a.NoError(err)
+	_, err = fizzBuzz(ctx, Request{Ruleset: created.ID, Limit: 15})
+	a.Equal(http.StatusNotFound, statusOf(err))
+}
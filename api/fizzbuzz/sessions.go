@@ -0,0 +1,187 @@
+package fizzbuzz
+
+import (
+	"context"
+	"math"
+
+	pkgErrors "github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	// SessionRules messages replace the rules of a session, with Rules or a saved Ruleset
+	SessionRules = "rules"
+	// SessionNext messages generate the next Count items of a session
+	SessionNext = "next"
+	// SessionSeek messages move a session to the item at Index
+	SessionSeek = "seek"
+)
+
+type (
+	// SessionRequest is the request for the FizzBuzzSession endpoint, setting the initial rules of the session.
+	// Sessions without initial rules must set them through a SessionRules message before generating items.
+	SessionRequest struct {
+		Int1    int64  `query:"int1" json:",omitempty"`
+		Int2    int64  `query:"int2" json:",omitempty"`
+		Str1    string `query:"str1" json:",omitempty"`
+		Str2    string `query:"str2" json:",omitempty"`
+		Ruleset string `query:"ruleset" json:",omitempty" description:"Identifier of a saved rule set"`
+		Version int    `query:"version" json:",omitempty" description:"Version of the rule set, the latest one if omitted"`
+	}
+	// SessionMessage is sent by clients to drive a session, every message being answered with a SessionReply
+	SessionMessage struct {
+		Type string `json:"type" required:"true" enum:"rules,next,seek"`
+		// ID is echoed by the reply, allowing clients to match replies with their messages
+		ID string `json:"id,omitempty"`
+		// Rules replace the rules of SessionRules messages, unless Ruleset is set
+		Rules   []Rule `json:"rules,omitempty"`
+		Ruleset string `json:"ruleset,omitempty"`
+		Version int    `json:"version,omitempty"`
+		// Count is the number of items generated by SessionNext messages
+		Count int64 `json:"count,omitempty"`
+		// Index is the index of the next item generated after SessionSeek messages
+		Index int64 `json:"index,omitempty"`
+	}
+	// SessionReply answers a SessionMessage, Error being set if it could not be processed
+	SessionReply struct {
+		Type string `json:"type"`
+		ID   string `json:"id,omitempty"`
+		// First is the index of the first of Items
+		First int64    `json:"first,omitempty"`
+		Items Response `json:"items,omitempty"`
+		// Next is the index of the next item of the session
+		Next  int64  `json:"next"`
+		Error string `json:"error,omitempty"`
+	}
+	// sessionState is the state of a FizzBuzzSession
+	sessionState struct {
+		generator generator
+		next      int64
+	}
+)
+
+var (
+	// FizzBuzzSession handles GET /api/v1/fizzbuzz/sessions, upgrading connections to WebSocket sessions
+	FizzBuzzSession = http.WebSocket("/api/v1/fizzbuzz/sessions", fizzBuzzSession).WithScopes("fizzbuzz:read")
+)
+
+// generator returns the initial generator of a session, nil if the request does not set any rules
+func (r SessionRequest) generator(ctx context.Context) (generator, error) {
+	if r == (SessionRequest{}) {
+		return nil, nil
+	}
+	// sessions are not limited, so neither is the template of their engine
+	request := Request{
+		Int1: r.Int1, Int2: r.Int2, Limit: math.MaxInt64, Str1: r.Str1, Str2: r.Str2,
+		Ruleset: r.Ruleset, Version: r.Version,
+	}
+	if err := request.validate(math.MaxInt64); err != nil {
+		return nil, err
+	}
+	return request.generator(ctx)
+}
+
+func fizzBuzzSession(ctx context.Context, request SessionRequest, session *http.Session[SessionMessage]) error {
+	log := logger.FromContext(ctx)
+
+	state := &sessionState{next: 1}
+	g, err := request.generator(ctx)
+	if err != nil {
+		return err
+	}
+	state.generator = g
+	log.Debug("fizzbuzz session started", zap.Bool("rules", g != nil))
+
+	for {
+		message, err := session.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		reply, err := state.handle(ctx, message)
+		if err != nil {
+			// invalid messages are answered with an error, the session going on
+			var httpErr *errors.Error
+			if !pkgErrors.As(err, &httpErr) {
+				return err
+			}
+			reply = SessionReply{Type: message.Type, ID: message.ID, Next: state.next, Error: httpErr.Message}
+		}
+		if err := session.Send(reply); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *sessionState) handle(ctx context.Context, message SessionMessage) (SessionReply, error) {
+	reply := SessionReply{Type: message.Type, ID: message.ID}
+	switch message.Type {
+	case SessionRules:
+		g, err := sessionRules(ctx, message)
+		if err != nil {
+			return reply, err
+		}
+		s.generator = g
+	case SessionNext:
+		items, err := s.generate(ctx, message.Count)
+		if err != nil {
+			return reply, err
+		}
+		reply.First, reply.Items = s.next, items
+		s.next += int64(len(items))
+	case SessionSeek:
+		if message.Index <= 0 {
+			return reply, errors.BadRequest(nil, "`index` must be a positive non-zero integer")
+		}
+		s.next = message.Index
+	default:
+		return reply, errors.BadRequest(nil, "unknown message type '%s', expected one of rules, next or seek", message.Type)
+	}
+	reply.Next = s.next
+	return reply, nil
+}
+
+// sessionRules compiles the rules of a SessionRules message
+func sessionRules(ctx context.Context, message SessionMessage) (generator, error) {
+	if message.Ruleset == "" {
+		return compileRules(message.Rules)
+	}
+	if len(message.Rules) > 0 {
+		return nil, errors.BadRequest(nil, "`ruleset` cannot be combined with `rules`")
+	}
+	ruleset, err := Rulesets.Get(ctx, message.Ruleset, message.Version)
+	if err != nil {
+		return nil, err
+	}
+	return compileRules(ruleset.Rules)
+}
+
+// generate returns the next count items of the session, without moving it
+func (s *sessionState) generate(ctx context.Context, count int64) (Response, error) {
+	if s.generator == nil {
+		return nil, errors.BadRequest(nil, "the session has no rules, a `rules` message must be sent first")
+	}
+	if count <= 0 {
+		return nil, errors.BadRequest(nil, "`count` must be a positive non-zero integer")
+	}
+	if count > config.Config.SessionMaxItems {
+		return nil, errors.BadRequest(nil, "`count` cannot exceed %d", config.Config.SessionMaxItems)
+	}
+	if s.next > math.MaxInt64-count {
+		return nil, errors.BadRequest(nil, "the session cannot generate items beyond %d", int64(math.MaxInt64))
+	}
+	if err := ItemsQuota.Consume(ctx, uint64(count)); err != nil {
+		return nil, err
+	}
+
+	items := make(Response, count)
+	renewBudget(s.generator)
+	if err := s.generator.fill(ctx, items, s.next); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
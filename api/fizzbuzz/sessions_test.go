@@ -0,0 +1,73 @@
+package fizzbuzz
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	coreHttp "github.com/Raphy42/industrial-fizz-buzz/core/http"
+)
+
+func TestFizzBuzzSession(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(coreHttp.NewServer(FizzBuzzSession))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/fizzbuzz/sessions"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	exchange := func(message SessionMessage) SessionReply {
+		var reply SessionReply
+		a.NoError(conn.WriteJSON(message))
+		a.NoError(conn.ReadJSON(&reply))
+		return reply
+	}
+
+	reply := exchange(SessionMessage{Type: SessionNext, ID: "1", Count: 3})
+	a.Equal("1", reply.ID)
+	a.NotEmpty(reply.Error, "sessions without rules cannot generate items")
+
+	reply = exchange(SessionMessage{Type: SessionRules, Rules: []Rule{
+		{Word: "Fizz", When: Predicate{Kind: DivisibleBy, Divisor: 3}},
+	}})
+	a.Empty(reply.Error)
+	reply = exchange(SessionMessage{Type: SessionNext, Count: 3})
+	a.Equal(SessionReply{Type: SessionNext, First: 1, Items: Response{"1", "2", "Fizz"}, Next: 4}, reply)
+
+	// rules can be replaced without moving the session
+	reply = exchange(SessionMessage{Type: SessionRules, Rules: []Rule{
+		{Word: "Buzz", When: Predicate{Kind: DivisibleBy, Divisor: 5}},
+	}})
+	a.Equal(int64(4), reply.Next)
+	reply = exchange(SessionMessage{Type: SessionNext, Count: 2})
+	a.Equal(Response{"4", "Buzz"}, reply.Items)
+
+	reply = exchange(SessionMessage{Type: SessionSeek, Index: 10})
+	a.Equal(int64(10), reply.Next)
+	reply = exchange(SessionMessage{Type: SessionNext, Count: 1})
+	a.Equal(Response{"Buzz"}, reply.Items)
+
+	reply = exchange(SessionMessage{Type: "jump"})
+	a.NotEmpty(reply.Error)
+	reply = exchange(SessionMessage{Type: SessionNext, Count: -1})
+	a.NotEmpty(reply.Error)
+
+	// sessions can be opened with initial rules, invalid ones closing the session
+	conn, _, err = websocket.DefaultDialer.Dial(url+"?int1=3&int2=5&str1=fizz&str2=buzz", nil)
+	if a.NoError(err) {
+		reply = exchange(SessionMessage{Type: SessionNext, Count: 5})
+		a.Equal(Response{"1", "2", "fizz", "4", "buzz"}, reply.Items)
+		_ = conn.Close()
+	}
+	conn, _, err = websocket.DefaultDialer.Dial(url+"?int1=-3&int2=5&str1=fizz&str2=buzz", nil)
+	if a.NoError(err) {
+		_, _, err = conn.ReadMessage()
+		a.True(websocket.IsCloseError(err, websocket.ClosePolicyViolation), "%v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package fizzbuzz
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	// defaultStreamInterval is the delay between two events of a stream whose interval is omitted
+	defaultStreamInterval = time.Second
+	// streamChunkSize is the number of items a stream generates at once, ahead of sending them
+	streamChunkSize = 256
+)
+
+type (
+	// StreamRequest is the request for the FizzBuzzStream endpoint, a Request along with the pacing of its events
+	StreamRequest struct {
+		Request
+		Interval int64 `query:"interval" json:",omitempty" description:"Delay between two events in milliseconds, defaults to 1000"`
+	}
+	// StreamItem is the data of the events sent by the FizzBuzzStream endpoint, the ID of each event being its Index.
+	// Clients reconnecting with the `Last-Event-ID` header resume the sequence right after this index.
+	StreamItem struct {
+		Index int64  `json:"index"`
+		Item  string `json:"item"`
+	}
+)
+
+var (
+	// FizzBuzzStream handles GET /api/v1/fizzbuzz/stream, sending items as Server-Sent Events
+	FizzBuzzStream = http.Stream("/api/v1/fizzbuzz/stream", fizzBuzzStream).WithScopes("fizzbuzz:read")
+)
+
+// interval returns the delay between two events of a validated StreamRequest
+func (r StreamRequest) interval() time.Duration {
+	if r.Interval == 0 {
+		return defaultStreamInterval
+	}
+	return time.Duration(r.Interval) * time.Millisecond
+}
+
+func (r StreamRequest) validate() error {
+	if err := r.Request.validate(config.Config.MaxItems); err != nil {
+		return err
+	}
+	minInterval := config.Config.StreamMinInterval
+	if r.Interval < 0 || (r.Interval != 0 && r.interval() < minInterval) {
+		return errors.BadRequest(nil, "`interval` query parameter cannot be lower than %d milliseconds", minInterval.Milliseconds())
+	}
+	return nil
+}
+
+// resumeIndex returns the index of the first item to send, following the last event received by the client
+func resumeIndex(lastEventID string) (int64, error) {
+	if lastEventID == "" {
+		return 1, nil
+	}
+	last, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil || last < 0 {
+		return 0, errors.BadRequest(err, "`Last-Event-ID` header must be the index of an item")
+	}
+	return last + 1, nil
+}
+
+func fizzBuzzStream(ctx context.Context, request StreamRequest, stream *http.EventStream[StreamItem]) error {
+	log := logger.FromContext(ctx)
+
+	if err := request.validate(); err != nil {
+		return err
+	}
+	first, err := resumeIndex(stream.LastEventID())
+	if err != nil {
+		return err
+	}
+	// the sequence already ended, the client must stop reconnecting
+	if first > request.Limit {
+		return nil
+	}
+	g, err := request.generator(ctx)
+	if err != nil {
+		return err
+	}
+	log.Debug("fizzbuzz stream started", zap.Int64("first", first), zap.Int64("limit", request.Limit))
+
+	ticker := time.NewTicker(request.interval())
+	defer ticker.Stop()
+	chunk := make(Response, streamChunkSize)
+	for start := first; first <= request.Limit; {
+		items := chunk
+		if remaining := request.Limit - first + 1; remaining < int64(len(items)) {
+			items = items[:remaining]
+		}
+		renewBudget(g)
+		if err := g.fill(ctx, items, first); err != nil {
+			return err
+		}
+
+		for i, item := range items {
+			index := first + int64(i)
+			// the first event is sent right away
+			if index > start {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-ticker.C:
+				}
+			}
+			// items are charged as they are sent, so that streams closed early or resumed are not charged twice
+			if err := ItemsQuota.Consume(ctx, 1); err != nil {
+				return err
+			}
+			event := http.Event[StreamItem]{ID: strconv.FormatInt(index, 10), Data: StreamItem{Index: index, Item: item}}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+		first += int64(len(items))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package fizzbuzz
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	coreHttp "github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/quota"
+)
+
+func TestFizzBuzzStream(t *testing.T) {
+	a := assert.New(t)
+	server := coreHttp.NewServer(FizzBuzzStream)
+
+	defaultQuota := ItemsQuota
+	ItemsQuota = quota.New("fizzbuzz.items", 10, nil)
+	defer func() { ItemsQuota = defaultQuota }()
+
+	serve := func(query, lastEventID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/fizzbuzz/stream?"+query, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("int1=3&int2=5&limit=3&str1=fizz&str2=buzz&interval=10", "")
+	if a.Equal(http.StatusOK, rec.Code) {
+		a.Equal(
+			"id: 1\ndata: {\"index\":1,\"item\":\"1\"}\n\n"+
+				"id: 2\ndata: {\"index\":2,\"item\":\"2\"}\n\n"+
+				"id: 3\ndata: {\"index\":3,\"item\":\"fizz\"}\n\n",
+			rec.Body.String(),
+		)
+	}
+
+	rec = serve("int1=3&int2=5&limit=5&str1=fizz&str2=buzz&interval=10", "3")
+	if a.Equal(http.StatusOK, rec.Code) {
+		a.Equal(
+			"id: 4\ndata: {\"index\":4,\"item\":\"4\"}\n\n"+
+				"id: 5\ndata: {\"index\":5,\"item\":\"buzz\"}\n\n",
+			rec.Body.String(),
+			"streams resume after the last event received",
+		)
+	}
+
+	rec = serve("int1=3&int2=5&limit=5&str1=fizz&str2=buzz&interval=10", "5")
+	a.Equal(http.StatusNoContent, rec.Code, "ended streams must not be resumed")
+	a.Equal(uint64(5), ItemsQuota.Status(identity.Anonymous).Used, "only sent items are charged")
+
+	rec = serve("int1=3&int2=5&limit=100&str1=fizz&str2=buzz&interval=10", "")
+	if a.Equal(http.StatusOK, rec.Code) {
+		a.Contains(rec.Body.String(), "id: 5\n")
+		a.NotContains(rec.Body.String(), "id: 6\n")
+		a.Contains(rec.Body.String(), "quota exceeded", "streams end once the quota is exhausted")
+	}
+	rec = serve("int1=3&int2=5&limit=100&str1=fizz&str2=buzz&interval=10", "5")
+	a.Equal(http.StatusTooManyRequests, rec.Code)
+
+	rec = serve("int1=3&int2=5&limit=5&str1=fizz&str2=buzz&interval=1", "")
+	a.Equal(http.StatusBadRequest, rec.Code, "interval is lower than the configured minimum")
+	rec = serve("int1=3&int2=5&limit=5&str1=fizz&str2=buzz", "fizz")
+	a.Equal(http.StatusBadRequest, rec.Code)
+	rec = serve("int1=-3&int2=5&limit=5&str1=fizz&str2=buzz", "")
+	a.Equal(http.StatusBadRequest, rec.Code)
+}
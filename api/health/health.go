@@ -3,14 +3,25 @@ package health
 import (
 	"context"
 
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
 	"github.com/Raphy42/industrial-fizz-buzz/core/http"
 )
 
 var (
-	// Handler handles GET /health
-	Handler = http.Get("/health", healthCheck)
+	// Handler handles GET /health, it is kept for compatibility and reports readiness
+	Handler = http.Get("/health", readinessCheck).Admin().Anonymous()
+	// Liveness handles GET /livez
+	Liveness = http.Get("/livez", livenessCheck).Admin().Anonymous()
+	// Readiness handles GET /readyz
+	Readiness = http.Get("/readyz", readinessCheck).Admin().Anonymous()
 )
 
-func healthCheck(_ context.Context, _ http.Empty) (*http.Empty, error) {
-	return &http.Empty{}, nil
+func livenessCheck(ctx context.Context, _ http.Empty) (*health.Report, error) {
+	report := health.Run(ctx, health.Liveness)
+	return &report, nil
+}
+
+func readinessCheck(ctx context.Context, _ http.Empty) (*health.Report, error) {
+	report := health.Run(ctx, health.Readiness)
+	return &report, nil
 }
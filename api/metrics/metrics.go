@@ -3,41 +3,76 @@ package metrics
 import (
 	"context"
 	"encoding/json"
+	"sort"
 
 	"github.com/pkg/errors"
 
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
 	"github.com/Raphy42/industrial-fizz-buzz/core/http"
 	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
 )
 
+const (
+	// SnapshotEvent is the name of the events describing the top requests when a feed starts
+	SnapshotEvent = "snapshot"
+	// TopEvent is the name of the events sent when a top request is replaced
+	TopEvent = "top"
+	// ThresholdEvent is the name of the events sent when the hit count of a top request crosses a threshold
+	ThresholdEvent = "threshold"
+)
+
 type (
 	// Response used by both the AllMetrics and FizzBuzzMetrics endpoints
 	Response struct {
 		Request map[string]any `json:"request"`
 		Hits    uint           `json:"hits"`
 	}
+	// FeedRequest is the request for the TopRequestFeed endpoint
+	FeedRequest struct {
+		Routes     []string `query:"route" description:"Routes whose top request is watched, every route if omitted"`
+		Thresholds []uint   `query:"threshold" description:"Hit counts sending an event once crossed by a top request, the configured ones if omitted"`
+	}
+	// TopRequestChange is the data of the events sent by the TopRequestFeed endpoint
+	TopRequestChange struct {
+		Route string `json:"route"`
+		Response
+		// Threshold is the highest threshold crossed by Hits since the previous event of the route
+		Threshold uint `json:"threshold,omitempty"`
+	}
 )
 
 var (
 	// FizzBuzzMetrics handles GET /api/v1/metrics/request/fizzbuzz
-	FizzBuzzMetrics = http.Get("/api/v1/metrics/request/fizzbuzz", fizzbuzzMetrics)
+	FizzBuzzMetrics = http.Get("/api/v1/metrics/request/fizzbuzz", fizzbuzzMetrics).WithScopes("metrics:read")
 	// AllMetrics handles GET /api/v1/metrics/request
-	AllMetrics = http.Get("/api/v1/metrics/request", listMetrics)
+	AllMetrics = http.Get("/api/v1/metrics/request", listMetrics).WithScopes("metrics:read")
+	// TopRequestFeed handles GET /api/v1/metrics/request/stream, sending top request changes as Server-Sent Events
+	TopRequestFeed = http.Stream("/api/v1/metrics/request/stream", topRequestFeed).WithScopes("metrics:read")
 )
 
+func newResponse(top metrics.TopRequest) (Response, error) {
+	var req map[string]any
+	if top.Bytes != nil {
+		if err := json.Unmarshal(top.Bytes, &req); err != nil {
+			return Response{}, errors.Wrapf(err, "json unmarshaling of top request body for '%s' failed", top.Route)
+		}
+	}
+	return Response{
+		Request: req,
+		Hits:    top.Hits,
+	}, nil
+}
+
 func listMetrics(_ context.Context, _ http.Empty) (*map[string]Response, error) {
 	responses := make(map[string]Response)
 	result, err := metrics.Top()
+	if err != nil {
+		return nil, err
+	}
 	for route, top := range result {
-		var req map[string]any
-		if top.Bytes != nil {
-			if err = json.Unmarshal(top.Bytes, &req); err != nil {
-				return nil, errors.Wrapf(err, "json unmarshaling of top request body for '%s' failed", top.Route)
-			}
-		}
-		responses[route] = Response{
-			Request: req,
-			Hits:    top.Hits,
+		if responses[route], err = newResponse(top); err != nil {
+			return nil, err
 		}
 	}
 	return &responses, nil
@@ -50,15 +85,57 @@ func fizzbuzzMetrics(_ context.Context, _ http.Empty) (*Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	top := result[endpoint]
-	var req map[string]any
-	if top.Bytes != nil {
-		if err = json.Unmarshal(top.Bytes, &req); err != nil {
-			return nil, errors.Wrapf(err, "json unmarshaling of top request body for '%s' failed", top.Route)
+	response, err := newResponse(result[endpoint])
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func sendTopRequest(stream *http.EventStream[TopRequestChange], name string, top metrics.TopRequest, threshold uint) error {
+	response, err := newResponse(top)
+	if err != nil {
+		return err
+	}
+	change := TopRequestChange{Route: top.Route, Response: response, Threshold: threshold}
+	return stream.Send(http.Event[TopRequestChange]{Name: name, Data: change})
+}
+
+// topRequestFeed sends the current top requests, followed by their changes
+func topRequestFeed(ctx context.Context, request FeedRequest, stream *http.EventStream[TopRequestChange]) error {
+	thresholds := request.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = config.Config.MetricsFeedThresholds
+	}
+	// subscribing first, so that no change is missed between the snapshot and the feed
+	changes, err := metrics.Subscribe(ctx, metrics.SubscribeOptions{
+		Routes:     request.Routes,
+		Thresholds: thresholds,
+		Interval:   config.Config.MetricsFeedInterval,
+	})
+	if err != nil {
+		return err
+	}
+	tops, err := metrics.Top(request.Routes...)
+	if err != nil {
+		return err
+	}
+	routes := generics.MapKeys(tops)
+	sort.Strings(routes)
+	for _, route := range routes {
+		if err := sendTopRequest(stream, SnapshotEvent, tops[route], 0); err != nil {
+			return err
 		}
 	}
-	return &Response{
-		Request: req,
-		Hits:    top.Hits,
-	}, nil
+
+	for change := range changes {
+		name := ThresholdEvent
+		if change.Changed {
+			name = TopEvent
+		}
+		if err := sendTopRequest(stream, name, change.Top, change.Threshold); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
 }
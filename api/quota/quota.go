@@ -0,0 +1,23 @@
+package quota
+
+import (
+	"context"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/quota"
+)
+
+type (
+	// Response returned by the Quota endpoint
+	Response []quota.Status
+)
+
+var (
+	// Quota handles GET /api/v1/quota, returning every quota status of the calling client
+	Quota = http.Get("/api/v1/quota", quotaStatus)
+)
+
+func quotaStatus(ctx context.Context, _ http.Empty) (*Response, error) {
+	response := Response(quota.StatusOf(ctx))
+	return &response, nil
+}
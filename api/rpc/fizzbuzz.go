@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Raphy42/industrial-fizz-buzz/api/fizzbuzz"
+	"github.com/Raphy42/industrial-fizz-buzz/api/rpc/fizzbuzzpb"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+)
+
+// fizzBuzzServer serves the FizzBuzz service through the fizzbuzz.FizzBuzz and fizzbuzz.FizzBuzzStream handlers
+type fizzBuzzServer struct {
+	fizzbuzzpb.UnimplementedFizzBuzzServer
+}
+
+func toRequest(request *fizzbuzzpb.GenerateRequest) fizzbuzz.Request {
+	return fizzbuzz.Request{
+		Int1:    request.GetInt1(),
+		Int2:    request.GetInt2(),
+		Limit:   request.GetLimit(),
+		Str1:    request.GetStr1(),
+		Str2:    request.GetStr2(),
+		Ruleset: request.GetRuleset(),
+		Version: int(request.GetVersion()),
+	}
+}
+
+// Generate is the unary counterpart of GET /api/v1/fizzbuzz
+func (fizzBuzzServer) Generate(ctx context.Context, request *fizzbuzzpb.GenerateRequest) (*fizzbuzzpb.GenerateResponse, error) {
+	response, err := http.Call[fizzbuzz.Request, fizzbuzz.Response](ctx, fizzbuzz.FizzBuzz, toRequest(request))
+	if err != nil {
+		return nil, err
+	}
+	return &fizzbuzzpb.GenerateResponse{Items: *response}, nil
+}
+
+// Stream is the server-streaming counterpart of GET /api/v1/fizzbuzz/stream, ResumeAfter standing for the
+// `Last-Event-ID` header
+func (fizzBuzzServer) Stream(request *fizzbuzzpb.StreamRequest, stream fizzbuzzpb.FizzBuzz_StreamServer) error {
+	if request.GetResumeAfter() < 0 {
+		return errors.BadRequest(nil, "`resume_after` cannot be negative")
+	}
+	var lastEventID string
+	if request.GetResumeAfter() > 0 {
+		lastEventID = strconv.FormatInt(request.GetResumeAfter(), 10)
+	}
+
+	streamRequest := fizzbuzz.StreamRequest{
+		Request:  toRequest(request.GetRequest()),
+		Interval: request.GetIntervalMs(),
+	}
+	return http.CallStream(stream.Context(), fizzbuzz.FizzBuzzStream, streamRequest, lastEventID,
+		func(event http.Event[fizzbuzz.StreamItem]) error {
+			return stream.Send(&fizzbuzzpb.StreamItem{Index: event.Data.Index, Item: event.Data.Item})
+		})
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/Raphy42/industrial-fizz-buzz/api/rpc/fizzbuzzpb"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+// itemStream collects the items sent through a FizzBuzz stream
+type itemStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	items []*fizzbuzzpb.StreamItem
+}
+
+func (s *itemStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *itemStream) Send(item *fizzbuzzpb.StreamItem) error {
+	s.items = append(s.items, item)
+	return nil
+}
+
+func statusOf(err error) int {
+	if httpErr, ok := err.(*errors.Error); ok {
+		return httpErr.HttpCode
+	}
+	return 0
+}
+
+func TestGenerate(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	server := fizzBuzzServer{}
+	request := &fizzbuzzpb.GenerateRequest{Int1: 3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"}
+
+	response, err := server.Generate(ctx, request)
+	if a.NoError(err) {
+		a.Equal([]string{"1", "2", "fizz", "4", "buzz"}, response.Items)
+	}
+	_, err = server.Generate(ctx, &fizzbuzzpb.GenerateRequest{Int1: -3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"})
+	a.Equal(http.StatusBadRequest, statusOf(err), "requests are validated like HTTP ones")
+
+	restricted := identity.Inject(ctx, identity.Identity{ClientId: "dashboard", Scopes: []string{"metrics:read"}})
+	_, err = server.Generate(restricted, request)
+	a.Equal(http.StatusForbidden, statusOf(err), "scopes are checked like HTTP ones")
+}
+
+func TestStream(t *testing.T) {
+	a := assert.New(t)
+	server := fizzBuzzServer{}
+	request := &fizzbuzzpb.GenerateRequest{Int1: 3, Int2: 5, Limit: 5, Str1: "fizz", Str2: "buzz"}
+
+	stream := &itemStream{ctx: context.Background()}
+	a.NoError(server.Stream(&fizzbuzzpb.StreamRequest{Request: request, IntervalMs: 10, ResumeAfter: 3}, stream))
+	if a.Len(stream.items, 2, "streams resume after the last item received") {
+		a.Equal(int64(4), stream.items[0].Index)
+		a.Equal("buzz", stream.items[1].Item)
+	}
+
+	stream = &itemStream{ctx: context.Background()}
+	err := server.Stream(&fizzbuzzpb.StreamRequest{Request: request, IntervalMs: 1}, stream)
+	a.Equal(http.StatusBadRequest, statusOf(err), "interval is lower than the configured minimum")
+	err = server.Stream(&fizzbuzzpb.StreamRequest{Request: request, ResumeAfter: -1}, stream)
+	a.Equal(http.StatusBadRequest, statusOf(err))
+	a.NoError(server.Stream(&fizzbuzzpb.StreamRequest{Request: request, ResumeAfter: 5}, stream))
+	a.Empty(stream.items, "ended streams send nothing")
+}
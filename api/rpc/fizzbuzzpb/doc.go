@@ -0,0 +1,4 @@
+// Package fizzbuzzpb contains the protocol buffers and gRPC bindings generated from fizzbuzz.proto.
+package fizzbuzzpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative fizzbuzz.proto
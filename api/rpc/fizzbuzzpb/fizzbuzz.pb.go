@@ -0,0 +1,903 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: fizzbuzz.proto
+
+// Package fizzbuzz.v1 exposes the fizzbuzz and metrics endpoints of the HTTP API to gRPC clients.
+// Requests are validated, counted and authorized exactly like their HTTP counterparts.
+
+package fizzbuzzpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TopRequestChange_Kind int32
+
+const (
+	TopRequestChange_KIND_UNSPECIFIED TopRequestChange_Kind = 0
+	// The top request when the watch started.
+	TopRequestChange_KIND_SNAPSHOT TopRequestChange_Kind = 1
+	// The top request has been replaced, or reset.
+	TopRequestChange_KIND_TOP TopRequestChange_Kind = 2
+	// The hit count of the top request crossed a threshold.
+	TopRequestChange_KIND_THRESHOLD TopRequestChange_Kind = 3
+)
+
+// Enum value maps for TopRequestChange_Kind.
+var (
+	TopRequestChange_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "KIND_SNAPSHOT",
+		2: "KIND_TOP",
+		3: "KIND_THRESHOLD",
+	}
+	TopRequestChange_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"KIND_SNAPSHOT":    1,
+		"KIND_TOP":         2,
+		"KIND_THRESHOLD":   3,
+	}
+)
+
+func (x TopRequestChange_Kind) Enum() *TopRequestChange_Kind {
+	p := new(TopRequestChange_Kind)
+	*p = x
+	return p
+}
+
+func (x TopRequestChange_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TopRequestChange_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_fizzbuzz_proto_enumTypes[0].Descriptor()
+}
+
+func (TopRequestChange_Kind) Type() protoreflect.EnumType {
+	return &file_fizzbuzz_proto_enumTypes[0]
+}
+
+func (x TopRequestChange_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TopRequestChange_Kind.Descriptor instead.
+func (TopRequestChange_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{8, 0}
+}
+
+type GenerateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Int1  int64  `protobuf:"varint,1,opt,name=int1,proto3" json:"int1,omitempty"`
+	Int2  int64  `protobuf:"varint,2,opt,name=int2,proto3" json:"int2,omitempty"`
+	Limit int64  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Str1  string `protobuf:"bytes,4,opt,name=str1,proto3" json:"str1,omitempty"`
+	Str2  string `protobuf:"bytes,5,opt,name=str2,proto3" json:"str2,omitempty"`
+	// Identifier of a saved rule set, replacing int1, int2, str1 and str2.
+	Ruleset string `protobuf:"bytes,6,opt,name=ruleset,proto3" json:"ruleset,omitempty"`
+	// Version of the rule set, the latest one if omitted.
+	Version int32 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *GenerateRequest) Reset() {
+	*x = GenerateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRequest) ProtoMessage() {}
+
+func (x *GenerateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRequest) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GenerateRequest) GetInt1() int64 {
+	if x != nil {
+		return x.Int1
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetInt2() int64 {
+	if x != nil {
+		return x.Int2
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetStr1() string {
+	if x != nil {
+		return x.Str1
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetStr2() string {
+	if x != nil {
+		return x.Str2
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetRuleset() string {
+	if x != nil {
+		return x.Ruleset
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type GenerateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Items []string `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+}
+
+func (x *GenerateResponse) Reset() {
+	*x = GenerateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GenerateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateResponse) ProtoMessage() {}
+
+func (x *GenerateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateResponse.ProtoReflect.Descriptor instead.
+func (*GenerateResponse) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GenerateResponse) GetItems() []string {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type StreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Request *GenerateRequest `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	// Delay between two items in milliseconds, defaults to 1000.
+	IntervalMs int64 `protobuf:"varint,2,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	// Index of the last item received by a client resuming a stream, the stream starting right after it.
+	ResumeAfter int64 `protobuf:"varint,3,opt,name=resume_after,json=resumeAfter,proto3" json:"resume_after,omitempty"`
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamRequest) GetRequest() *GenerateRequest {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *StreamRequest) GetIntervalMs() int64 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+func (x *StreamRequest) GetResumeAfter() int64 {
+	if x != nil {
+		return x.ResumeAfter
+	}
+	return 0
+}
+
+type StreamItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Index int64  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Item  string `protobuf:"bytes,2,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (x *StreamItem) Reset() {
+	*x = StreamItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamItem) ProtoMessage() {}
+
+func (x *StreamItem) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamItem.ProtoReflect.Descriptor instead.
+func (*StreamItem) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamItem) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *StreamItem) GetItem() string {
+	if x != nil {
+		return x.Item
+	}
+	return ""
+}
+
+type TopRequestsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Routes whose top request is returned, every route if empty.
+	Routes []string `protobuf:"bytes,1,rep,name=routes,proto3" json:"routes,omitempty"`
+}
+
+func (x *TopRequestsRequest) Reset() {
+	*x = TopRequestsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TopRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopRequestsRequest) ProtoMessage() {}
+
+func (x *TopRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopRequestsRequest.ProtoReflect.Descriptor instead.
+func (*TopRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TopRequestsRequest) GetRoutes() []string {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+type TopRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Route string `protobuf:"bytes,1,opt,name=route,proto3" json:"route,omitempty"`
+	// The top request of the route, unset once the metrics of the route have been reset.
+	Request *structpb.Struct `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
+	Hits    uint64           `protobuf:"varint,3,opt,name=hits,proto3" json:"hits,omitempty"`
+}
+
+func (x *TopRequest) Reset() {
+	*x = TopRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopRequest) ProtoMessage() {}
+
+func (x *TopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopRequest.ProtoReflect.Descriptor instead.
+func (*TopRequest) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TopRequest) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+func (x *TopRequest) GetRequest() *structpb.Struct {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *TopRequest) GetHits() uint64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
+type TopRequestsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Top requests, sorted by route.
+	Tops []*TopRequest `protobuf:"bytes,1,rep,name=tops,proto3" json:"tops,omitempty"`
+}
+
+func (x *TopRequestsResponse) Reset() {
+	*x = TopRequestsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TopRequestsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopRequestsResponse) ProtoMessage() {}
+
+func (x *TopRequestsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopRequestsResponse.ProtoReflect.Descriptor instead.
+func (*TopRequestsResponse) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TopRequestsResponse) GetTops() []*TopRequest {
+	if x != nil {
+		return x.Tops
+	}
+	return nil
+}
+
+type WatchTopRequestsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Routes whose top request is watched, every route if empty.
+	Routes []string `protobuf:"bytes,1,rep,name=routes,proto3" json:"routes,omitempty"`
+	// Hit counts sending a change once crossed by a top request, the configured ones if empty.
+	Thresholds []uint64 `protobuf:"varint,2,rep,packed,name=thresholds,proto3" json:"thresholds,omitempty"`
+}
+
+func (x *WatchTopRequestsRequest) Reset() {
+	*x = WatchTopRequestsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchTopRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTopRequestsRequest) ProtoMessage() {}
+
+func (x *WatchTopRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTopRequestsRequest.ProtoReflect.Descriptor instead.
+func (*WatchTopRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchTopRequestsRequest) GetRoutes() []string {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *WatchTopRequestsRequest) GetThresholds() []uint64 {
+	if x != nil {
+		return x.Thresholds
+	}
+	return nil
+}
+
+type TopRequestChange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Kind TopRequestChange_Kind `protobuf:"varint,1,opt,name=kind,proto3,enum=fizzbuzz.v1.TopRequestChange_Kind" json:"kind,omitempty"`
+	Top  *TopRequest           `protobuf:"bytes,2,opt,name=top,proto3" json:"top,omitempty"`
+	// Highest threshold crossed by the hit count since the previous change of the route.
+	Threshold uint64 `protobuf:"varint,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
+}
+
+func (x *TopRequestChange) Reset() {
+	*x = TopRequestChange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fizzbuzz_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TopRequestChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopRequestChange) ProtoMessage() {}
+
+func (x *TopRequestChange) ProtoReflect() protoreflect.Message {
+	mi := &file_fizzbuzz_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopRequestChange.ProtoReflect.Descriptor instead.
+func (*TopRequestChange) Descriptor() ([]byte, []int) {
+	return file_fizzbuzz_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TopRequestChange) GetKind() TopRequestChange_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return TopRequestChange_KIND_UNSPECIFIED
+}
+
+func (x *TopRequestChange) GetTop() *TopRequest {
+	if x != nil {
+		return x.Top
+	}
+	return nil
+}
+
+func (x *TopRequestChange) GetThreshold() uint64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+var File_fizzbuzz_proto protoreflect.FileDescriptor
+
+var file_fizzbuzz_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x0b, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x73,
+	0x74, 0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xab, 0x01, 0x0a, 0x0f,
+	0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x69, 0x6e, 0x74, 0x31, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x69,
+	0x6e, 0x74, 0x31, 0x12, 0x12, 0x0a, 0x04, 0x69, 0x6e, 0x74, 0x32, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x04, 0x69, 0x6e, 0x74, 0x32, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x73, 0x74, 0x72, 0x31, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x74, 0x72,
+	0x31, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x72, 0x32, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x73, 0x74, 0x72, 0x32, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x65, 0x74,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x12,
+	0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x28, 0x0a, 0x10, 0x47, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x69, 0x74,
+	0x65, 0x6d, 0x73, 0x22, 0x8b, 0x01, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x36, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a,
+	0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x52, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a,
+	0x0b, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0a, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x4d, 0x73, 0x12, 0x21,
+	0x0a, 0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x41, 0x66, 0x74, 0x65,
+	0x72, 0x22, 0x36, 0x0a, 0x0a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x74, 0x65, 0x6d, 0x12,
+	0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x12, 0x0a, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x22, 0x2c, 0x0a, 0x12, 0x54, 0x6f, 0x70,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x22, 0x69, 0x0a, 0x0a, 0x54, 0x6f, 0x70, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x12, 0x31, 0x0a, 0x07, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53,
+	0x74, 0x72, 0x75, 0x63, 0x74, 0x52, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x69, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x68, 0x69,
+	0x74, 0x73, 0x22, 0x42, 0x0a, 0x13, 0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2b, 0x0a, 0x04, 0x74, 0x6f, 0x70,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75,
+	0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x52, 0x04, 0x74, 0x6f, 0x70, 0x73, 0x22, 0x51, 0x0a, 0x17, 0x57, 0x61, 0x74, 0x63, 0x68, 0x54,
+	0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x68, 0x72,
+	0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x04, 0x52, 0x0a, 0x74,
+	0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x73, 0x22, 0xe6, 0x01, 0x0a, 0x10, 0x54, 0x6f,
+	0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x36,
+	0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x22, 0x2e, 0x66,
+	0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6f, 0x70, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x4b, 0x69, 0x6e, 0x64,
+	0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x29, 0x0a, 0x03, 0x74, 0x6f, 0x70, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76,
+	0x31, 0x2e, 0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x03, 0x74, 0x6f,
+	0x70, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x22,
+	0x51, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x10, 0x4b, 0x49, 0x4e, 0x44, 0x5f,
+	0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x11, 0x0a,
+	0x0d, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x53, 0x4e, 0x41, 0x50, 0x53, 0x48, 0x4f, 0x54, 0x10, 0x01,
+	0x12, 0x0c, 0x0a, 0x08, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x54, 0x4f, 0x50, 0x10, 0x02, 0x12, 0x12,
+	0x0a, 0x0e, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x54, 0x48, 0x52, 0x45, 0x53, 0x48, 0x4f, 0x4c, 0x44,
+	0x10, 0x03, 0x32, 0x94, 0x01, 0x0a, 0x08, 0x46, 0x69, 0x7a, 0x7a, 0x42, 0x75, 0x7a, 0x7a, 0x12,
+	0x47, 0x0a, 0x08, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x12, 0x1c, 0x2e, 0x66, 0x69,
+	0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61,
+	0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x66, 0x69, 0x7a, 0x7a,
+	0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3f, 0x0a, 0x06, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x12, 0x1a, 0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31,
+	0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17,
+	0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x49, 0x74, 0x65, 0x6d, 0x30, 0x01, 0x32, 0xb6, 0x01, 0x0a, 0x07, 0x4d, 0x65,
+	0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x50, 0x0a, 0x0b, 0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x73, 0x12, 0x1f, 0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e,
+	0x76, 0x31, 0x2e, 0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a,
+	0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x59, 0x0a, 0x10, 0x57, 0x61, 0x74, 0x63, 0x68,
+	0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x12, 0x24, 0x2e, 0x66, 0x69,
+	0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x54,
+	0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1d, 0x2e, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x2e, 0x76, 0x31, 0x2e,
+	0x54, 0x6f, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x30, 0x01, 0x42, 0x3c, 0x5a, 0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x52, 0x61, 0x70, 0x68, 0x79, 0x34, 0x32, 0x2f, 0x69, 0x6e, 0x64, 0x75, 0x73, 0x74, 0x72,
+	0x69, 0x61, 0x6c, 0x2d, 0x66, 0x69, 0x7a, 0x7a, 0x2d, 0x62, 0x75, 0x7a, 0x7a, 0x2f, 0x61, 0x70,
+	0x69, 0x2f, 0x72, 0x70, 0x63, 0x2f, 0x66, 0x69, 0x7a, 0x7a, 0x62, 0x75, 0x7a, 0x7a, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_fizzbuzz_proto_rawDescOnce sync.Once
+	file_fizzbuzz_proto_rawDescData = file_fizzbuzz_proto_rawDesc
+)
+
+func file_fizzbuzz_proto_rawDescGZIP() []byte {
+	file_fizzbuzz_proto_rawDescOnce.Do(func() {
+		file_fizzbuzz_proto_rawDescData = protoimpl.X.CompressGZIP(file_fizzbuzz_proto_rawDescData)
+	})
+	return file_fizzbuzz_proto_rawDescData
+}
+
+var file_fizzbuzz_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_fizzbuzz_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_fizzbuzz_proto_goTypes = []interface{}{
+	(TopRequestChange_Kind)(0),      // 0: fizzbuzz.v1.TopRequestChange.Kind
+	(*GenerateRequest)(nil),         // 1: fizzbuzz.v1.GenerateRequest
+	(*GenerateResponse)(nil),        // 2: fizzbuzz.v1.GenerateResponse
+	(*StreamRequest)(nil),           // 3: fizzbuzz.v1.StreamRequest
+	(*StreamItem)(nil),              // 4: fizzbuzz.v1.StreamItem
+	(*TopRequestsRequest)(nil),      // 5: fizzbuzz.v1.TopRequestsRequest
+	(*TopRequest)(nil),              // 6: fizzbuzz.v1.TopRequest
+	(*TopRequestsResponse)(nil),     // 7: fizzbuzz.v1.TopRequestsResponse
+	(*WatchTopRequestsRequest)(nil), // 8: fizzbuzz.v1.WatchTopRequestsRequest
+	(*TopRequestChange)(nil),        // 9: fizzbuzz.v1.TopRequestChange
+	(*structpb.Struct)(nil),         // 10: google.protobuf.Struct
+}
+var file_fizzbuzz_proto_depIdxs = []int32{
+	1,  // 0: fizzbuzz.v1.StreamRequest.request:type_name -> fizzbuzz.v1.GenerateRequest
+	10, // 1: fizzbuzz.v1.TopRequest.request:type_name -> google.protobuf.Struct
+	6,  // 2: fizzbuzz.v1.TopRequestsResponse.tops:type_name -> fizzbuzz.v1.TopRequest
+	0,  // 3: fizzbuzz.v1.TopRequestChange.kind:type_name -> fizzbuzz.v1.TopRequestChange.Kind
+	6,  // 4: fizzbuzz.v1.TopRequestChange.top:type_name -> fizzbuzz.v1.TopRequest
+	1,  // 5: fizzbuzz.v1.FizzBuzz.Generate:input_type -> fizzbuzz.v1.GenerateRequest
+	3,  // 6: fizzbuzz.v1.FizzBuzz.Stream:input_type -> fizzbuzz.v1.StreamRequest
+	5,  // 7: fizzbuzz.v1.Metrics.TopRequests:input_type -> fizzbuzz.v1.TopRequestsRequest
+	8,  // 8: fizzbuzz.v1.Metrics.WatchTopRequests:input_type -> fizzbuzz.v1.WatchTopRequestsRequest
+	2,  // 9: fizzbuzz.v1.FizzBuzz.Generate:output_type -> fizzbuzz.v1.GenerateResponse
+	4,  // 10: fizzbuzz.v1.FizzBuzz.Stream:output_type -> fizzbuzz.v1.StreamItem
+	7,  // 11: fizzbuzz.v1.Metrics.TopRequests:output_type -> fizzbuzz.v1.TopRequestsResponse
+	9,  // 12: fizzbuzz.v1.Metrics.WatchTopRequests:output_type -> fizzbuzz.v1.TopRequestChange
+	9,  // [9:13] is the sub-list for method output_type
+	5,  // [5:9] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_fizzbuzz_proto_init() }
+func file_fizzbuzz_proto_init() {
+	if File_fizzbuzz_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_fizzbuzz_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GenerateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GenerateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamItem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TopRequestsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TopRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TopRequestsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchTopRequestsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_fizzbuzz_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TopRequestChange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_fizzbuzz_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_fizzbuzz_proto_goTypes,
+		DependencyIndexes: file_fizzbuzz_proto_depIdxs,
+		EnumInfos:         file_fizzbuzz_proto_enumTypes,
+		MessageInfos:      file_fizzbuzz_proto_msgTypes,
+	}.Build()
+	File_fizzbuzz_proto = out.File
+	file_fizzbuzz_proto_rawDesc = nil
+	file_fizzbuzz_proto_goTypes = nil
+	file_fizzbuzz_proto_depIdxs = nil
+}
@@ -0,0 +1,340 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: fizzbuzz.proto
+
+// Package fizzbuzz.v1 exposes the fizzbuzz and metrics endpoints of the HTTP API to gRPC clients.
+// Requests are validated, counted and authorized exactly like their HTTP counterparts.
+
+package fizzbuzzpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FizzBuzz_Generate_FullMethodName = "/fizzbuzz.v1.FizzBuzz/Generate"
+	FizzBuzz_Stream_FullMethodName   = "/fizzbuzz.v1.FizzBuzz/Stream"
+)
+
+// FizzBuzzClient is the client API for FizzBuzz service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FizzBuzzClient interface {
+	// Generate returns a whole sequence at once.
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error)
+	// Stream sends the items of a sequence one by one, paced by the interval of the request.
+	Stream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (FizzBuzz_StreamClient, error)
+}
+
+type fizzBuzzClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFizzBuzzClient(cc grpc.ClientConnInterface) FizzBuzzClient {
+	return &fizzBuzzClient{cc}
+}
+
+func (c *fizzBuzzClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error) {
+	out := new(GenerateResponse)
+	err := c.cc.Invoke(ctx, FizzBuzz_Generate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fizzBuzzClient) Stream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (FizzBuzz_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FizzBuzz_ServiceDesc.Streams[0], FizzBuzz_Stream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fizzBuzzStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FizzBuzz_StreamClient interface {
+	Recv() (*StreamItem, error)
+	grpc.ClientStream
+}
+
+type fizzBuzzStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *fizzBuzzStreamClient) Recv() (*StreamItem, error) {
+	m := new(StreamItem)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FizzBuzzServer is the server API for FizzBuzz service.
+// All implementations must embed UnimplementedFizzBuzzServer
+// for forward compatibility
+type FizzBuzzServer interface {
+	// Generate returns a whole sequence at once.
+	Generate(context.Context, *GenerateRequest) (*GenerateResponse, error)
+	// Stream sends the items of a sequence one by one, paced by the interval of the request.
+	Stream(*StreamRequest, FizzBuzz_StreamServer) error
+	mustEmbedUnimplementedFizzBuzzServer()
+}
+
+// UnimplementedFizzBuzzServer must be embedded to have forward compatible implementations.
+type UnimplementedFizzBuzzServer struct {
+}
+
+func (UnimplementedFizzBuzzServer) Generate(context.Context, *GenerateRequest) (*GenerateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (UnimplementedFizzBuzzServer) Stream(*StreamRequest, FizzBuzz_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedFizzBuzzServer) mustEmbedUnimplementedFizzBuzzServer() {}
+
+// UnsafeFizzBuzzServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FizzBuzzServer will
+// result in compilation errors.
+type UnsafeFizzBuzzServer interface {
+	mustEmbedUnimplementedFizzBuzzServer()
+}
+
+func RegisterFizzBuzzServer(s grpc.ServiceRegistrar, srv FizzBuzzServer) {
+	s.RegisterService(&FizzBuzz_ServiceDesc, srv)
+}
+
+func _FizzBuzz_Generate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FizzBuzzServer).Generate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FizzBuzz_Generate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FizzBuzzServer).Generate(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FizzBuzz_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FizzBuzzServer).Stream(m, &fizzBuzzStreamServer{stream})
+}
+
+type FizzBuzz_StreamServer interface {
+	Send(*StreamItem) error
+	grpc.ServerStream
+}
+
+type fizzBuzzStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *fizzBuzzStreamServer) Send(m *StreamItem) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// FizzBuzz_ServiceDesc is the grpc.ServiceDesc for FizzBuzz service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FizzBuzz_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fizzbuzz.v1.FizzBuzz",
+	HandlerType: (*FizzBuzzServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Generate",
+			Handler:    _FizzBuzz_Generate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _FizzBuzz_Stream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "fizzbuzz.proto",
+}
+
+const (
+	Metrics_TopRequests_FullMethodName      = "/fizzbuzz.v1.Metrics/TopRequests"
+	Metrics_WatchTopRequests_FullMethodName = "/fizzbuzz.v1.Metrics/WatchTopRequests"
+)
+
+// MetricsClient is the client API for Metrics service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MetricsClient interface {
+	// TopRequests returns the current top request of each route.
+	TopRequests(ctx context.Context, in *TopRequestsRequest, opts ...grpc.CallOption) (*TopRequestsResponse, error)
+	// WatchTopRequests sends the current top requests, followed by their changes.
+	WatchTopRequests(ctx context.Context, in *WatchTopRequestsRequest, opts ...grpc.CallOption) (Metrics_WatchTopRequestsClient, error)
+}
+
+type metricsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMetricsClient(cc grpc.ClientConnInterface) MetricsClient {
+	return &metricsClient{cc}
+}
+
+func (c *metricsClient) TopRequests(ctx context.Context, in *TopRequestsRequest, opts ...grpc.CallOption) (*TopRequestsResponse, error) {
+	out := new(TopRequestsResponse)
+	err := c.cc.Invoke(ctx, Metrics_TopRequests_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) WatchTopRequests(ctx context.Context, in *WatchTopRequestsRequest, opts ...grpc.CallOption) (Metrics_WatchTopRequestsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Metrics_ServiceDesc.Streams[0], Metrics_WatchTopRequests_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metricsWatchTopRequestsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Metrics_WatchTopRequestsClient interface {
+	Recv() (*TopRequestChange, error)
+	grpc.ClientStream
+}
+
+type metricsWatchTopRequestsClient struct {
+	grpc.ClientStream
+}
+
+func (x *metricsWatchTopRequestsClient) Recv() (*TopRequestChange, error) {
+	m := new(TopRequestChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MetricsServer is the server API for Metrics service.
+// All implementations must embed UnimplementedMetricsServer
+// for forward compatibility
+type MetricsServer interface {
+	// TopRequests returns the current top request of each route.
+	TopRequests(context.Context, *TopRequestsRequest) (*TopRequestsResponse, error)
+	// WatchTopRequests sends the current top requests, followed by their changes.
+	WatchTopRequests(*WatchTopRequestsRequest, Metrics_WatchTopRequestsServer) error
+	mustEmbedUnimplementedMetricsServer()
+}
+
+// UnimplementedMetricsServer must be embedded to have forward compatible implementations.
+type UnimplementedMetricsServer struct {
+}
+
+func (UnimplementedMetricsServer) TopRequests(context.Context, *TopRequestsRequest) (*TopRequestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TopRequests not implemented")
+}
+func (UnimplementedMetricsServer) WatchTopRequests(*WatchTopRequestsRequest, Metrics_WatchTopRequestsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTopRequests not implemented")
+}
+func (UnimplementedMetricsServer) mustEmbedUnimplementedMetricsServer() {}
+
+// UnsafeMetricsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MetricsServer will
+// result in compilation errors.
+type UnsafeMetricsServer interface {
+	mustEmbedUnimplementedMetricsServer()
+}
+
+func RegisterMetricsServer(s grpc.ServiceRegistrar, srv MetricsServer) {
+	s.RegisterService(&Metrics_ServiceDesc, srv)
+}
+
+func _Metrics_TopRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).TopRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_TopRequests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).TopRequests(ctx, req.(*TopRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_WatchTopRequests_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTopRequestsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetricsServer).WatchTopRequests(m, &metricsWatchTopRequestsServer{stream})
+}
+
+type Metrics_WatchTopRequestsServer interface {
+	Send(*TopRequestChange) error
+	grpc.ServerStream
+}
+
+type metricsWatchTopRequestsServer struct {
+	grpc.ServerStream
+}
+
+func (x *metricsWatchTopRequestsServer) Send(m *TopRequestChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Metrics_ServiceDesc is the grpc.ServiceDesc for Metrics service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Metrics_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fizzbuzz.v1.Metrics",
+	HandlerType: (*MetricsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "TopRequests",
+			Handler:    _Metrics_TopRequests_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTopRequests",
+			Handler:       _Metrics_WatchTopRequests_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "fizzbuzz.proto",
+}
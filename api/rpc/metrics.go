@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Raphy42/industrial-fizz-buzz/api/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/api/rpc/fizzbuzzpb"
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+)
+
+// changeKinds maps the events of the metrics.TopRequestFeed handler to TopRequestChange kinds
+var changeKinds = map[string]fizzbuzzpb.TopRequestChange_Kind{
+	metrics.SnapshotEvent:  fizzbuzzpb.TopRequestChange_KIND_SNAPSHOT,
+	metrics.TopEvent:       fizzbuzzpb.TopRequestChange_KIND_TOP,
+	metrics.ThresholdEvent: fizzbuzzpb.TopRequestChange_KIND_THRESHOLD,
+}
+
+// metricsServer serves the Metrics service through the metrics.AllMetrics and metrics.TopRequestFeed handlers
+type metricsServer struct {
+	fizzbuzzpb.UnimplementedMetricsServer
+}
+
+func toTopRequest(route string, response metrics.Response) (*fizzbuzzpb.TopRequest, error) {
+	top := &fizzbuzzpb.TopRequest{Route: route, Hits: uint64(response.Hits)}
+	if response.Request != nil {
+		request, err := structpb.NewStruct(response.Request)
+		if err != nil {
+			return nil, errors.Wrapf(err, "top request of '%s' cannot be converted", route)
+		}
+		top.Request = request
+	}
+	return top, nil
+}
+
+// TopRequests is the counterpart of GET /api/v1/metrics/request, restricted to the requested routes
+func (metricsServer) TopRequests(ctx context.Context, request *fizzbuzzpb.TopRequestsRequest) (*fizzbuzzpb.TopRequestsResponse, error) {
+	responses, err := http.Call[http.Empty, map[string]metrics.Response](ctx, metrics.AllMetrics, http.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	routes := request.GetRoutes()
+	if len(routes) == 0 {
+		routes = generics.MapKeys(*responses)
+	}
+	sort.Strings(routes)
+	result := &fizzbuzzpb.TopRequestsResponse{}
+	for _, route := range routes {
+		response, ok := (*responses)[route]
+		if !ok {
+			continue
+		}
+		top, err := toTopRequest(route, response)
+		if err != nil {
+			return nil, err
+		}
+		result.Tops = append(result.Tops, top)
+	}
+	return result, nil
+}
+
+// WatchTopRequests is the server-streaming counterpart of GET /api/v1/metrics/request/stream
+func (metricsServer) WatchTopRequests(request *fizzbuzzpb.WatchTopRequestsRequest, stream fizzbuzzpb.Metrics_WatchTopRequestsServer) error {
+	feedRequest := metrics.FeedRequest{Routes: request.GetRoutes()}
+	for _, threshold := range request.GetThresholds() {
+		feedRequest.Thresholds = append(feedRequest.Thresholds, uint(threshold))
+	}
+	return http.CallStream(stream.Context(), metrics.TopRequestFeed, feedRequest, "",
+		func(event http.Event[metrics.TopRequestChange]) error {
+			top, err := toTopRequest(event.Data.Route, event.Data.Response)
+			if err != nil {
+				return err
+			}
+			return stream.Send(&fizzbuzzpb.TopRequestChange{
+				Kind:      changeKinds[event.Name],
+				Top:       top,
+				Threshold: uint64(event.Data.Threshold),
+			})
+		})
+}
@@ -0,0 +1,15 @@
+// Package rpc implements the gRPC services of the fizzbuzzpb package on top of the HTTP handlers, through http.Call
+// and http.CallStream, so that both transports share their validation, authorization and metrics.
+package rpc
+
+import (
+	"google.golang.org/grpc"
+
+	"github.com/Raphy42/industrial-fizz-buzz/api/rpc/fizzbuzzpb"
+)
+
+// Register registers every gRPC service, it is meant to be given to the gRPC server as a registration
+func Register(registrar grpc.ServiceRegistrar) {
+	fizzbuzzpb.RegisterFizzBuzzServer(registrar, fizzBuzzServer{})
+	fizzbuzzpb.RegisterMetricsServer(registrar, metricsServer{})
+}
@@ -0,0 +1,154 @@
+package webhooks
+
+import (
+	"context"
+	nethttp "net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/webhooks"
+)
+
+// defaultDeadLetters is the number of dead letters returned when the request sets no limit
+const defaultDeadLetters = 100
+
+type (
+	// WebhookRequest is the request body for the CreateWebhook endpoint
+	WebhookRequest struct {
+		URL    string   `json:"url" required:"true" format:"uri"`
+		Events []string `json:"events,omitempty" description:"Types of events sent to the webhook (top.changed, threshold.crossed, ping), every type if omitted"`
+		Routes []string `json:"routes,omitempty" description:"Routes whose events are sent to the webhook, every route if omitted"`
+		Secret string   `json:"secret,omitempty" minLength:"16" description:"Key payloads are signed with, generated if omitted"`
+	}
+	// WebhookPath identifies a webhook through the request path
+	WebhookPath struct {
+		ID string `param:"id" path:"id"`
+	}
+	// DeadLettersRequest is the request for the DeadLetters endpoint
+	DeadLettersRequest struct {
+		Limit int `query:"limit" minimum:"0" description:"Maximum number of dead letters, the latest ones being returned first, 100 if omitted"`
+	}
+	// CreatedWebhook is returned by the CreateWebhook endpoint, along with the secret of the webhook
+	CreatedWebhook struct {
+		webhooks.Webhook
+	}
+	// SentPing is returned by the PingWebhook endpoint
+	SentPing struct {
+		webhooks.Event
+	}
+	// WebhooksResponse is returned by the ListWebhooks endpoint
+	WebhooksResponse []webhooks.Webhook
+	// DeliveriesResponse is returned by the WebhookDeliveries endpoint
+	DeliveriesResponse []webhooks.Delivery
+	// DeadLettersResponse is returned by the DeadLetters endpoint
+	DeadLettersResponse []webhooks.DeadLetter
+)
+
+var (
+	// CreateWebhook handles POST /api/v1/webhooks
+	CreateWebhook = http.Post("/api/v1/webhooks", createWebhook).WithScopes("webhooks:write")
+	// ListWebhooks handles GET /api/v1/webhooks
+	ListWebhooks = http.Get("/api/v1/webhooks", listWebhooks).WithScopes("webhooks:read")
+	// GetWebhook handles GET /api/v1/webhooks/:id
+	GetWebhook = http.Get("/api/v1/webhooks/:id", getWebhook).WithScopes("webhooks:read")
+	// DeleteWebhook handles DELETE /api/v1/webhooks/:id
+	DeleteWebhook = http.Delete("/api/v1/webhooks/:id", deleteWebhook).WithScopes("webhooks:write")
+	// WebhookDeliveries handles GET /api/v1/webhooks/:id/deliveries
+	WebhookDeliveries = http.Get("/api/v1/webhooks/:id/deliveries", webhookDeliveries).WithScopes("webhooks:read")
+	// PingWebhook handles POST /api/v1/webhooks/:id/ping
+	PingWebhook = http.Post("/api/v1/webhooks/:id/ping", pingWebhook).WithScopes("webhooks:write")
+	// DeadLetters handles GET /api/v1/webhooks/dead-letters
+	DeadLetters = http.Get("/api/v1/webhooks/dead-letters", deadLetters).WithScopes("webhooks:read")
+)
+
+// Handlers returns every webhook endpoint
+func Handlers() []http.Handler {
+	return []http.Handler{
+		CreateWebhook,
+		ListWebhooks,
+		GetWebhook,
+		DeleteWebhook,
+		WebhookDeliveries,
+		PingWebhook,
+		DeadLetters,
+	}
+}
+
+// StatusCode returns 201, as a new webhook has been registered
+func (CreatedWebhook) StatusCode() int {
+	return nethttp.StatusCreated
+}
+
+// StatusCode returns 202, as the ping is delivered in the background
+func (SentPing) StatusCode() int {
+	return nethttp.StatusAccepted
+}
+
+func createWebhook(ctx context.Context, request WebhookRequest) (*CreatedWebhook, error) {
+	webhook, err := webhooks.Create(ctx, webhooks.Webhook{
+		URL:    request.URL,
+		Events: request.Events,
+		Routes: request.Routes,
+		Secret: request.Secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("webhook created", zap.String("webhook.id", webhook.ID))
+	return &CreatedWebhook{Webhook: webhook}, nil
+}
+
+func listWebhooks(ctx context.Context, _ http.Empty) (*WebhooksResponse, error) {
+	response := WebhooksResponse(webhooks.List(ctx))
+	return &response, nil
+}
+
+func getWebhook(ctx context.Context, request WebhookPath) (*webhooks.Webhook, error) {
+	webhook, err := webhooks.Get(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func deleteWebhook(ctx context.Context, request WebhookPath) (*http.Empty, error) {
+	if err := webhooks.Delete(ctx, request.ID); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("webhook deleted", zap.String("webhook.id", request.ID))
+	return &http.Empty{}, nil
+}
+
+func webhookDeliveries(ctx context.Context, request WebhookPath) (*DeliveriesResponse, error) {
+	deliveries, err := webhooks.Deliveries(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	response := DeliveriesResponse(deliveries)
+	return &response, nil
+}
+
+func pingWebhook(ctx context.Context, request WebhookPath) (*SentPing, error) {
+	event, err := webhooks.SendPing(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SentPing{Event: event}, nil
+}
+
+func deadLetters(ctx context.Context, request DeadLettersRequest) (*DeadLettersResponse, error) {
+	limit := request.Limit
+	if limit == 0 {
+		limit = defaultDeadLetters
+	}
+	deadLetters, err := webhooks.DeadLetters(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	response := DeadLettersResponse(deadLetters)
+	return &response, nil
+}
@@ -4,6 +4,8 @@ const apiUrl = Deno.env.get('API_URL');
 if (apiUrl === undefined) {
     throw new Error('Missing mandatory env variable API_URL');
 }
+// optional, required whenever the api has authentication enabled
+const apiKey = Deno.env.get('API_KEY');
 
 function random(min: number, max: number): number {
     return Math.floor(Math.random() * (max - min + 1) + min);
@@ -33,7 +35,8 @@ interface Request extends Record<string, number | string> {
 }
 
 function fizzBuzzer(request: Request): Promise<Array<string>> {
-    return fetch(`${apiUrl!}/api/v1/fizzbuzz?${toQueryString(request)}`)
+    const headers: Record<string, string> = apiKey !== undefined ? {'X-Api-Key': apiKey} : {};
+    return fetch(`${apiUrl!}/api/v1/fizzbuzz?${toQueryString(request)}`, {headers})
         .then((response) => response.json().then((data) => [response.status, data] as const))
         .then(([status, data]) => {
             if (status !== 200) {
@@ -6,15 +6,37 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/Raphy42/industrial-fizz-buzz/api"
+	rpcApi "github.com/Raphy42/industrial-fizz-buzz/api/rpc"
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
 	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/jobs"
 	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/rpc"
+	"github.com/Raphy42/industrial-fizz-buzz/core/webhooks"
 )
 
 func main() {
 	log := logger.New()
 	ctx := context.Background()
+	for _, deprecation := range config.Deprecations() {
+		log.Warn("deprecated environment variable", zap.String("deprecation", deprecation))
+	}
 
-	server := http.NewServer(api.Handlers()...)
+	// jobs are resumed before serving requests, and interrupted once the server stopped serving them
+	if err := jobs.Start(ctx); err != nil {
+		log.Fatal("jobs initialisation failed", zap.Error(err))
+	}
+	// webhooks are notified of metrics events until the server stopped serving requests
+	if err := webhooks.Start(ctx); err != nil {
+		log.Fatal("webhooks initialisation failed", zap.Error(err))
+	}
+	server := http.NewServer(api.Handlers()...).
+		OnShutdown("jobs", jobs.Stop).
+		OnShutdown("webhooks", webhooks.Stop)
+	// gRPC calls are guarded by the HTTP server, and served until it shuts down
+	if config.Config.GrpcEnabled() {
+		server.WithService("grpc", rpc.NewServer(server, rpcApi.Register))
+	}
 
 	if err := server.Run(ctx); err != nil {
 		log.Fatal("server crashed", zap.Error(err))
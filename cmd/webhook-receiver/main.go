@@ -0,0 +1,74 @@
+// Command webhook-receiver is a local stand-in for the systems notified through webhooks. It verifies the signature of
+// every delivery and logs its event, optionally failing the first attempts of each delivery to exercise retries.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/webhooks"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	secret := flag.String("secret", "", "secret of the webhook, signatures are not verified if empty")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum age of signed payloads, 0 disables the check")
+	failures := flag.Int("fail", 0, "number of attempts of each delivery answered with 503 before accepting it")
+	flag.Parse()
+	log := logger.New()
+
+	var lock sync.Mutex
+	attempts := make(map[string]int)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		deliveryLog := log.With(zap.String("delivery.id", r.Header.Get(webhooks.DeliveryHeader)))
+		if *secret != "" {
+			if err := webhooks.Verify(*secret, r.Header, payload, *tolerance); err != nil {
+				deliveryLog.Warn("invalid signature", zap.Error(err))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		lock.Lock()
+		attempts[r.Header.Get(webhooks.DeliveryHeader)]++
+		attempt := attempts[r.Header.Get(webhooks.DeliveryHeader)]
+		lock.Unlock()
+		if attempt <= *failures {
+			deliveryLog.Info("delivery failed on purpose", zap.Int("delivery.attempt", attempt))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event webhooks.Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			deliveryLog.Warn("invalid event", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		deliveryLog.Info("event received",
+			zap.String("event.id", event.ID),
+			zap.String("event.type", event.Type),
+			zap.String("event.route", event.Route),
+			zap.ByteString("event.data", event.Data),
+			zap.Int("delivery.attempt", attempt),
+		)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Info("webhook receiver listening", zap.String("addr", *addr))
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		log.Fatal("webhook receiver crashed", zap.Error(err))
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type (
+	// LRU is a thread-safe least recently used cache, bounded by both its number of entries and their total size.
+	// Entries also expire after their own time to live.
+	LRU[V any] struct {
+		lock       sync.Mutex
+		maxEntries int
+		maxBytes   int
+		bytes      int
+		entries    map[string]*list.Element
+		order      list.List
+		stats      Stats
+	}
+	// Stats are the LRU cumulative counters and current usage
+	Stats struct {
+		Hits      uint64
+		Misses    uint64
+		Evictions uint64
+		Entries   int
+		Bytes     int
+	}
+	entry[V any] struct {
+		key       string
+		value     V
+		size      int
+		expiresAt time.Time
+	}
+)
+
+// now is overridden in tests
+var now = time.Now
+
+// New instantiate a LRU, a zero bound disables it.
+func New[V any](maxEntries, maxBytes int) *LRU[V] {
+	return &LRU[V]{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value associated with a key, ok is false if it is absent or expired.
+func (l *LRU[V]) Get(key string) (value V, ok bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		l.stats.Misses++
+		return value, false
+	}
+	e := elem.Value.(*entry[V])
+	if now().After(e.expiresAt) {
+		l.remove(elem)
+		l.stats.Misses++
+		return value, false
+	}
+	l.order.MoveToFront(elem)
+	l.stats.Hits++
+	return e.value, true
+}
+
+// Set associates a value of the given size in bytes with a key for the given time to live, evicting least recently
+// used entries if needed. Values larger than the whole cache are ignored.
+func (l *LRU[V]) Set(key string, value V, size int, ttl time.Duration) {
+	size += len(key)
+	if size > l.maxBytes || l.maxEntries <= 0 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if elem, ok := l.entries[key]; ok {
+		l.remove(elem)
+	}
+	l.entries[key] = l.order.PushFront(&entry[V]{key: key, value: value, size: size, expiresAt: now().Add(ttl)})
+	l.bytes += size
+
+	for l.order.Len() > l.maxEntries || l.bytes > l.maxBytes {
+		l.remove(l.order.Back())
+		l.stats.Evictions++
+	}
+}
+
+// Purge removes every entry.
+func (l *LRU[V]) Purge() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.entries = make(map[string]*list.Element)
+	l.order.Init()
+	l.bytes = 0
+}
+
+// Stats returns the current Stats.
+func (l *LRU[V]) Stats() Stats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stats := l.stats
+	stats.Entries = l.order.Len()
+	stats.Bytes = l.bytes
+	return stats
+}
+
+// remove expects the lock to be held
+func (l *LRU[V]) remove(elem *list.Element) {
+	e := l.order.Remove(elem).(*entry[V])
+	delete(l.entries, e.key)
+	l.bytes -= e.size
+}
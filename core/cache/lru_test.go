@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	a := assert.New(t)
+	current := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	lru := New[string](2, 64)
+	lru.Set("a", "1", 10, time.Minute)
+	lru.Set("b", "2", 10, time.Minute)
+	_, ok := lru.Get("a")
+	a.True(ok)
+
+	// "b" is the least recently used entry
+	lru.Set("c", "3", 10, time.Minute)
+	_, ok = lru.Get("b")
+	a.False(ok, "least recently used entry should be evicted by entries bound")
+
+	// "c" and "a" use 22 bytes, "d" requires evicting "a"
+	lru.Set("d", "4", 40, time.Minute)
+	_, ok = lru.Get("a")
+	a.False(ok, "least recently used entry should be evicted by size bound")
+	value, ok := lru.Get("d")
+	a.True(ok)
+	a.Equal("4", value)
+
+	lru.Set("huge", "5", 100, time.Minute)
+	_, ok = lru.Get("huge")
+	a.False(ok, "values larger than the cache should be ignored")
+
+	current = current.Add(2 * time.Minute)
+	_, ok = lru.Get("d")
+	a.False(ok, "expired entry should not be returned")
+
+	stats := lru.Stats()
+	a.Equal(uint64(2), stats.Hits)
+	a.Equal(uint64(4), stats.Misses)
+	a.Equal(uint64(2), stats.Evictions)
+	a.Equal(1, stats.Entries)
+	a.Equal(11, stats.Bytes)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/pkg/errors"
@@ -26,6 +27,7 @@ var (
 func init() {
 	once.Do(func() {
 		var m Manifest
+		deprecations = applyLegacyNames("fizzbuzz")
 		if err := envconfig.Process("fizzbuzz", &m); err != nil {
 			panic(errors.Wrapf(err, "ambiguous environment variables"))
 		}
@@ -38,14 +40,208 @@ type Manifest struct {
 	// Mode defines the application environment, 'dev' or 'prod'.
 	// This affects multiple internal subsystems such as logs, error handlers and other conveniences.
 	Mode string `default:"dev"`
+	// LogLevel is the minimum enabled log level, such as "debug" or "warn",
+	// defaults to empty, using "debug" in dev mode and "info" in prod mode
+	LogLevel string `split_words:"true"`
 	// Port is the http port that should be used by the server, defaults to 8080
 	Port uint16 `default:"8080"`
 	// Addr if set will override Port config, expects a valid golang listener addr, such as ":8080", defaults to empty
-	Addr string `splitwords:"true"`
+	Addr string `split_words:"true"`
+	// AdminAddr if set starts a second listener serving operational endpoints (health, metrics, profiling...),
+	// expects a valid golang listener addr such as ":8081", defaults to empty, sharing the public listener
+	AdminAddr string `split_words:"true"`
+	// GrpcAddr if set starts a gRPC server exposing the fizzbuzz and metrics services along with gRPC health checking,
+	// expects a valid golang listener addr such as ":50051", defaults to empty
+	GrpcAddr string `split_words:"true"`
+	// GrpcReflectionEnabled exposes the gRPC server reflection service, allowing tools such as grpcurl to discover
+	// services, defaults to true
+	GrpcReflectionEnabled bool `split_words:"true" default:"true"`
 	// CorsEnabled will enable CORS on all endpoints if set, defaults to true
-	CorsEnabled bool `splitwords:"true" default:"true"`
+	CorsEnabled bool `split_words:"true" default:"true"`
 	// AllowEmptyStr allows empty str to be used as words for the fizzbuzz endpoint, defaults to false
-	AllowEmptyStr bool `splitwords:"true" default:"false"`
+	AllowEmptyStr bool `split_words:"true" default:"false"`
+	// ReadTimeout is the maximum duration for reading an entire request, including its body, defaults to 5s
+	ReadTimeout time.Duration `split_words:"true" default:"5s"`
+	// ReadHeaderTimeout is the maximum duration for reading request headers, defaults to 2s
+	ReadHeaderTimeout time.Duration `split_words:"true" default:"2s"`
+	// WriteTimeout is the maximum duration before timing out writes of a response, defaults to 30s
+	WriteTimeout time.Duration `split_words:"true" default:"30s"`
+	// IdleTimeout is the maximum duration to wait for the next request on a keep-alive connection, defaults to 60s
+	IdleTimeout time.Duration `split_words:"true" default:"60s"`
+	// HandlerTimeout is the default deadline applied to every handler context, 0 disables it, defaults to 10s.
+	// It can be overridden per handler using http.Handler.WithTimeout.
+	HandlerTimeout time.Duration `split_words:"true" default:"10s"`
+	// BodyLimit is the maximum allowed request body size, using echo notation such as "4K" or "2M", defaults to 1M.
+	// An empty value disables the limit.
+	BodyLimit string `split_words:"true" default:"1M"`
+	// ApiKeys maps client identifiers to their API key, such as "loadgen:secret,dashboard:secret2", defaults to empty.
+	// Authentication is enabled as soon as at least one API key is configured, see also ApiKeysFile.
+	ApiKeys map[string]string `split_words:"true"`
+	// ApiKeysFile is the path of a JSON file mapping client identifiers to their API key, defaults to empty
+	ApiKeysFile string `split_words:"true"`
+	// ApiKeyHeader is the request header carrying the API key, defaults to X-Api-Key
+	ApiKeyHeader string `split_words:"true" default:"X-Api-Key"`
+	// ApiKeyQuery is the query parameter carrying the API key, defaults to api_key
+	ApiKeyQuery string `split_words:"true" default:"api_key"`
+	// JwksFile is the path of a local JSON Web Key Set used to verify JWT bearer tokens, defaults to empty.
+	// Bearer token authentication is enabled whenever it is set.
+	JwksFile string `split_words:"true"`
+	// JwksReloadInterval is the minimum interval between two JwksFile modification checks, 0 disables reloading,
+	// defaults to 30s
+	JwksReloadInterval time.Duration `split_words:"true" default:"30s"`
+	// JwtAudience if set is the audience every bearer token must be issued for, defaults to empty
+	JwtAudience string `split_words:"true"`
+	// JwtIssuer if set is the issuer every bearer token must be issued by, defaults to empty
+	JwtIssuer string `split_words:"true"`
+	// JwtLeeway is the clock skew tolerated when validating bearer token `exp` and `nbf` claims, defaults to 30s
+	JwtLeeway time.Duration `split_words:"true" default:"30s"`
+	// RateLimit is the number of requests per second accepted by the whole instance, 0 disables it, defaults to 0
+	RateLimit float64 `split_words:"true" default:"0"`
+	// RateLimitBurst is the number of requests which can exceed RateLimit at once, defaults to RateLimit
+	RateLimitBurst int `split_words:"true" default:"0"`
+	// ClientRateLimit is the number of requests per second accepted for each client, 0 disables it, defaults to 0.
+	// Anonymous clients are identified by their IP address.
+	ClientRateLimit float64 `split_words:"true" default:"0"`
+	// ClientRateLimitBurst is the number of requests which can exceed ClientRateLimit at once,
+	// defaults to ClientRateLimit
+	ClientRateLimitBurst int `split_words:"true" default:"0"`
+	// ClientRateLimits overrides ClientRateLimit by client identifier, such as "loadgen:5,dashboard:50"
+	ClientRateLimits map[string]float64 `split_words:"true"`
+	// TrustedProxies lists the addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For header identifies
+	// clients, such as "10.0.0.1,10.1.0.0/16", defaults to empty: clients are identified by their connection address
+	TrustedProxies []string `split_words:"true"`
+	// DailyItemQuota is the number of fizzbuzz items each client can generate per UTC day, 0 disables it,
+	// defaults to 0
+	DailyItemQuota uint64 `split_words:"true" default:"0"`
+	// DailyItemQuotas overrides DailyItemQuota by client identifier, such as "loadgen:1000,dashboard:0"
+	DailyItemQuotas map[string]uint64 `split_words:"true"`
+	// CostBudget is the total cost of requests which can be processed concurrently by the instance, 0 disables it,
+	// defaults to 0. See also http.Coster.
+	CostBudget uint64 `split_words:"true" default:"0"`
+	// CostQueueTimeout is the maximum duration a request waits for budget before being rejected, 0 rejects requests
+	// immediately, defaults to 1s
+	CostQueueTimeout time.Duration `split_words:"true" default:"1s"`
+	// CacheMaxEntries is the maximum number of responses kept by the response cache, defaults to 10000
+	CacheMaxEntries int `split_words:"true" default:"10000"`
+	// CacheMaxBytes is the maximum total size of responses kept by the response cache, 0 disables it,
+	// defaults to 64MiB
+	CacheMaxBytes int `split_words:"true" default:"67108864"`
+	// CacheTTL is the default time to live of cached responses, defaults to 5m.
+	// Caching is opt-in, see http.Handler.WithCache.
+	CacheTTL time.Duration `split_words:"true" default:"5m"`
+	// MaxItems is the maximum number of items of a single fizzbuzz response, defaults to 100000000
+	MaxItems int64 `split_words:"true" default:"100000000"`
+	// MaxBatchSize is the maximum number of requests of a single fizzbuzz batch, defaults to 1000
+	MaxBatchSize int `split_words:"true" default:"1000"`
+	// MaxBatchItems is the maximum number of items generated by all the requests of a single fizzbuzz batch,
+	// defaults to 10000000
+	MaxBatchItems int64 `split_words:"true" default:"10000000"`
+	// StreamHeartbeatInterval is the interval between two heartbeats of Server-Sent Events streams, keeping idle
+	// streams open through proxies, 0 disables them, defaults to 15s
+	StreamHeartbeatInterval time.Duration `split_words:"true" default:"15s"`
+	// StreamMinInterval is the minimum delay between two events of a fizzbuzz stream, defaults to 10ms
+	StreamMinInterval time.Duration `split_words:"true" default:"10ms"`
+	// WebSocketIdleTimeout is the duration after which WebSocket sessions without any client message are closed,
+	// 0 disables it, defaults to 60s
+	WebSocketIdleTimeout time.Duration `split_words:"true" default:"60s"`
+	// WebSocketMaxMessageSize is the maximum size in bytes of a WebSocket client message, larger messages closing the
+	// session, defaults to 16384
+	WebSocketMaxMessageSize int64 `split_words:"true" default:"16384"`
+	// WebSocketMessageRate is the number of messages per second each WebSocket session can send, 0 disables it,
+	// defaults to 10
+	WebSocketMessageRate float64 `split_words:"true" default:"10"`
+	// WebSocketMessageBurst is the number of messages which can exceed WebSocketMessageRate at once,
+	// defaults to WebSocketMessageRate
+	WebSocketMessageBurst int `split_words:"true" default:"0"`
+	// SessionMaxItems is the maximum number of items a fizzbuzz session generates per message, defaults to 10000
+	SessionMaxItems int64 `split_words:"true" default:"10000"`
+	// MetricsFeedInterval is the minimum delay between two top request change events of a route, changes being
+	// coalesced in between, defaults to 1s
+	MetricsFeedInterval time.Duration `split_words:"true" default:"1s"`
+	// MetricsFeedThresholds are the hit counts sending a top request change event once crossed, unless subscribers
+	// choose their own, defaults to 10,100,1000,10000,100000,1000000
+	MetricsFeedThresholds []uint `split_words:"true" default:"10,100,1000,10000,100000,1000000"`
+	// WebhooksDir is the directory where webhooks registered through the API and the dead-letter log are stored,
+	// defaults to /tmp/fizzbuzz/webhooks
+	WebhooksDir string `split_words:"true" default:"/tmp/fizzbuzz/webhooks"`
+	// WebhooksFile is the path of a JSON file listing webhooks registered through configuration, such as
+	// [{"id": "alerting", "url": "http://alerting.internal/hooks", "secret": "...", "events": ["threshold.crossed"]}],
+	// defaults to empty
+	WebhooksFile string `split_words:"true"`
+	// WebhookAllowedHosts lists the host names, addresses or CIDR ranges webhooks can be delivered to although they are
+	// loopback, private or link-local addresses, such as "alerting.internal,10.1.0.0/16", defaults to empty
+	WebhookAllowedHosts []string `split_words:"true"`
+	// MaxWebhooks is the maximum number of webhooks registered through the API, defaults to 100
+	MaxWebhooks int `split_words:"true" default:"100"`
+	// MaxClientWebhooks is the maximum number of webhooks each client can register through the API, defaults to 10
+	MaxClientWebhooks int `split_words:"true" default:"10"`
+	// WebhookWorkers is the number of webhook deliveries attempted concurrently, defaults to 2
+	WebhookWorkers int `split_words:"true" default:"2"`
+	// WebhookQueueSize is the maximum number of queued webhook deliveries, deliveries being dead-lettered once it is
+	// full, defaults to 1000
+	WebhookQueueSize int `split_words:"true" default:"1000"`
+	// WebhookTimeout is the maximum duration of a webhook delivery attempt, defaults to 5s
+	WebhookTimeout time.Duration `split_words:"true" default:"5s"`
+	// WebhookMaxAttempts is the number of attempts of a webhook delivery before it is dead-lettered, defaults to 5
+	WebhookMaxAttempts int `split_words:"true" default:"5"`
+	// WebhookRetryBackoff is the delay before the first retry of a webhook delivery, doubling on every retry,
+	// defaults to 1s
+	WebhookRetryBackoff time.Duration `split_words:"true" default:"1s"`
+	// WebhookMaxBackoff caps the delay between two attempts of a webhook delivery, defaults to 5m
+	WebhookMaxBackoff time.Duration `split_words:"true" default:"5m"`
+	// WebhookHistorySize is the number of delivery attempts kept by webhook, defaults to 100
+	WebhookHistorySize int `split_words:"true" default:"100"`
+	// JobsDir is the directory where asynchronous jobs and their results are stored, defaults to /tmp/fizzbuzz/jobs
+	JobsDir string `split_words:"true" default:"/tmp/fizzbuzz/jobs"`
+	// JobWorkers is the number of asynchronous jobs running concurrently, defaults to 2
+	JobWorkers int `split_words:"true" default:"2"`
+	// JobQueueSize is the maximum number of queued asynchronous jobs, submissions being rejected once it is full,
+	// defaults to 100
+	JobQueueSize int `split_words:"true" default:"100"`
+	// JobTTL is the duration finished jobs and their results are kept, defaults to 24h
+	JobTTL time.Duration `split_words:"true" default:"24h"`
+	// JobMaxItems is the maximum number of items of a single fizzbuzz job, defaults to 1000000000
+	JobMaxItems int64 `split_words:"true" default:"1000000000"`
+	// GenerationWorkers is the number of goroutines generating fizzbuzz chunks concurrently, shared by every request,
+	// defaults to 0 which uses GOMAXPROCS
+	GenerationWorkers int `split_words:"true" default:"0"`
+	// GenerationRequestWorkers caps the number of workers a single request can use, so huge requests don't starve
+	// other ones, defaults to 4
+	GenerationRequestWorkers int `split_words:"true" default:"4"`
+	// GenerationChunkSize is the number of items generated by a worker at once, requests with fewer items are
+	// generated by their own goroutine, defaults to 65536
+	GenerationChunkSize int `split_words:"true" default:"65536"`
+	// ExpressionMaxSteps is the maximum number of evaluation steps of the expressions of a single request,
+	// defaults to 100000000
+	ExpressionMaxSteps int64 `split_words:"true" default:"100000000"`
+	// ExpressionTimeout is the maximum duration of the evaluation of the expressions of a single request, defaults to 5s
+	ExpressionTimeout time.Duration `split_words:"true" default:"5s"`
+	// RulesetsDir is the directory where saved rule sets and their version history are stored,
+	// defaults to /tmp/fizzbuzz/rulesets
+	RulesetsDir string `split_words:"true" default:"/tmp/fizzbuzz/rulesets"`
+	// MaxRulesets is the maximum number of saved rule sets, defaults to 1000
+	MaxRulesets int `split_words:"true" default:"1000"`
+	// RulesetMaxVersions is the maximum number of versions kept per rule set, older versions being dropped on update,
+	// 0 keeps every version, defaults to 100
+	RulesetMaxVersions int `split_words:"true" default:"100"`
+	// CompressionEnabled negotiates zstd, gzip or deflate response compression, defaults to true
+	CompressionEnabled bool `split_words:"true" default:"true"`
+	// CompressionMinSize is the minimum size in bytes of a response body worth compressing, defaults to 1024.
+	// Streamed responses are compressed as soon as they are flushed.
+	CompressionMinSize int `split_words:"true" default:"1024"`
+	// ProfilingEnabled exposes runtime profiling endpoints, which are only mounted on the admin listener,
+	// defaults to false
+	ProfilingEnabled bool `split_words:"true" default:"false"`
+	// ProfilingDir is the directory where on-demand profiles are written, defaults to /tmp/fizzbuzz/profiles
+	ProfilingDir string `split_words:"true" default:"/tmp/fizzbuzz/profiles"`
+	// ProfilingMaxDuration is the maximum duration of an on-demand CPU profile or trace, defaults to 60s
+	ProfilingMaxDuration time.Duration `split_words:"true" default:"60s"`
+	// ShutdownDrainPeriod is the duration during which the server keeps serving traffic while reporting itself as
+	// not ready, before shutting down, allowing load balancers to stop routing requests to it, defaults to 0s
+	ShutdownDrainPeriod time.Duration `split_words:"true" default:"0s"`
+	// ShutdownTimeout is the maximum duration allowed for in-flight requests and shutdown hooks to complete,
+	// defaults to 5s
+	ShutdownTimeout time.Duration `split_words:"true" default:"5s"`
 }
 
 // IsProd check whether the application is configured for production.
@@ -53,6 +249,28 @@ func (m Manifest) IsProd() bool {
 	return m.Mode == Prod
 }
 
+// AdminEnabled checks whether operational endpoints are served by a dedicated listener
+func (m Manifest) AdminEnabled() bool {
+	return m.AdminAddr != ""
+}
+
+// GrpcEnabled checks whether the gRPC server is started along with the HTTP listeners
+func (m Manifest) GrpcEnabled() bool {
+	return m.GrpcAddr != ""
+}
+
+// Redacted returns a copy of the Manifest with secrets masked, suitable for logging or dumping.
+func (m Manifest) Redacted() Manifest {
+	const mask = "<redacted>"
+
+	apiKeys := make(map[string]string, len(m.ApiKeys))
+	for client := range m.ApiKeys {
+		apiKeys[client] = mask
+	}
+	m.ApiKeys = apiKeys
+	return m
+}
+
 // ListenAddr returns a http.Listener compatible string address
 func (m Manifest) ListenAddr() string {
 	if m.Addr == "" {
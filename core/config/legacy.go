@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// word splitting rules of envconfig `split_words` tags
+var (
+	gatherRegexp  = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
+	acronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
+)
+
+// deprecations lists the legacy environment variables in use, see Deprecations
+var deprecations []string
+
+// Deprecations returns a message for every legacy environment variable in use, so that they can be logged once the
+// logger is available.
+func Deprecations() []string {
+	return deprecations
+}
+
+// splitWords returns the environment variable name of a field tagged with `split_words`, as envconfig does
+func splitWords(prefix, field string) string {
+	var words []string
+	for _, match := range gatherRegexp.FindAllStringSubmatch(field, -1) {
+		if m := acronymRegexp.FindStringSubmatch(match[0]); len(m) == 3 {
+			words = append(words, m[1], m[2])
+		} else {
+			words = append(words, match[0])
+		}
+	}
+	return strings.ToUpper(prefix + "_" + strings.Join(words, "_"))
+}
+
+// applyLegacyNames reads the environment variables named before `split_words` tags were honoured, such as
+// FIZZBUZZ_CORSENABLED for FIZZBUZZ_CORS_ENABLED. Legacy names are only used when the current name is unset, and are
+// reported through Deprecations.
+func applyLegacyNames(prefix string) []string {
+	var messages []string
+	t := reflect.TypeOf(Manifest{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("split_words") != "true" {
+			continue
+		}
+		current := splitWords(prefix, field.Name)
+		legacy := strings.ToUpper(prefix + "_" + field.Name)
+		if current == legacy {
+			continue
+		}
+		value, ok := os.LookupEnv(legacy)
+		if !ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(current); ok {
+			messages = append(messages, fmt.Sprintf("%s is ignored in favour of %s", legacy, current))
+			continue
+		}
+		_ = os.Setenv(current, value)
+		messages = append(messages, fmt.Sprintf("%s is deprecated, use %s instead", legacy, current))
+	}
+	return messages
+}
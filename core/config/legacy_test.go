@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyNames(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("FIZZBUZZ_CORS_ENABLED", splitWords("fizzbuzz", "CorsEnabled"))
+	a.Equal("FIZZBUZZ_JWKS_FILE", splitWords("fizzbuzz", "JwksFile"))
+	a.Equal("FIZZBUZZ_API_KEYS", splitWords("fizzbuzz", "ApiKeys"))
+
+	t.Setenv("FIZZBUZZ_ALLOWEMPTYSTR", "true")
+	t.Cleanup(func() { _ = os.Unsetenv("FIZZBUZZ_ALLOW_EMPTY_STR") })
+	t.Setenv("FIZZBUZZ_HANDLERTIMEOUT", "3s")
+	t.Setenv("FIZZBUZZ_HANDLER_TIMEOUT", "4s")
+	a.ElementsMatch([]string{
+		"FIZZBUZZ_ALLOWEMPTYSTR is deprecated, use FIZZBUZZ_ALLOW_EMPTY_STR instead",
+		"FIZZBUZZ_HANDLERTIMEOUT is ignored in favour of FIZZBUZZ_HANDLER_TIMEOUT",
+	}, applyLegacyNames("fizzbuzz"))
+
+	var m Manifest
+	a.NoError(envconfig.Process("fizzbuzz", &m))
+	a.True(m.AllowEmptyStr, "legacy names are read when the current one is unset")
+	a.Equal("4s", m.HandlerTimeout.String(), "current names take precedence")
+}
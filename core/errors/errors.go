@@ -43,9 +43,9 @@ func newError(skipCallers int, parent error, statusCode int, format string, args
 		}
 	}
 	if parent != nil {
-		err = errors.Wrapf(parent, msg)
+		err = errors.Wrap(parent, msg)
 	} else {
-		err = errors.Errorf(msg)
+		err = errors.New(msg)
 	}
 
 	return &Error{
@@ -56,6 +56,11 @@ func newError(skipCallers int, parent error, statusCode int, format string, args
 	}
 }
 
+// Unwrap returns the wrapped error, allowing errors.Is and errors.As to inspect the error chain.
+func (e *Error) Unwrap() error {
+	return e.error
+}
+
 // NotImplemented is a placeholder error which can be used whenever needed to mark a work in progress.
 // StatusCode: 501
 func NotImplemented() error {
@@ -74,3 +79,43 @@ func BadRequest(err error, format string, args ...any) error {
 func NotFound() error {
 	return newError(1, nil, http.StatusNotFound, "resource not found")
 }
+
+// Unavailable wraps an optional error and a message with args, used whenever the server cannot process the request
+// right now, such as an expired request deadline.
+// StatusCode: 503
+func Unavailable(err error, format string, args ...any) error {
+	return newError(1, err, http.StatusServiceUnavailable, format, args...)
+}
+
+// Unauthorized wraps an optional error and a message with args, used whenever the request lacks valid credentials.
+// StatusCode: 401
+func Unauthorized(err error, format string, args ...any) error {
+	return newError(1, err, http.StatusUnauthorized, format, args...)
+}
+
+// Forbidden wraps an optional error and a message with args, used whenever the client lacks the required permissions.
+// StatusCode: 403
+func Forbidden(err error, format string, args ...any) error {
+	return newError(1, err, http.StatusForbidden, format, args...)
+}
+
+// Conflict wraps an optional error and a message with args, used whenever the request conflicts with the current
+// state of a resource, such as a stale version.
+// StatusCode: 409
+func Conflict(err error, format string, args ...any) error {
+	return newError(1, err, http.StatusConflict, format, args...)
+}
+
+// TooManyRequests wraps an optional error and a message with args, used whenever a client exceeds a rate limit or
+// a quota.
+// StatusCode: 429
+func TooManyRequests(err error, format string, args ...any) error {
+	return newError(1, err, http.StatusTooManyRequests, format, args...)
+}
+
+// UnsupportedMediaType wraps an optional error and a message with args, used whenever the request body format or
+// encoding is not supported.
+// StatusCode: 415
+func UnsupportedMediaType(err error, format string, args ...any) error {
+	return newError(1, err, http.StatusUnsupportedMediaType, format, args...)
+}
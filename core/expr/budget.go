@@ -0,0 +1,69 @@
+package expr
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// meterBatch is the number of steps a Meter takes from its Budget at once, the deadline being checked on every batch
+const meterBatch = 1024
+
+type (
+	// Budget bounds the evaluation of programs, in number of steps and time.
+	// A Budget can be shared by concurrent evaluations, each of them using its own Meter.
+	Budget struct {
+		steps    atomic.Int64
+		deadline time.Time
+	}
+	// Meter consumes steps from a Budget, it is not safe for concurrent use
+	Meter struct {
+		budget *Budget
+		steps  int64
+	}
+)
+
+// NewBudget returns a Budget allowing the given number of evaluation steps, during the given duration.
+func NewBudget(steps int64, timeout time.Duration) *Budget {
+	b := &Budget{deadline: time.Now().Add(timeout)}
+	b.steps.Store(steps)
+	return b
+}
+
+// Meter returns a new Meter consuming steps from the Budget.
+func (b *Budget) Meter() *Meter {
+	return &Meter{budget: b}
+}
+
+// Remaining returns the number of steps which have not been taken by any Meter yet.
+func (b *Budget) Remaining() int64 {
+	return b.steps.Load()
+}
+
+func (m *Meter) step() error {
+	if m.steps > 0 {
+		m.steps--
+		return nil
+	}
+	return m.refill()
+}
+
+// refill takes a batch of steps from the Budget, and consumes one of them
+func (m *Meter) refill() error {
+	if time.Now().After(m.budget.deadline) {
+		return ErrTimeBudgetExceeded
+	}
+	for {
+		remaining := m.budget.steps.Load()
+		if remaining <= 0 {
+			return ErrStepBudgetExceeded
+		}
+		take := int64(meterBatch)
+		if remaining < take {
+			take = remaining
+		}
+		if m.budget.steps.CompareAndSwap(remaining, remaining-take) {
+			m.steps = take - 1
+			return nil
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package expr
+
+import "fmt"
+
+type (
+	// state is the evaluation state of a single Program run
+	state struct {
+		vars  []int64
+		meter *Meter
+	}
+	builtin struct {
+		arity int
+		fn    func(args []int64) int64
+	}
+)
+
+var builtins = map[string]builtin{
+	"abs": {arity: 1, fn: func(args []int64) int64 {
+		if args[0] < 0 {
+			return -args[0]
+		}
+		return args[0]
+	}},
+	"min": {arity: 2, fn: func(args []int64) int64 {
+		if args[0] < args[1] {
+			return args[0]
+		}
+		return args[1]
+	}},
+	"max": {arity: 2, fn: func(args []int64) int64 {
+		if args[0] > args[1] {
+			return args[0]
+		}
+		return args[1]
+	}},
+}
+
+func (s *state) step() error {
+	return s.meter.step()
+}
+
+func logical(operator token, left, right *typedNode) (*typedNode, error) {
+	if err := expect(left, Bool, operator.text); err != nil {
+		return nil, err
+	}
+	if err := expect(right, Bool, operator.text); err != nil {
+		return nil, err
+	}
+	l, r := left.boolFn, right.boolFn
+
+	// both operators short-circuit
+	if operator.text == "&&" {
+		return &typedNode{typ: Bool, pos: left.pos, boolFn: func(s *state) (bool, error) {
+			if err := s.step(); err != nil {
+				return false, err
+			}
+			if ok, err := l(s); err != nil || !ok {
+				return false, err
+			}
+			return r(s)
+		}}, nil
+	}
+	return &typedNode{typ: Bool, pos: left.pos, boolFn: func(s *state) (bool, error) {
+		if err := s.step(); err != nil {
+			return false, err
+		}
+		if ok, err := l(s); err != nil || ok {
+			return ok, err
+		}
+		return r(s)
+	}}, nil
+}
+
+func comparison(operator token, left, right *typedNode) (*typedNode, error) {
+	if left.typ != right.typ {
+		return nil, &SyntaxError{Pos: operator.pos, Msg: fmt.Sprintf("cannot compare %s with %s", left.typ, right.typ)}
+	}
+
+	if left.typ == Bool {
+		if operator.text != "==" && operator.text != "!=" {
+			return nil, &SyntaxError{Pos: operator.pos, Msg: fmt.Sprintf("%s expects int operands, got bool", operator.text)}
+		}
+		l, r := left.boolFn, right.boolFn
+		equal := operator.text == "=="
+		return &typedNode{typ: Bool, pos: left.pos, boolFn: func(s *state) (bool, error) {
+			if err := s.step(); err != nil {
+				return false, err
+			}
+			a, err := l(s)
+			if err != nil {
+				return false, err
+			}
+			b, err := r(s)
+			if err != nil {
+				return false, err
+			}
+			return (a == b) == equal, nil
+		}}, nil
+	}
+
+	var compare func(a, b int64) bool
+	switch operator.text {
+	case "==":
+		compare = func(a, b int64) bool { return a == b }
+	case "!=":
+		compare = func(a, b int64) bool { return a != b }
+	case "<":
+		compare = func(a, b int64) bool { return a < b }
+	case "<=":
+		compare = func(a, b int64) bool { return a <= b }
+	case ">":
+		compare = func(a, b int64) bool { return a > b }
+	case ">=":
+		compare = func(a, b int64) bool { return a >= b }
+	}
+	l, r := left.intFn, right.intFn
+	return &typedNode{typ: Bool, pos: left.pos, boolFn: func(s *state) (bool, error) {
+		if err := s.step(); err != nil {
+			return false, err
+		}
+		a, err := l(s)
+		if err != nil {
+			return false, err
+		}
+		b, err := r(s)
+		if err != nil {
+			return false, err
+		}
+		return compare(a, b), nil
+	}}, nil
+}
+
+// arithmetic compiles integer operators, overflows wrap around as in Go
+func arithmetic(operator token, left, right *typedNode) (*typedNode, error) {
+	if err := expect(left, Int, operator.text); err != nil {
+		return nil, err
+	}
+	if err := expect(right, Int, operator.text); err != nil {
+		return nil, err
+	}
+
+	var apply func(a, b int64) (int64, error)
+	pos := operator.pos
+	switch operator.text {
+	case "+":
+		apply = func(a, b int64) (int64, error) { return a + b, nil }
+	case "-":
+		apply = func(a, b int64) (int64, error) { return a - b, nil }
+	case "*":
+		apply = func(a, b int64) (int64, error) { return a * b, nil }
+	case "/":
+		apply = func(a, b int64) (int64, error) {
+			if b == 0 {
+				return 0, &RuntimeError{Pos: pos, Msg: "division by zero"}
+			}
+			return a / b, nil
+		}
+	case "%":
+		apply = func(a, b int64) (int64, error) {
+			if b == 0 {
+				return 0, &RuntimeError{Pos: pos, Msg: "modulo by zero"}
+			}
+			return a % b, nil
+		}
+	}
+	l, r := left.intFn, right.intFn
+	return &typedNode{typ: Int, pos: left.pos, intFn: func(s *state) (int64, error) {
+		if err := s.step(); err != nil {
+			return 0, err
+		}
+		a, err := l(s)
+		if err != nil {
+			return 0, err
+		}
+		b, err := r(s)
+		if err != nil {
+			return 0, err
+		}
+		return apply(a, b)
+	}}, nil
+}
+
+func unary(operator token, operand *typedNode) (*typedNode, error) {
+	if operator.text == "!" {
+		if err := expect(operand, Bool, operator.text); err != nil {
+			return nil, err
+		}
+		fn := operand.boolFn
+		return &typedNode{typ: Bool, pos: operator.pos, boolFn: func(s *state) (bool, error) {
+			if err := s.step(); err != nil {
+				return false, err
+			}
+			value, err := fn(s)
+			return !value, err
+		}}, nil
+	}
+
+	if err := expect(operand, Int, operator.text); err != nil {
+		return nil, err
+	}
+	fn := operand.intFn
+	return &typedNode{typ: Int, pos: operator.pos, intFn: func(s *state) (int64, error) {
+		if err := s.step(); err != nil {
+			return 0, err
+		}
+		value, err := fn(s)
+		return -value, err
+	}}, nil
+}
+
+func call(name token, b builtin, args []*typedNode) *typedNode {
+	fns := make([]func(s *state) (int64, error), len(args))
+	for i, arg := range args {
+		fns[i] = arg.intFn
+	}
+	return &typedNode{typ: Int, pos: name.pos, intFn: func(s *state) (int64, error) {
+		if err := s.step(); err != nil {
+			return 0, err
+		}
+		// builtins have at most two arguments, so this does not escape
+		var values [2]int64
+		for i, fn := range fns {
+			value, err := fn(s)
+			if err != nil {
+				return 0, err
+			}
+			values[i] = value
+		}
+		return b.fn(values[:len(fns)]), nil
+	}}
+}
@@ -0,0 +1,120 @@
+// Package expr implements a small sandboxed expression language over 64-bit integers, such as `n % 3 == 0 && n > 10`.
+//
+// Expressions support integer literals, variables, the `+ - * / %` arithmetic operators, the `== != < <= > >=`
+// comparisons, the `&& || !` logical operators, parentheses, and the `abs(x)`, `min(a, b)` and `max(a, b)` builtins.
+// Programs have no access to anything but their variables, and their evaluation is bounded by a Budget.
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/cache"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+const (
+	// cacheEntries is the maximum number of compiled programs kept in memory
+	cacheEntries = 1024
+	// cacheTTL is the duration after which an unused compiled program is evicted
+	cacheTTL = time.Hour
+)
+
+// Type is the static type of an expression
+type Type int
+
+// Types of expressions, integers being int64
+const (
+	Int Type = iota
+	Bool
+)
+
+type (
+	// Program is a compiled expression, safe for concurrent use
+	Program struct {
+		source string
+		vars   []string
+		root   *typedNode
+	}
+	// SyntaxError is returned by Compile for invalid expressions, Pos being the byte offset of the error in the source
+	SyntaxError struct {
+		Pos int
+		Msg string
+	}
+	// RuntimeError is returned when an evaluation fails, such as a division by zero
+	RuntimeError struct {
+		Pos int
+		Msg string
+	}
+)
+
+var (
+	// ErrStepBudgetExceeded is returned once every step of a Budget has been consumed
+	ErrStepBudgetExceeded = errors.New("expression evaluation step budget exceeded")
+	// ErrTimeBudgetExceeded is returned once the deadline of a Budget has been exceeded
+	ErrTimeBudgetExceeded = errors.New("expression evaluation time budget exceeded")
+)
+
+var programs = cache.New[*Program](cacheEntries, cacheEntries*maxSourceLength)
+
+func (t Type) String() string {
+	if t == Bool {
+		return "bool"
+	}
+	return "int"
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+// Compile parses and type checks an expression whose only identifiers are the given variables.
+// Programs are cached by source and variables, so compiling the same expression twice is cheap.
+// Invalid expressions return a 400 error wrapping a *SyntaxError.
+func Compile(source string, vars ...string) (*Program, error) {
+	key := strings.Join(vars, ",") + ":" + source
+	if program, ok := programs.Get(key); ok {
+		return program, nil
+	}
+
+	root, err := parse(source, vars)
+	if err != nil {
+		return nil, coreErrors.BadRequest(err, "invalid expression `%s`, %s", source, err.Error())
+	}
+	program := &Program{source: source, vars: vars, root: root}
+	programs.Set(key, program, len(source), cacheTTL)
+	return program, nil
+}
+
+// Source returns the expression the Program was compiled from.
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Type returns the static type of the Program result.
+func (p *Program) Type() Type {
+	return p.root.typ
+}
+
+// EvalInt evaluates an Int Program, given the values of its variables in declaration order.
+func (p *Program) EvalInt(meter *Meter, vars ...int64) (int64, error) {
+	if p.root.typ != Int {
+		return 0, errors.Errorf("expression `%s` is a %s, not an int", p.source, p.root.typ)
+	}
+	return p.root.intFn(&state{vars: vars, meter: meter})
+}
+
+// EvalBool evaluates a Bool Program, given the values of its variables in declaration order.
+func (p *Program) EvalBool(meter *Meter, vars ...int64) (bool, error) {
+	if p.root.typ != Bool {
+		return false, errors.Errorf("expression `%s` is an %s, not a bool", p.source, p.root.typ)
+	}
+	return p.root.boolFn(&state{vars: vars, meter: meter})
+}
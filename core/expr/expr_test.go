@@ -0,0 +1,115 @@
+package expr
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+func TestEval(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		source   string
+		n        int64
+		expected any
+	}{
+		{"n % 3 == 0 && n > 10", 12, true},
+		{"n % 3 == 0 && n > 10", 9, false},
+		{"n % 3 == 0 || n % 5 == 0", 10, true},
+		{"!(n < 0)", -1, false},
+		{"1 + 2 * 3 - 4 / 2", 0, int64(5)},
+		{"(1 + 2) * 3 % 4", 0, int64(1)},
+		{"-n + abs(-n) + min(n, 2) + max(n, 2)", 5, int64(7)},
+		{"n * n", math.MaxInt64, int64(1)},
+		{"true == (n != 1)", 2, true},
+		// short-circuiting skips the division by zero
+		{"n == 0 || 10 / n > 1", 0, true},
+	}
+	for _, test := range tests {
+		program, err := Compile(test.source, "n")
+		if !a.NoError(err, test.source) {
+			continue
+		}
+		meter := NewBudget(1000, time.Second).Meter()
+		switch expected := test.expected.(type) {
+		case bool:
+			value, err := program.EvalBool(meter, test.n)
+			a.NoError(err, test.source)
+			a.Equal(expected, value, test.source)
+		case int64:
+			value, err := program.EvalInt(meter, test.n)
+			a.NoError(err, test.source)
+			a.Equal(expected, value, test.source)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		source string
+		pos    int
+	}{
+		{"", 0},
+		{"n %", 3},
+		{"n $ 3", 2},
+		{"m == 3", 0},
+		{"n == 3 == 4", 7},
+		{"n && true", 0},
+		{"(n > 1", 6},
+		{"foo(n)", 0},
+		{"min(n)", 0},
+		{"n + true", 4},
+		{"99999999999999999999", 0},
+	}
+	for _, test := range tests {
+		_, err := Compile(test.source, "n")
+		httpErr, ok := err.(*coreErrors.Error)
+		if !a.Truef(ok, "%s: error is not a valid *errors.Error", test.source) {
+			continue
+		}
+		a.Equal(http.StatusBadRequest, httpErr.HttpCode, test.source)
+		var syntaxErr *SyntaxError
+		if a.True(errors.As(err, &syntaxErr), test.source) {
+			a.Equal(test.pos, syntaxErr.Pos, test.source)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	a := assert.New(t)
+
+	program, err := Compile("n / (n - 1) > 0", "n")
+	if !a.NoError(err) {
+		return
+	}
+	cached, err := Compile("n / (n - 1) > 0", "n")
+	a.NoError(err)
+	a.Same(program, cached, "compiled programs are cached")
+
+	var runtimeErr *RuntimeError
+	_, err = program.EvalBool(NewBudget(100, time.Second).Meter(), 1)
+	if a.True(errors.As(err, &runtimeErr)) {
+		a.Equal(2, runtimeErr.Pos)
+	}
+
+	// each evaluation takes 6 steps
+	budget := NewBudget(10, time.Second)
+	meter := budget.Meter()
+	_, err = program.EvalBool(meter, 3)
+	a.NoError(err)
+	_, err = program.EvalBool(meter, 3)
+	a.ErrorIs(err, ErrStepBudgetExceeded)
+	a.Zero(budget.Remaining())
+
+	_, err = program.EvalBool(NewBudget(100, -time.Second).Meter(), 3)
+	a.ErrorIs(err, ErrTimeBudgetExceeded)
+}
@@ -0,0 +1,387 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxSourceLength is the maximum length in bytes of an expression
+	maxSourceLength = 1024
+	// maxDepth bounds the nesting of sub-expressions, so parsing and evaluation cannot exhaust the stack
+	maxDepth = 64
+)
+
+type (
+	tokenKind int
+	token     struct {
+		kind  tokenKind
+		text  string
+		pos   int
+		value int64
+	}
+	parser struct {
+		source string
+		vars   []string
+		tokens []token
+		next   int
+		depth  int
+	}
+	// typedNode is a compiled sub-expression, only one of its functions being set depending on its Type
+	typedNode struct {
+		typ    Type
+		pos    int
+		intFn  func(s *state) (int64, error)
+		boolFn func(s *state) (bool, error)
+	}
+)
+
+const (
+	tokenEOF tokenKind = iota
+	tokenInt
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// operators are sorted so that longer operators are matched first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!"}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(source); {
+		r, size := utf8.DecodeRuneInString(source[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+		case r >= '0' && r <= '9':
+			end := pos
+			for end < len(source) && source[end] >= '0' && source[end] <= '9' {
+				end++
+			}
+			value, err := strconv.ParseInt(source[pos:end], 10, 64)
+			if err != nil {
+				return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("integer literal '%s' overflows int64", source[pos:end])}
+			}
+			tokens = append(tokens, token{kind: tokenInt, text: source[pos:end], pos: pos, value: value})
+			pos = end
+		case r == '_' || unicode.IsLetter(r):
+			end := pos
+			for end < len(source) {
+				r, size := utf8.DecodeRuneInString(source[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[pos:end], pos: pos})
+			pos = end
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			pos++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			pos++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: pos})
+			pos++
+		default:
+			matched := false
+			for _, operator := range operators {
+				if len(source)-pos >= len(operator) && source[pos:pos+len(operator)] == operator {
+					tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: pos})
+					pos += len(operator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unexpected character '%c'", r)}
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+func parse(source string, vars []string) (*typedNode, error) {
+	if len(source) > maxSourceLength {
+		return nil, &SyntaxError{Pos: maxSourceLength, Msg: fmt.Sprintf("expressions cannot exceed %d bytes", maxSourceLength)}
+	}
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{source: source, vars: vars, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+	}
+	return root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+// acceptOperator consumes the next token if it is one of the given operators
+func (p *parser) acceptOperator(operators ...string) (token, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return t, false
+	}
+	for _, operator := range operators {
+		if t.text == operator {
+			return p.advance(), true
+		}
+	}
+	return t, false
+}
+
+func (p *parser) enter(pos int) error {
+	if p.depth++; p.depth > maxDepth {
+		return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("expressions cannot be nested more than %d times", maxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func expect(node *typedNode, typ Type, context string) error {
+	if node.typ != typ {
+		return &SyntaxError{Pos: node.pos, Msg: fmt.Sprintf("%s expects %s operands, got %s", context, typ, node.typ)}
+	}
+	return nil
+}
+
+// parseOr parses `and ('||' and)*`
+func (p *parser) parseOr() (*typedNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := p.acceptOperator("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(operator, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseAnd parses `comparison ('&&' comparison)*`
+func (p *parser) parseAnd() (*typedNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := p.acceptOperator("&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(operator, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseComparison parses `additive (comparator additive)?`, comparisons are not associative
+func (p *parser) parseComparison() (*typedNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	operator, ok := p.acceptOperator("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if next, ok := p.acceptOperator("==", "!=", "<", "<=", ">", ">="); ok {
+		return nil, &SyntaxError{Pos: next.pos, Msg: "comparisons cannot be chained, use && instead"}
+	}
+	return comparison(operator, left, right)
+}
+
+// parseAdditive parses `multiplicative (('+' | '-') multiplicative)*`
+func (p *parser) parseAdditive() (*typedNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := p.acceptOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(operator, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseMultiplicative parses `unary (('*' | '/' | '%') unary)*`
+func (p *parser) parseMultiplicative() (*typedNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := p.acceptOperator("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(operator, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseUnary parses `('!' | '-') unary | primary`
+func (p *parser) parseUnary() (*typedNode, error) {
+	operator, ok := p.acceptOperator("!", "-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	if err := p.enter(operator.pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return unary(operator, operand)
+}
+
+// parsePrimary parses literals, variables, function calls and parenthesized expressions
+func (p *parser) parsePrimary() (*typedNode, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenInt:
+		value := t.value
+		return &typedNode{typ: Int, pos: t.pos, intFn: func(s *state) (int64, error) {
+			return value, s.step()
+		}}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			value := t.text == "true"
+			return &typedNode{typ: Bool, pos: t.pos, boolFn: func(s *state) (bool, error) {
+				return value, s.step()
+			}}, nil
+		}
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(t)
+		}
+		for i, name := range p.vars {
+			if name == t.text {
+				index := i
+				return &typedNode{typ: Int, pos: t.pos, intFn: func(s *state) (int64, error) {
+					return s.vars[index], s.step()
+				}}, nil
+			}
+		}
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unknown identifier '%s'", t.text)}
+	case tokenLParen:
+		if err := p.enter(t.pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: closing.pos, Msg: fmt.Sprintf("expected ')', got %s", closing)}
+		}
+		return node, nil
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+	}
+}
+
+// parseCall parses the arguments of a builtin function call
+func (p *parser) parseCall(name token) (*typedNode, error) {
+	b, ok := builtins[name.text]
+	if !ok {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("unknown function '%s'", name.text)}
+	}
+	if err := p.enter(name.pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	p.advance()
+	var args []*typedNode
+	if p.peek().kind != tokenRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := expect(arg, Int, name.text); err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.advance()
+		}
+	}
+	if closing := p.advance(); closing.kind != tokenRParen {
+		return nil, &SyntaxError{Pos: closing.pos, Msg: fmt.Sprintf("expected ')', got %s", closing)}
+	}
+	if len(args) != b.arity {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("%s expects %d arguments, got %d", name.text, b.arity, len(args))}
+	}
+	return call(name, b, args), nil
+}
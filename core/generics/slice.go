@@ -0,0 +1,11 @@
+package generics
+
+// SliceContains checks whether a value is present in a given slice
+func SliceContains[V comparable](slice []V, value V) bool {
+	for _, v := range slice {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package health
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// WritableDir returns a Check failing whenever files cannot be created in dir, which is created if missing.
+// It is meant for subsystems persisting their state to a local directory.
+func WritableDir(dir string) Check {
+	return func(_ context.Context) error {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return errors.Wrapf(err, "could not create directory '%s'", dir)
+		}
+		f, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return errors.Wrapf(err, "directory '%s' is not writable", dir)
+		}
+		err = f.Close()
+		if removeErr := os.Remove(f.Name()); err == nil {
+			err = removeErr
+		}
+		return err
+	}
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type (
+	// Probe is the kind of probe a Check participates in
+	Probe string
+	// Status is the outcome of a Check, or of a whole Report
+	Status string
+	// Check is a named health check, it should return a non nil error whenever the subsystem it monitors is unhealthy.
+	// The given context.Context is done once the check timeout is exceeded.
+	Check func(ctx context.Context) error
+	// CheckResult is the outcome of a single Check
+	CheckResult struct {
+		Name    string `json:"name"`
+		Status  Status `json:"status"`
+		Latency string `json:"latency,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+	// Report is the aggregated outcome of every Check registered for a given Probe
+	Report struct {
+		Status Status        `json:"status"`
+		Checks []CheckResult `json:"checks"`
+	}
+)
+
+const (
+	// Liveness checks report whether the application should be restarted
+	Liveness Probe = "liveness"
+	// Readiness checks report whether the application can receive traffic, every Liveness check is also part of
+	// the readiness Report
+	Readiness Probe = "readiness"
+
+	// StatusOk is reported when every check succeeded
+	StatusOk Status = "ok"
+	// StatusFailing is reported when at least one check failed
+	StatusFailing Status = "failing"
+)
+
+// Register adds a named Check to the given Probe, each run is bounded by timeout, 0 disables it.
+// Registering a Check with an already existing name replaces it.
+func Register(name string, probe Probe, timeout time.Duration, check Check) {
+	globalRegistry.register(name, probe, timeout, check)
+}
+
+// Unregister removes a named Check, this is a noop if the Check does not exist.
+func Unregister(name string) {
+	globalRegistry.unregister(name)
+}
+
+// Run executes every Check associated with the given Probe concurrently, and aggregates them into a Report.
+func Run(ctx context.Context, probe Probe) Report {
+	return globalRegistry.report(ctx, probe)
+}
+
+// StatusCode allows the Report to be used as a http.StatusCoder, failing reports are served as 503.
+func (r Report) StatusCode() int {
+	if r.Status == StatusOk {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
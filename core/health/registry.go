@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type check struct {
+	name    string
+	probe   Probe
+	timeout time.Duration
+	fn      Check
+}
+
+type registry struct {
+	lock   sync.RWMutex
+	checks map[string]check
+}
+
+var (
+	once           sync.Once
+	globalRegistry *registry
+)
+
+func init() {
+	once.Do(func() {
+		globalRegistry = newRegistry()
+	})
+}
+
+func newRegistry() *registry {
+	return &registry{
+		checks: make(map[string]check),
+	}
+}
+
+func (r *registry) register(name string, probe Probe, timeout time.Duration, fn Check) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.checks[name] = check{
+		name:    name,
+		probe:   probe,
+		timeout: timeout,
+		fn:      fn,
+	}
+}
+
+func (r *registry) unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.checks, name)
+}
+
+func (r *registry) report(ctx context.Context, probe Probe) Report {
+	r.lock.RLock()
+	checks := make([]check, 0, len(r.checks))
+	for _, c := range r.checks {
+		// readiness implies liveness, a dead application can't be ready
+		if c.probe == probe || probe == Readiness {
+			checks = append(checks, c)
+		}
+	}
+	r.lock.RUnlock()
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].name < checks[j].name
+	})
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for idx, c := range checks {
+		idx, c := idx, c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[idx] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	status := StatusOk
+	if probe == Readiness && IsDraining() {
+		status = StatusFailing
+		results = append(results, CheckResult{Name: "shutdown", Status: StatusFailing, Error: "server is shutting down"})
+	}
+	for _, result := range results {
+		if result.Status != StatusOk {
+			status = StatusFailing
+		}
+	}
+	return Report{
+		Status: status,
+		Checks: results,
+	}
+}
+
+func (c check) run(ctx context.Context) CheckResult {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.Wrapf(ctx.Err(), "check did not complete in time")
+	}
+
+	result := CheckResult{
+		Name:    c.name,
+		Status:  StatusOk,
+		Latency: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
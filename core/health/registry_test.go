@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryReport(t *testing.T) {
+	a := assert.New(t)
+	r := newRegistry()
+
+	r.register("alive", Liveness, 0, func(_ context.Context) error {
+		return nil
+	})
+	r.register("store", Readiness, 0, func(_ context.Context) error {
+		return errors.New("store is unreachable")
+	})
+	r.register("slow", Readiness, 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+
+	liveness := r.report(context.Background(), Liveness)
+	a.Equal(StatusOk, liveness.Status, "liveness should not include readiness checks")
+	a.Len(liveness.Checks, 1)
+	a.Equal(http.StatusOK, liveness.StatusCode())
+
+	readiness := r.report(context.Background(), Readiness)
+	a.Equal(StatusFailing, readiness.Status)
+	a.Equal(http.StatusServiceUnavailable, readiness.StatusCode())
+	if a.Len(readiness.Checks, 3, "readiness should include liveness checks") {
+		// checks are sorted by name
+		a.Equal("alive", readiness.Checks[0].Name)
+		a.Equal(StatusOk, readiness.Checks[0].Status)
+		a.Equal("slow", readiness.Checks[1].Name)
+		a.Equal(StatusFailing, readiness.Checks[1].Status, "timed out check should be failing")
+		a.Equal("store", readiness.Checks[2].Name)
+		a.Equal("store is unreachable", readiness.Checks[2].Error)
+	}
+
+	r.unregister("store")
+	r.unregister("slow")
+	a.Equal(StatusOk, r.report(context.Background(), Readiness).Status)
+}
+
+func TestWritableDir(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	a.NoError(WritableDir(filepath.Join(dir, "store"))(ctx), "missing directories are created")
+	entries, err := os.ReadDir(filepath.Join(dir, "store"))
+	if a.NoError(err) {
+		a.Empty(entries, "no file is left behind")
+	}
+
+	file := filepath.Join(dir, "file")
+	a.NoError(os.WriteFile(file, nil, 0o600))
+	a.Error(WritableDir(file)(ctx))
+}
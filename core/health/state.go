@@ -0,0 +1,16 @@
+package health
+
+import "sync/atomic"
+
+var draining atomic.Bool
+
+// Drain marks the application as shutting down, readiness is reported as failing from this point onward.
+// This is called by http.Server once a shutdown has been requested.
+func Drain() {
+	draining.Store(true)
+}
+
+// IsDraining checks whether the application is shutting down.
+func IsDraining() bool {
+	return draining.Load()
+}
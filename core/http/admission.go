@@ -0,0 +1,186 @@
+package http
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+type (
+	// Coster can be implemented by a GenericHandler request to declare its cost up front.
+	// Requests are admitted as long as the sum of in-flight costs stays within config.Manifest CostBudget, and
+	// queued for at most CostQueueTimeout otherwise.
+	Coster interface {
+		Cost() uint64
+	}
+	admissionWaiter struct {
+		cost  uint64
+		ready chan struct{}
+	}
+	// admissionController is a weighted FIFO semaphore, bounding the total cost of in-flight requests
+	admissionController struct {
+		lock         sync.Mutex
+		budget       uint64
+		inUse        uint64
+		queueTimeout time.Duration
+		waiters      list.List
+		admitted     uint64
+		rejected     uint64
+	}
+)
+
+var globalAdmission *admissionController
+
+func init() {
+	globalAdmission = newAdmissionController(config.Config.CostBudget, config.Config.CostQueueTimeout)
+
+	metrics.RegisterGauge(semconv.MetricName("admission", "cost", "budget"),
+		"Total cost of requests which can be processed concurrently, 0 if unbounded.",
+		func() float64 { return float64(globalAdmission.stats().budget) })
+	metrics.RegisterGauge(semconv.MetricName("admission", "cost", "in", "use"),
+		"Total cost of requests currently being processed.",
+		func() float64 { return float64(globalAdmission.stats().inUse) })
+	metrics.RegisterGauge(semconv.MetricName("admission", "queued", "requests"),
+		"Number of requests waiting for admission.",
+		func() float64 { return float64(globalAdmission.stats().queued) })
+	metrics.RegisterCounter(semconv.MetricName("admission", "admitted", "total"),
+		"Total number of admitted requests.",
+		func() float64 { return float64(globalAdmission.stats().admitted) })
+	metrics.RegisterCounter(semconv.MetricName("admission", "rejected", "total"),
+		"Total number of requests rejected because of an exhausted budget.",
+		func() float64 { return float64(globalAdmission.stats().rejected) })
+}
+
+// admit acquires the cost of a Coster request from the global admission controller, the returned function releasing
+// it once the request has been processed
+func admit(ctx context.Context, request any) (func(), error) {
+	coster, ok := request.(Coster)
+	if !ok {
+		return func() {}, nil
+	}
+	cost := coster.Cost()
+	if err := globalAdmission.acquire(ctx, cost); err != nil {
+		return nil, err
+	}
+	return func() { globalAdmission.release(cost) }, nil
+}
+
+func newAdmissionController(budget uint64, queueTimeout time.Duration) *admissionController {
+	return &admissionController{
+		budget:       budget,
+		queueTimeout: queueTimeout,
+	}
+}
+
+// acquire admits a request of the given cost, waiting in line for at most the queue timeout.
+// It returns a 503 error if the request could not be admitted in time, or a 400 error if its cost exceeds the budget.
+func (a *admissionController) acquire(ctx context.Context, cost uint64) error {
+	if a.budget == 0 {
+		return nil
+	}
+
+	a.lock.Lock()
+	if cost > a.budget {
+		a.rejected++
+		a.lock.Unlock()
+		return errors.BadRequest(nil, "request cost %d exceeds the instance budget of %d", cost, a.budget)
+	}
+	if a.inUse+cost <= a.budget && a.waiters.Len() == 0 {
+		a.inUse += cost
+		a.admitted++
+		a.lock.Unlock()
+		return nil
+	}
+	if a.queueTimeout <= 0 {
+		a.rejected++
+		a.lock.Unlock()
+		return errors.Unavailable(nil, "instance cost budget exhausted")
+	}
+
+	waiter := &admissionWaiter{cost: cost, ready: make(chan struct{})}
+	elem := a.waiters.PushBack(waiter)
+	a.lock.Unlock()
+
+	timer := time.NewTimer(a.queueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-timer.C:
+		err = errors.Unavailable(nil, "instance cost budget exhausted, request was queued for %s", a.queueTimeout)
+	case <-ctx.Done():
+		err = errors.Unavailable(ctx.Err(), "request was cancelled while queued for admission")
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	select {
+	case <-waiter.ready:
+		// admitted concurrently with the timeout or cancellation
+		return nil
+	default:
+	}
+	a.waiters.Remove(elem)
+	a.rejected++
+	// a large request leaving the front of the queue might unblock the next ones
+	a.notifyWaiters()
+	return err
+}
+
+// release gives back the cost of a request admitted through acquire.
+func (a *admissionController) release(cost uint64) {
+	if a.budget == 0 {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.inUse -= cost
+	a.notifyWaiters()
+}
+
+// notifyWaiters admits queued requests in FIFO order, as long as they fit in the budget.
+// It expects the lock to be held.
+func (a *admissionController) notifyWaiters() {
+	for {
+		front := a.waiters.Front()
+		if front == nil {
+			return
+		}
+		waiter := front.Value.(*admissionWaiter)
+		if a.inUse+waiter.cost > a.budget {
+			return
+		}
+		a.inUse += waiter.cost
+		a.admitted++
+		a.waiters.Remove(front)
+		close(waiter.ready)
+	}
+}
+
+type admissionStats struct {
+	budget, inUse, admitted, rejected uint64
+	queued                            int
+}
+
+func (a *admissionController) stats() admissionStats {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return admissionStats{
+		budget:   a.budget,
+		inUse:    a.inUse,
+		admitted: a.admitted,
+		rejected: a.rejected,
+		queued:   a.waiters.Len(),
+	}
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+func TestAdmissionController(t *testing.T) {
+	a := assert.New(t)
+	controller := newAdmissionController(10, 50*time.Millisecond)
+	ctx := context.Background()
+
+	assertStatus := func(err error, status int, name string) {
+		httpErr, ok := err.(*errors.Error)
+		if a.Truef(ok, "%s: error is not a valid *errors.Error", name) {
+			a.Equal(status, httpErr.HttpCode, name)
+		}
+	}
+	assertUnavailable := func(err error, name string) {
+		assertStatus(err, http.StatusServiceUnavailable, name)
+	}
+
+	assertStatus(controller.acquire(ctx, 11), http.StatusBadRequest, "request costlier than the budget")
+	a.NoError(controller.acquire(ctx, 6))
+	a.NoError(controller.acquire(ctx, 4))
+	assertUnavailable(controller.acquire(ctx, 1), "queued request timing out")
+
+	admitted := make(chan error)
+	go func() {
+		admitted <- controller.acquire(ctx, 5)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	a.Equal(1, controller.stats().queued)
+	controller.release(6)
+	a.NoError(<-admitted, "queued request should be admitted once budget is released")
+
+	stats := controller.stats()
+	a.Equal(uint64(9), stats.inUse)
+	a.Equal(uint64(3), stats.admitted)
+	a.Equal(uint64(2), stats.rejected)
+}
+
+type costlyRequest struct {
+	Items uint64 `query:"items"`
+}
+
+func (r costlyRequest) Cost() uint64 {
+	return r.Items
+}
+
+func TestAdmissionRetryAfter(t *testing.T) {
+	a := assert.New(t)
+	previous := globalAdmission
+	globalAdmission = newAdmissionController(10, 0)
+	defer func() { globalAdmission = previous }()
+	s := NewServer(Get("/costly", func(context.Context, costlyRequest) (*Empty, error) { return &Empty{}, nil }))
+	serve := func(items string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/costly?items="+items, nil))
+		return recorder
+	}
+
+	a.Equal(http.StatusOK, serve("10").Code)
+	response := serve("11")
+	a.Equal(http.StatusBadRequest, response.Code)
+	a.Empty(response.Header().Get(headerRetryAfter), "requests costlier than the budget are never admitted")
+
+	a.NoError(globalAdmission.acquire(context.Background(), 10))
+	response = serve("1")
+	a.Equal(http.StatusServiceUnavailable, response.Code)
+	a.Equal("1", response.Header().Get(headerRetryAfter))
+}
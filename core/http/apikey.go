@@ -0,0 +1,98 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+const (
+	apiKeyMethod      = "apikey"
+	apiKeyHeaderName  = "apiKeyHeader"
+	apiKeyQueryName   = "apiKeyQuery"
+	apiKeyDescription = "API key issued to a client"
+)
+
+// apiKeyAuthenticator authenticates clients using API keys sent through either a header or a query parameter.
+// Keys are stored hashed, so lookups don't leak timing information about key contents.
+type apiKeyAuthenticator struct {
+	header  string
+	query   string
+	clients map[[sha256.Size]byte]string
+}
+
+// newApiKeyAuthenticator loads API keys from config.Manifest, it returns nil if no key is configured.
+func newApiKeyAuthenticator() (*apiKeyAuthenticator, error) {
+	keys := make(map[string]string)
+	if config.Config.ApiKeysFile != "" {
+		buf, err := os.ReadFile(config.Config.ApiKeysFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read API keys file '%s'", config.Config.ApiKeysFile)
+		}
+		if err = json.Unmarshal(buf, &keys); err != nil {
+			return nil, errors.Wrapf(err, "invalid API keys file '%s'", config.Config.ApiKeysFile)
+		}
+	}
+	for client, key := range config.Config.ApiKeys {
+		keys[client] = key
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	clients := make(map[[sha256.Size]byte]string, len(keys))
+	for client, key := range keys {
+		if key == "" {
+			return nil, errors.Errorf("empty API key for client '%s'", client)
+		}
+		clients[sha256.Sum256([]byte(key))] = client
+	}
+	return &apiKeyAuthenticator{
+		header:  config.Config.ApiKeyHeader,
+		query:   config.Config.ApiKeyQuery,
+		clients: clients,
+	}, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(c echo.Context) (identity.Identity, bool, error) {
+	key := c.Request().Header.Get(a.header)
+	if key == "" && a.query != "" {
+		key = c.QueryParam(a.query)
+	}
+	if key == "" {
+		return identity.Identity{}, false, nil
+	}
+
+	client, ok := a.clients[sha256.Sum256([]byte(key))]
+	if !ok {
+		return identity.Identity{}, false, coreErrors.Unauthorized(nil, "invalid API key")
+	}
+	return identity.Identity{ClientId: client, Method: apiKeyMethod}, true, nil
+}
+
+func (a *apiKeyAuthenticator) SecuritySchemes() map[string]openapi3.SecurityScheme {
+	description := apiKeyDescription
+	schemes := map[string]openapi3.SecurityScheme{
+		apiKeyHeaderName: {APIKeySecurityScheme: &openapi3.APIKeySecurityScheme{
+			Name:        a.header,
+			In:          openapi3.APIKeySecuritySchemeInHeader,
+			Description: &description,
+		}},
+	}
+	if a.query != "" {
+		schemes[apiKeyQueryName] = openapi3.SecurityScheme{APIKeySecurityScheme: &openapi3.APIKeySecurityScheme{
+			Name:        a.query,
+			In:          openapi3.APIKeySecuritySchemeInQuery,
+			Description: &description,
+		}}
+	}
+	return schemes
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/swaggest/openapi-go/openapi3"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+// Authenticator extracts a client identity.Identity from incoming requests.
+type Authenticator interface {
+	// Authenticate returns the identity.Identity of the request client.
+	// ok is false whenever the request carries no credentials for this Authenticator, err is set whenever the request
+	// carries invalid credentials.
+	Authenticate(c echo.Context) (id identity.Identity, ok bool, err error)
+	// SecuritySchemes returns the openapi3 security schemes implemented by this Authenticator, by name.
+	SecuritySchemes() map[string]openapi3.SecurityScheme
+}
+
+// defaultAuthenticators returns every Authenticator enabled through configuration.
+func defaultAuthenticators() ([]Authenticator, error) {
+	var authenticators []Authenticator
+
+	apiKeys, err := newApiKeyAuthenticator()
+	if err != nil {
+		return nil, err
+	}
+	if apiKeys != nil {
+		authenticators = append(authenticators, apiKeys)
+	}
+
+	bearer, err := newJwtAuthenticator()
+	if err != nil {
+		return nil, err
+	}
+	if bearer != nil {
+		authenticators = append(authenticators, bearer)
+		health.Register("jwks", health.Readiness, time.Second, bearer.check)
+	} else {
+		health.Unregister("jwks")
+	}
+	return authenticators, nil
+}
+
+// securityRequirements registers every security scheme into the openapi3 spec, and returns the associated
+// operation security requirements, any of them being sufficient.
+func securityRequirements(reflector openapi3.Reflector, authenticators []Authenticator) []map[string][]string {
+	var requirements []map[string][]string
+	schemes := reflector.Spec.ComponentsEns().SecuritySchemesEns()
+	for _, authenticator := range authenticators {
+		authenticatorSchemes := authenticator.SecuritySchemes()
+		names := generics.MapKeys(authenticatorSchemes)
+		sort.Strings(names)
+		for _, name := range names {
+			scheme := authenticatorSchemes[name]
+			schemes.WithMapOfSecuritySchemeOrRefValuesItem(name, openapi3.SecuritySchemeOrRef{SecurityScheme: &scheme})
+			requirements = append(requirements, map[string][]string{name: {}})
+		}
+	}
+	return requirements
+}
+
+// authMiddleware authenticates requests using the first Authenticator matching the request credentials.
+// The resulting identity.Identity is stored in the request context.Context, and attached to its logger.
+func authMiddleware(authenticators []Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			for _, authenticator := range authenticators {
+				id, ok, err := authenticator.Authenticate(c)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+
+				ctx := identity.Inject(c.Request().Context(), id)
+				ctx = logger.Inject(ctx, zap.Fields(
+					zap.String("client.id", id.ClientId),
+					zap.String("client.auth", id.Method),
+				))
+				c.SetRequest(c.Request().WithContext(ctx))
+				return next(c)
+			}
+			return errors.Unauthorized(nil, "missing credentials")
+		}
+	}
+}
+
+// scopesMiddleware rejects authenticated requests whose identity.Identity lacks any of the given scopes.
+func scopesMiddleware(scopes []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, _ := identity.FromContext(c.Request().Context())
+			if !id.HasScopes(scopes...) {
+				return errors.Forbidden(nil, "missing required scopes %v", scopes)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+func TestApiKeyAuthentication(t *testing.T) {
+	a := assert.New(t)
+	config.Config.ApiKeys = map[string]string{"loadgen": "secret"}
+	defer func() { config.Config.ApiKeys = nil }()
+
+	authenticators, err := defaultAuthenticators()
+	if !a.NoError(err) || !a.Len(authenticators, 1) {
+		return
+	}
+
+	var clientId string
+	handler := authMiddleware(authenticators)(func(c echo.Context) error {
+		clientId = identity.ClientId(c.Request().Context())
+		return nil
+	})
+
+	for _, test := range []struct {
+		name       string
+		target     string
+		header     string
+		statusCode int
+		clientId   string
+	}{
+		{name: "missing key is rejected", target: "/", statusCode: http.StatusUnauthorized},
+		{name: "invalid key is rejected", target: "/", header: "nope", statusCode: http.StatusUnauthorized},
+		{name: "header key is accepted", target: "/", header: "secret", clientId: "loadgen"},
+		{name: "query key is accepted", target: "/?api_key=secret", clientId: "loadgen"},
+	} {
+		clientId = ""
+		req := httptest.NewRequest(http.MethodGet, test.target, nil)
+		if test.header != "" {
+			req.Header.Set(config.Config.ApiKeyHeader, test.header)
+		}
+		err = handler(echo.New().NewContext(req, httptest.NewRecorder()))
+		if test.statusCode != 0 {
+			httpErr, ok := err.(*errors.Error)
+			if a.Truef(ok, "%s: error is not a valid *errors.Error", test.name) {
+				a.Equal(test.statusCode, httpErr.HttpCode, test.name)
+			}
+			continue
+		}
+		a.NoError(err, test.name)
+		a.Equal(test.clientId, clientId, test.name)
+	}
+}
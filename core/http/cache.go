@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/cache"
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+const (
+	headerCacheControl = "Cache-Control"
+	headerXCache       = "X-Cache"
+
+	cacheHit    = "HIT"
+	cacheMiss   = "MISS"
+	cacheBypass = "BYPASS"
+)
+
+type (
+	// cachedResponse is a serialized GenericHandler response
+	cachedResponse struct {
+		status int
+		body   []byte
+	}
+	// Cacheable can be implemented by a GenericHandler request whose response does not always depend on the request
+	// alone, such as requests reading mutable server-side state. Requests which are not cacheable skip the response
+	// cache, request ETags and the handler Cache-Control header, their ETag being derived from the response body.
+	Cacheable interface {
+		Cacheable() bool
+	}
+	// Charger can be implemented by a GenericHandler request whose handler consumes a quota. Responses served from
+	// the response cache skip the handler, the request is charged instead.
+	Charger interface {
+		Charge(ctx context.Context) error
+	}
+)
+
+// globalResponseCache is shared by every handler using Handler.WithCache, it is nil if disabled through configuration
+var globalResponseCache *cache.LRU[cachedResponse]
+
+func init() {
+	if config.Config.CacheMaxBytes <= 0 || config.Config.CacheMaxEntries <= 0 {
+		return
+	}
+	globalResponseCache = cache.New[cachedResponse](config.Config.CacheMaxEntries, config.Config.CacheMaxBytes)
+
+	metrics.RegisterCounter(semconv.MetricName("cache", "hits", "total"),
+		"Total number of responses served from the response cache.",
+		func() float64 { return float64(globalResponseCache.Stats().Hits) })
+	metrics.RegisterCounter(semconv.MetricName("cache", "misses", "total"),
+		"Total number of response cache lookups which missed.",
+		func() float64 { return float64(globalResponseCache.Stats().Misses) })
+	metrics.RegisterCounter(semconv.MetricName("cache", "evictions", "total"),
+		"Total number of responses evicted from the response cache to honor its bounds.",
+		func() float64 { return float64(globalResponseCache.Stats().Evictions) })
+	metrics.RegisterGauge(semconv.MetricName("cache", "entries"),
+		"Number of responses currently cached.",
+		func() float64 { return float64(globalResponseCache.Stats().Entries) })
+	metrics.RegisterGauge(semconv.MetricName("cache", "bytes"),
+		"Total size of responses currently cached.",
+		func() float64 { return float64(globalResponseCache.Stats().Bytes) })
+}
+
+// cachePolicy is the response cache behavior requested by the client through the Cache-Control header.
+// no-cache forces a fresh response which is then cached, while no-store skips the cache entirely.
+type cachePolicy struct {
+	lookup bool
+	store  bool
+}
+
+func requestCachePolicy(c echo.Context) cachePolicy {
+	policy := cachePolicy{lookup: true, store: true}
+	for _, directive := range strings.Split(c.Request().Header.Get(headerCacheControl), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache":
+			policy.lookup = false
+		case "no-store":
+			policy.lookup = false
+			policy.store = false
+		}
+	}
+	return policy
+}
+
+// cacheKey derives a response cache key from the handler, the serialized request, and the response indentation
+func cacheKey(c echo.Context, h Handler, request []byte) string {
+	variant := "compact"
+	if indented(c) {
+		variant = "indented"
+	}
+	return h.method + " " + h.path + " " + variant + " " + string(request)
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+// chargedRequest counts its charges, rejecting them once its budget is spent
+type chargedRequest struct {
+	Name string `query:"name"`
+}
+
+var chargeBudget int
+
+func (chargedRequest) Charge(context.Context) error {
+	if chargeBudget == 0 {
+		return errors.TooManyRequests(nil, "quota exceeded")
+	}
+	chargeBudget--
+	return nil
+}
+
+func TestCacheCharge(t *testing.T) {
+	a := assert.New(t)
+	calls := 0
+	s := NewServer(Get("/charged", func(ctx context.Context, request chargedRequest) (*string, error) {
+		calls++
+		if err := request.Charge(ctx); err != nil {
+			return nil, err
+		}
+		return &request.Name, nil
+	}).WithCache(0))
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/charged?name=charge", nil))
+		return recorder
+	}
+
+	globalResponseCache.Purge()
+	chargeBudget = 2
+	a.Equal(cacheMiss, serve().Header().Get(headerXCache))
+	response := serve()
+	a.Equal(http.StatusOK, response.Code)
+	a.Equal(cacheHit, response.Header().Get(headerXCache))
+	a.Equal(1, calls)
+	a.Zero(chargeBudget, "cached responses are charged")
+	a.Equal(http.StatusTooManyRequests, serve().Code)
+}
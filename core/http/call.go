@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+// headerRecorder is a http.ResponseWriter only keeping headers, for calls guarded outside of echo
+type headerRecorder http.Header
+
+// Call invokes the implementation of a GenericHandler outside of echo, such as from a gRPC service, with an already
+// decoded request. The call goes through the same scopes check, metrics, admission control and deadline as HTTP
+// requests, while caching and ETags only apply to HTTP responses. Clients are authenticated by the caller, see
+// Server.Guard.
+func Call[Request any, Response any](ctx context.Context, h Handler, request Request) (*Response, error) {
+	if h.call == nil {
+		return nil, errors.Errorf("handler '%s' cannot be called outside of echo", h.operationId)
+	}
+	if err := h.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	timeout := h.timeout
+	if timeout == 0 {
+		timeout = config.Config.HandlerTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	response, err := h.call(h, ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	typed, ok := response.(*Response)
+	if !ok {
+		return nil, errors.Errorf("handler '%s' returns a %T response, not a %T", h.operationId, response, typed)
+	}
+	return typed, nil
+}
+
+// CallStream invokes the implementation of a Stream handler outside of echo, events being handed over to send
+// instead of being written as Server-Sent Events, and lastEventID resuming the stream like the `Last-Event-ID`
+// header. The call goes through the same scopes check and metrics as HTTP requests, see Call.
+// Callers are responsible for interrupting streams when shutting down.
+func CallStream[Request any, Data any](ctx context.Context, h Handler, request Request, lastEventID string, send func(event Event[Data]) error) error {
+	if h.callStream == nil {
+		return errors.Errorf("handler '%s' is not a stream", h.operationId)
+	}
+	if err := h.authorize(ctx); err != nil {
+		return err
+	}
+	return h.callStream(ctx, request, &EventStream[Data]{lastEventID: lastEventID, send: send})
+}
+
+// authorize rejects calls whose identity.Identity lacks any of the Handler scopes, like scopesMiddleware.
+// Anonymous calls are authorized, as authentication is only enforced when enabled.
+func (h Handler) authorize(ctx context.Context) error {
+	id, ok := identity.FromContext(ctx)
+	if ok && len(h.scopes) > 0 && !id.HasScopes(h.scopes...) {
+		return coreErrors.Forbidden(nil, "missing required scopes %v", h.scopes)
+	}
+	return nil
+}
+
+// Guard authenticates and rate limits a call served outside of echo, such as a gRPC call, like the public listener
+// does for HTTP requests. The call is described by its headers and the address of its client.
+// The returned context.Context carries the identity.Identity of the client, and the returned headers advertise the
+// client rate limits, whether the call has been rejected or not.
+func (s *Server) Guard(ctx context.Context, header http.Header, remoteAddr string) (context.Context, http.Header, error) {
+	request := (&http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: "/"},
+		Header:     header,
+		RemoteAddr: remoteAddr,
+	}).WithContext(ctx)
+	response := make(headerRecorder)
+	c := s.inner.NewContext(request, response)
+
+	guarded := ctx
+	next := func(c echo.Context) error {
+		guarded = c.Request().Context()
+		return nil
+	}
+	if s.limiters.enabled() {
+		next = rateLimitMiddleware(s.limiters, nil)(next)
+	}
+	if len(s.authenticators) > 0 {
+		next = authMiddleware(s.authenticators)(next)
+		if s.limiters.clients != nil {
+			next = authFailureLimitMiddleware(s.limiters)(next)
+		}
+	}
+	if err := next(c); err != nil {
+		return ctx, http.Header(response), err
+	}
+	return guarded, http.Header(response), nil
+}
+
+func (r headerRecorder) Header() http.Header {
+	return http.Header(r)
+}
+
+func (headerRecorder) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (headerRecorder) WriteHeader(int) {}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+type callRequest struct {
+	Name string `query:"name"`
+}
+
+func statusOf(err error) int {
+	if httpErr, ok := err.(*errors.Error); ok {
+		return httpErr.HttpCode
+	}
+	return 0
+}
+
+func TestCall(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	h := Get("/hello", func(ctx context.Context, request callRequest) (*string, error) {
+		if request.Name == "" {
+			return nil, errors.BadRequest(nil, "`name` query parameter is required")
+		}
+		_, hasDeadline := ctx.Deadline()
+		greeting := "hello " + request.Name
+		if !hasDeadline {
+			greeting += " without deadline"
+		}
+		return &greeting, nil
+	}).WithScopes("hello:read")
+
+	response, err := Call[callRequest, string](ctx, h, callRequest{Name: "alice"})
+	if a.NoError(err) {
+		a.Equal("hello alice", *response, "the handler deadline applies")
+	}
+	_, err = Call[callRequest, string](ctx, h, callRequest{})
+	a.Equal(http.StatusBadRequest, statusOf(err))
+	_, err = Call[callRequest, int](ctx, h, callRequest{Name: "alice"})
+	a.Error(err, "the response type must match the handler")
+	_, err = Call[string, string](ctx, h, "alice")
+	a.Error(err, "the request type must match the handler")
+
+	restricted := identity.Inject(ctx, identity.Identity{ClientId: "loadgen", Scopes: []string{"other:read"}})
+	_, err = Call[callRequest, string](restricted, h, callRequest{Name: "alice"})
+	a.Equal(http.StatusForbidden, statusOf(err))
+	granted := identity.Inject(ctx, identity.Identity{ClientId: "loadgen", Scopes: []string{"hello:read"}})
+	_, err = Call[callRequest, string](granted, h, callRequest{Name: "alice"})
+	a.NoError(err)
+
+	_, err = Call[Empty, Empty](ctx, EchoHandler("/echo", http.MethodGet, nil), Empty{})
+	a.Error(err, "echo handlers cannot be called")
+}
+
+func TestCallMetrics(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	impl := func(context.Context, Empty) (*Empty, error) { return &Empty{}, nil }
+	h := Get("/counted", impl)
+	probe := Get("/probe", impl).Admin().Anonymous()
+
+	metrics.StartAggregator(ctx)
+	defer func() { a.NoError(metrics.StopAggregator(ctx)) }()
+	a.NoError(metrics.Reset("/counted", "/probe"))
+
+	_, err := Call[Empty, Empty](ctx, probe, Empty{})
+	a.NoError(err)
+	_, err = Call[Empty, Empty](ctx, h, Empty{})
+	a.NoError(err)
+	a.Eventually(func() bool {
+		return metrics.Stats()["/counted"].Requests == 1
+	}, time.Second, 10*time.Millisecond)
+	a.Never(func() bool {
+		return metrics.Stats()["/probe"].Requests > 0
+	}, 50*time.Millisecond, 10*time.Millisecond, "admin requests are not counted")
+}
+
+func TestDispatchRequest(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	requestChan := make(chan metrics.Request, 1)
+	dropped := droppedRequests.Load()
+
+	dispatchRequest(ctx, "/dispatched", requestChan, []byte("a"))
+	a.Equal(dropped, droppedRequests.Load())
+	// a full request counter does not block the handler
+	dispatchRequest(ctx, "/dispatched", requestChan, []byte("b"))
+	a.Equal(dropped+1, droppedRequests.Load())
+	a.Equal([]byte("a"), (<-requestChan).Payload)
+}
+
+func TestCallStream(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	h := Stream("/stream", func(_ context.Context, request callRequest, stream *EventStream[string]) error {
+		for _, item := range []string{stream.LastEventID(), request.Name} {
+			if err := stream.Send(Event[string]{Data: item}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).WithScopes("stream:read")
+
+	var received []string
+	send := func(event Event[string]) error {
+		received = append(received, event.Data)
+		return nil
+	}
+	a.NoError(CallStream(ctx, h, callRequest{Name: "alice"}, "41", send))
+	a.Equal([]string{"41", "alice"}, received)
+
+	restricted := identity.Inject(ctx, identity.Identity{ClientId: "loadgen", Scopes: []string{}})
+	a.Equal(http.StatusForbidden, statusOf(CallStream(restricted, h, callRequest{}, "", send)))
+	a.Error(CallStream(ctx, Get("/hello", func(context.Context, Empty) (*Empty, error) {
+		return &Empty{}, nil
+	}), Empty{}, "", send), "generic handlers are not streams")
+}
+
+func TestGuard(t *testing.T) {
+	a := assert.New(t)
+	config.Config.ApiKeys = map[string]string{"loadgen": "secret"}
+	config.Config.ClientRateLimit = 1
+	defer func() { config.Config.ApiKeys, config.Config.ClientRateLimit = nil, 0 }()
+	server := NewServer()
+	ctx := context.Background()
+
+	_, _, err := server.Guard(ctx, http.Header{}, "10.0.0.3:1234")
+	a.Equal(http.StatusUnauthorized, statusOf(err))
+	_, _, err = server.Guard(ctx, http.Header{}, "10.0.0.3:1234")
+	a.Equal(http.StatusTooManyRequests, statusOf(err), "failed authentications are rate limited")
+
+	header := http.Header{}
+	header.Set(config.Config.ApiKeyHeader, "secret")
+	guarded, limits, err := server.Guard(ctx, header, "10.0.0.1:1234")
+	if a.NoError(err) {
+		a.Equal("loadgen", identity.ClientId(guarded))
+		a.Equal("1", limits.Get(headerRateLimitLimit))
+	}
+	_, limits, err = server.Guard(ctx, header, "10.0.0.2:1234")
+	a.Equal(http.StatusTooManyRequests, statusOf(err), "clients are rate limited whatever their address")
+	a.NotEmpty(limits.Get(headerRetryAfter))
+}
+
+func TestGuardForwarding(t *testing.T) {
+	a := assert.New(t)
+	config.Config.ClientRateLimit = 1
+	defer func() { config.Config.ClientRateLimit, config.Config.TrustedProxies = 0, nil }()
+	ctx := context.Background()
+	guard := func(server *Server, forwardedFor string) error {
+		header := http.Header{}
+		header.Set("X-Forwarded-For", forwardedFor)
+		_, _, err := server.Guard(ctx, header, "10.0.0.1:1234")
+		return err
+	}
+
+	server := NewServer()
+	a.NoError(guard(server, "203.0.113.1"))
+	a.Equal(http.StatusTooManyRequests, statusOf(guard(server, "203.0.113.2")), "untrusted peers cannot choose their address")
+
+	config.Config.TrustedProxies = []string{"10.0.0.0/24"}
+	server = NewServer()
+	a.NoError(guard(server, "203.0.113.1"))
+	a.NoError(guard(server, "203.0.113.2"), "trusted proxies forward the client address")
+	a.Equal(http.StatusTooManyRequests, statusOf(guard(server, "203.0.113.1")))
+}
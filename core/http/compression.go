@@ -0,0 +1,289 @@
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+	headerVary            = "Vary"
+
+	encodingZstd     = "zstd"
+	encodingGzip     = "gzip"
+	encodingDeflate  = "deflate"
+	encodingIdentity = "identity"
+
+	// zstdMaxDecoderMemory bounds the memory used to decode a single request body
+	zstdMaxDecoderMemory = 64 << 20
+)
+
+type (
+	// compressor is implemented by gzip.Writer, zlib.Writer and zstd.Encoder
+	compressor interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+	// compressWriter buffers the response body until it reaches the minimum size, or is flushed, before committing
+	// the response headers and compressing it.
+	compressWriter struct {
+		http.ResponseWriter
+		encoding    string
+		minSize     int
+		status      int
+		wroteHeader bool
+		committed   bool
+		buf         []byte
+		compressor  compressor
+	}
+)
+
+// supportedEncodings are ordered by server preference, when the client accepts several of them with the same quality
+var supportedEncodings = []string{encodingZstd, encodingGzip, encodingDeflate}
+
+var compressorPools = map[string]*sync.Pool{
+	encodingZstd: {New: func() any {
+		// options are valid, so no error can be returned
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}},
+	encodingGzip: {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	encodingDeflate: {New: func() any {
+		return zlib.NewWriter(nil)
+	}},
+}
+
+// negotiateEncoding selects the preferred supported encoding accepted by the client, following RFC 9110
+// section 12.5.3. It returns an empty string if the response should not be compressed.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = parsed
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(name))] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range supportedEncodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// compressionMiddleware compresses response bodies larger than minSize, using the encoding negotiated through the
+// `Accept-Encoding` request header. Connection upgrades and HEAD requests are left untouched.
+func compressionMiddleware(minSize int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Add(headerVary, headerAcceptEncoding)
+
+			req := c.Request()
+			encoding := negotiateEncoding(req.Header.Get(headerAcceptEncoding))
+			if encoding == "" || req.Method == http.MethodHead || req.Header.Get(echo.HeaderUpgrade) != "" {
+				return next(c)
+			}
+
+			writer := &compressWriter{
+				ResponseWriter: c.Response().Writer,
+				encoding:       encoding,
+				minSize:        minSize,
+				status:         http.StatusOK,
+			}
+			c.Response().Writer = writer
+			defer func() {
+				// errors returned by next are written by the error handler once this middleware returns
+				c.Response().Writer = writer.ResponseWriter
+				if err := writer.Close(); err != nil {
+					logger.FromContext(req.Context()).Warn("response compression failed", zap.Error(err))
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.committed {
+		if w.compressor != nil {
+			return w.compressor.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.commit(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// commit writes the response headers and the buffered body, compressing it if allowed by the response.
+// Strong ETags are weakened, as they identify the uncompressed representation.
+func (w *compressWriter) commit(compress bool) error {
+	w.committed = true
+
+	header := w.Header()
+	if compress && header.Get(headerContentEncoding) == "" &&
+		w.status >= http.StatusOK && w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		header.Set(headerContentEncoding, w.encoding)
+		header.Del(headerContentLength)
+		if etag := header.Get(headerETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set(headerETag, "W/"+etag)
+		}
+		w.compressor = compressorPools[w.encoding].Get().(compressor)
+		w.compressor.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.compressor != nil {
+		_, err = w.compressor.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush compresses streamed responses regardless of their size, and sends every pending byte to the client.
+func (w *compressWriter) Flush() {
+	if !w.committed {
+		if err := w.commit(true); err != nil {
+			return
+		}
+	}
+	if w.compressor != nil {
+		if err := w.compressor.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes responses smaller than the minimum size as is, or terminates the compressed stream.
+// Nothing is written if the handler did not write anything, leaving the response to the error handler.
+func (w *compressWriter) Close() error {
+	if !w.committed {
+		if !w.wroteHeader && len(w.buf) == 0 {
+			return nil
+		}
+		if err := w.commit(false); err != nil {
+			return err
+		}
+	}
+	if w.compressor == nil {
+		return nil
+	}
+	err := w.compressor.Close()
+	compressorPools[w.encoding].Put(w.compressor)
+	w.compressor = nil
+	return err
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decompressionMiddleware decodes request bodies sent with a zstd, gzip or deflate `Content-Encoding`.
+// It must run before the body limit middleware, so the limit applies to the decompressed body.
+func decompressionMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			encoding := strings.ToLower(strings.TrimSpace(req.Header.Get(headerContentEncoding)))
+			if encoding == "" || encoding == encodingIdentity || req.Body == nil || req.Body == http.NoBody {
+				return next(c)
+			}
+
+			var body io.ReadCloser
+			switch encoding {
+			case encodingGzip:
+				reader, err := gzip.NewReader(req.Body)
+				if err != nil {
+					return errors.BadRequest(err, "invalid gzip request body")
+				}
+				body = reader
+			case encodingDeflate:
+				reader, err := zlib.NewReader(req.Body)
+				if err != nil {
+					return errors.BadRequest(err, "invalid deflate request body")
+				}
+				body = reader
+			case encodingZstd:
+				decoder, err := zstd.NewReader(req.Body,
+					zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(zstdMaxDecoderMemory))
+				if err != nil {
+					return errors.BadRequest(err, "invalid zstd request body")
+				}
+				body = decoder.IOReadCloser()
+			default:
+				return errors.UnsupportedMediaType(nil, "unsupported request content encoding '%s'", encoding)
+			}
+			defer body.Close()
+
+			req.Body = body
+			req.ContentLength = -1
+			req.Header.Del(headerContentEncoding)
+			req.Header.Del(headerContentLength)
+			return next(c)
+		}
+	}
+}
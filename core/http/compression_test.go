@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", encodingGzip},
+		{"gzip, deflate, br, zstd", encodingZstd},
+		{"deflate;q=0.5, gzip;q=0.8", encodingGzip},
+		{"*", encodingZstd},
+		{"*;q=0.5, zstd;q=0", encodingGzip},
+		{"GZIP;q=1.0", encodingGzip},
+	}
+	for _, test := range tests {
+		a.Equal(test.expected, negotiateEncoding(test.acceptEncoding), test.acceptEncoding)
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	a := assert.New(t)
+	e := echo.New()
+	large := strings.Repeat("fizzbuzz", 1024)
+
+	serve := func(acceptEncoding string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(headerAcceptEncoding, acceptEncoding)
+		rec := httptest.NewRecorder()
+		a.NoError(compressionMiddleware(1024)(handler)(e.NewContext(req, rec)))
+		return rec
+	}
+
+	rec := serve("gzip", func(c echo.Context) error {
+		return c.String(http.StatusOK, "fizz")
+	})
+	a.Empty(rec.Header().Get(headerContentEncoding), "small responses are not compressed")
+	a.Equal("fizz", rec.Body.String())
+
+	rec = serve("gzip", func(c echo.Context) error {
+		c.Response().Header().Set(headerETag, `"fizz"`)
+		return c.String(http.StatusOK, large)
+	})
+	a.Equal(encodingGzip, rec.Header().Get(headerContentEncoding))
+	a.Equal(`W/"fizz"`, rec.Header().Get(headerETag))
+	a.Less(rec.Body.Len(), len(large))
+	reader, err := gzip.NewReader(rec.Body)
+	if a.NoError(err) {
+		body, err := io.ReadAll(reader)
+		a.NoError(err)
+		a.Equal(large, string(body))
+	}
+
+	rec = serve("zstd", func(c echo.Context) error {
+		c.Response().WriteHeader(http.StatusOK)
+		_, err := c.Response().Write([]byte("fizz"))
+		c.Response().Flush()
+		return err
+	})
+	a.Equal(encodingZstd, rec.Header().Get(headerContentEncoding), "flushed responses are compressed")
+	a.True(rec.Flushed)
+	decoder, err := zstd.NewReader(bytes.NewReader(rec.Body.Bytes()))
+	if a.NoError(err) {
+		body, err := io.ReadAll(decoder)
+		a.NoError(err)
+		a.Equal("fizz", string(body))
+		decoder.Close()
+	}
+}
+
+func TestDecompressionMiddleware(t *testing.T) {
+	a := assert.New(t)
+	e := echo.New()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(`{"fizz": "buzz"}`))
+	a.NoError(err)
+	a.NoError(writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set(headerContentEncoding, encodingGzip)
+	err = decompressionMiddleware()(func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		a.NoError(err)
+		a.Equal(`{"fizz": "buzz"}`, string(body))
+		a.Empty(c.Request().Header.Get(headerContentEncoding))
+		return nil
+	})(e.NewContext(req, httptest.NewRecorder()))
+	a.NoError(err)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("fizz"))
+	req.Header.Set(headerContentEncoding, "br")
+	err = decompressionMiddleware()(func(c echo.Context) error {
+		return nil
+	})(e.NewContext(req, httptest.NewRecorder()))
+	a.Error(err)
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfNoneMatch = "If-None-Match"
+
+	// etagLength is the number of hex characters kept from the sha256 digest
+	etagLength = 32
+)
+
+// isConditional checks whether the request method supports conditional requests through ETags
+func isConditional(c echo.Context) bool {
+	method := c.Request().Method
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+func strongETag(data []byte) string {
+	digest := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(digest[:])[:etagLength] + `"`
+}
+
+// buildVersion identifies the running build, as responses to a given request may change across deployments
+var buildVersion = readBuildVersion()
+
+func readBuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" || setting.Key == "vcs.modified" {
+			version += " " + setting.Value
+		}
+	}
+	return version
+}
+
+// requestETag derives a strong ETag from every input of the response representation: the build, the handler, the
+// serialized request and the response indentation
+func requestETag(c echo.Context, h Handler, request []byte) string {
+	return strongETag([]byte(buildVersion + " " + cacheKey(c, h, request)))
+}
+
+// etagMatches implements the weak comparison required by If-None-Match, as described in RFC 9110 section 13.1.2
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// indented checks whether response bodies are indented, in debug mode or if requested through the `pretty` query
+// parameter
+func indented(c echo.Context) bool {
+	_, pretty := c.QueryParams()["pretty"]
+	return c.Echo().Debug || pretty
+}
+
+// marshalResponse serializes a response body the same way echo.Context JSON does, see indented.
+func marshalResponse(c echo.Context, response any) ([]byte, error) {
+	if indented(c) {
+		return json.MarshalIndent(response, "", "  ")
+	}
+	return json.Marshal(response)
+}
+
+func writeNotModified(c echo.Context, h Handler, etag string) error {
+	header := c.Response().Header()
+	header.Set(headerETag, etag)
+	if h.cacheControl != "" {
+		header.Set(headerCacheControl, h.cacheControl)
+	}
+	return c.NoContent(http.StatusNotModified)
+}
+
+// writeResponse writes a serialized response body, along with its ETag and Cache-Control headers.
+// Successful conditional requests matching the ETag are answered with 304.
+func writeResponse(c echo.Context, h Handler, etag string, status int, body []byte) error {
+	if h.cacheControl != "" {
+		c.Response().Header().Set(headerCacheControl, h.cacheControl)
+	}
+	if status != http.StatusOK || !isConditional(c) {
+		return c.JSONBlob(status, body)
+	}
+
+	if etag == "" {
+		etag = strongETag(body)
+	}
+	if etagMatches(c.Request().Header.Get(headerIfNoneMatch), etag) {
+		return writeNotModified(c, h, etag)
+	}
+	c.Response().Header().Set(headerETag, etag)
+	return c.JSONBlob(status, body)
+}
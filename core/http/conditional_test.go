@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMatches(t *testing.T) {
+	a := assert.New(t)
+	etag := strongETag([]byte("fizzbuzz"))
+
+	a.Len(etag, etagLength+2)
+	a.Equal(etag, strongETag([]byte("fizzbuzz")))
+	a.NotEqual(etag, strongETag([]byte("buzzfizz")))
+
+	tests := []struct {
+		ifNoneMatch string
+		matches     bool
+	}{
+		{"", false},
+		{etag, true},
+		{"*", true},
+		{"W/" + etag, true},
+		{`"other", ` + etag, true},
+		{`"other"`, false},
+	}
+	for _, test := range tests {
+		a.Equal(test.matches, etagMatches(test.ifNoneMatch, etag), test.ifNoneMatch)
+	}
+}
+
+func TestRequestETag(t *testing.T) {
+	a := assert.New(t)
+	h := Get("/etag", func(context.Context, Empty) (*Empty, error) { return &Empty{}, nil })
+	request := []byte(`{"name":"fizz"}`)
+	etag := func(target string) string {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, target, nil), httptest.NewRecorder())
+		return requestETag(c, h, request)
+	}
+
+	a.Equal(etag("/etag"), etag("/etag"))
+	a.NotEqual(etag("/etag"), etag("/etag?pretty"), "indented responses have their own ETag")
+
+	previous := buildVersion
+	defer func() { buildVersion = previous }()
+	compact := etag("/etag")
+	buildVersion = "v2.0.0"
+	a.NotEqual(compact, etag("/etag"), "responses of other builds have their own ETag")
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/pkg/errors"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+// deadlineMiddleware applies the handler deadline, or the configured default, to the request context.
+// It returns nil if no deadline should be applied.
+func deadlineMiddleware(timeout time.Duration) echo.MiddlewareFunc {
+	if timeout == 0 {
+		timeout = config.Config.HandlerTimeout
+	}
+	if timeout <= 0 {
+		return nil
+	}
+	return middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+		Timeout: timeout,
+		ErrorHandler: func(err error, _ echo.Context) error {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return coreErrors.Unavailable(err, "request deadline of %s exceeded", timeout)
+			}
+			return err
+		},
+	})
+}
+
+// deadlineWriter extends the write deadline of the connection before every write, so that responses outlive
+// config.Manifest WriteTimeout as long as the client keeps reading them
+type deadlineWriter struct {
+	http.ResponseWriter
+	controller *http.ResponseController
+	timeout    time.Duration
+}
+
+func (w deadlineWriter) Write(b []byte) (int, error) {
+	_ = w.controller.SetWriteDeadline(time.Now().Add(w.timeout))
+	return w.ResponseWriter.Write(b)
+}
+
+func (w deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ServeContent replies with the given content as net/http ServeContent does, supporting conditional and range
+// requests. config.Manifest WriteTimeout is applied to every write instead of the whole response, so that contents
+// of any size can be downloaded.
+func ServeContent(c echo.Context, name string, modtime time.Time, content io.ReadSeeker) {
+	var w http.ResponseWriter = c.Response()
+	if timeout := config.Config.WriteTimeout; timeout > 0 {
+		w = deadlineWriter{ResponseWriter: w, controller: http.NewResponseController(c.Response().Writer), timeout: timeout}
+	}
+	http.ServeContent(w, c.Request(), name, modtime, content)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	pkgErrors "github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	"github.com/Raphy42/industrial-fizz-buzz/core/config"
@@ -17,6 +18,25 @@ func errorBody(value any) map[string]any {
 	}
 }
 
+// errorMessage returns the message of an error which can be shown to clients, once a response can no longer carry
+// an error status, such as streams and WebSocket sessions
+func errorMessage(err error) string {
+	var httpErr *errors.Error
+	if pkgErrors.As(err, &httpErr) {
+		return httpErr.Message
+	}
+	return "internal server error"
+}
+
+// errorStatus returns the HTTP status code of an error, 500 unless it is an *errors.Error
+func errorStatus(err error) int {
+	var httpErr *errors.Error
+	if pkgErrors.As(err, &httpErr) {
+		return httpErr.HttpCode
+	}
+	return http.StatusInternalServerError
+}
+
 func developmentErrorMiddleware(err error, c echo.Context) {
 	var body any
 	status := http.StatusInternalServerError
@@ -27,6 +47,9 @@ func developmentErrorMiddleware(err error, c echo.Context) {
 	case *errors.Error:
 		body = v
 		status = v.HttpCode
+	case *echo.HTTPError:
+		body = errorBody(v.Message)
+		status = v.Code
 	case error:
 		body = errorBody(err)
 	default:
@@ -35,7 +58,7 @@ func developmentErrorMiddleware(err error, c echo.Context) {
 	log.Error("handler error",
 		zap.String("request.path", c.Path()),
 		zap.String("request.method", c.Request().Method),
-		zap.String("request.uri", c.Request().RequestURI),
+		zap.String("request.uri", logger.RedactURI(c.Request().RequestURI)),
 		zap.Error(err),
 	)
 	if err = c.JSON(status, body); err != nil {
@@ -52,13 +75,17 @@ func prodErrorMiddleware(err error, c echo.Context) {
 	switch v := err.(type) {
 	case *errors.Error:
 		body = errorBody(v.Message)
+		status = v.HttpCode
+	case *echo.HTTPError:
+		body = errorBody(v.Message)
+		status = v.Code
 	default:
 		body = errorBody("internal server error")
 	}
 	log.Error("handler error",
 		zap.String("request.path", c.Path()),
 		zap.String("request.method", c.Request().Method),
-		zap.String("request.uri", c.Request().RequestURI),
+		zap.String("request.uri", logger.RedactURI(c.Request().RequestURI)),
 		zap.Error(err),
 	)
 	if err = c.JSON(status, body); err != nil {
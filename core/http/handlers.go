@@ -7,33 +7,56 @@ import (
 	"path"
 	"reflect"
 	"runtime"
+	"sync/atomic"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
 	"github.com/swaggest/openapi-go/openapi3"
+	"go.uber.org/zap"
 
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
 	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
 )
 
 type (
 	// Handler is a wrapper around echo.HandlerFunc with openapi3 and type safety in mind.
 	Handler struct {
-		operationId string
-		path        string
-		method      string
-		impl        func(c echo.Context) error
-		middlewares []echo.MiddlewareFunc
-		reflect     func(reflector openapi3.Reflector) error
+		operationId  string
+		path         string
+		method       string
+		impl         func(h Handler, c echo.Context) error
+		call         func(h Handler, ctx context.Context, request any) (any, error)
+		callStream   func(ctx context.Context, request any, stream any) error
+		middlewares  []echo.MiddlewareFunc
+		reflect      func(reflector openapi3.Reflector, h Handler, security []map[string][]string) error
+		timeout      time.Duration
+		admin        bool
+		anonymous    bool
+		scopes       []string
+		rateLimit    *RateLimit
+		cacheTTL     time.Duration
+		requestETag  bool
+		cacheControl string
+		uncompressed bool
 	}
 	// GenericHandlerFunc is a type for generic request handlers.
 	// GenericHandler expects a function of this type whenever trying to convert a generic handler func to a valid Handler.
 	GenericHandlerFunc[Request any, Response any] func(ctx context.Context, request Request) (*Response, error)
+	// StatusCoder can be implemented by a GenericHandler response to override the default 200 status code.
+	StatusCoder interface {
+		StatusCode() int
+	}
 	// Empty is a convenience opaque type for struct{}, use it whenever your generic handler takes no request body
 	// or doesn't return a response body.
 	Empty struct{}
 )
 
 // EchoHandler allows you to declare your own handlers, with full access to the echo.Context api.
-// This is currently unused and not connected with openapi3 systems.
+// It is not connected with openapi3 systems, and is mostly used for operational endpoints.
 func EchoHandler(route, method string, impl echo.HandlerFunc, middlewares ...echo.MiddlewareFunc) Handler {
 	fullNameOf := runtime.FuncForPC(reflect.ValueOf(impl).Pointer()).Name()
 	nameOf := path.Base(fullNameOf)
@@ -45,12 +68,57 @@ func EchoHandler(route, method string, impl echo.HandlerFunc, middlewares ...ech
 		operationId: nameOf,
 		path:        route,
 		method:      method,
-		impl:        impl,
+		impl: func(_ Handler, c echo.Context) error {
+			return impl(c)
+		},
 		middlewares: middlewares,
 	}
 	return h
 }
 
+// droppedRequests is the number of requests which were not counted in the metrics of their route, as the aggregator
+// could not keep up with them
+var droppedRequests atomic.Uint64
+
+func init() {
+	metrics.RegisterCounter(semconv.MetricName("http", "request", "metrics", "dropped", "total"),
+		"Total number of requests dropped from route metrics because the aggregator could not keep up.",
+		func() float64 { return float64(droppedRequests.Load()) })
+}
+
+// dispatchRequest counts a serialized request in the metrics of its route.
+// It never blocks the handler, requests being dropped whenever the buffer of the route is full.
+func dispatchRequest(ctx context.Context, route string, requestChan chan<- metrics.Request, payload []byte) {
+	select {
+	case requestChan <- metrics.Request{Client: identity.ClientId(ctx), Payload: payload}:
+	default:
+		droppedRequests.Add(1)
+		logger.FromContext(ctx).Debug("request metrics dropped", zap.String("route", route))
+	}
+}
+
+// RequestCounter counts requests in the metrics of a route, as if they had been served by the GenericHandler of this
+// route. This allows composite handlers, such as batches, to count each of their items individually.
+type RequestCounter struct {
+	route       string
+	requestChan chan<- metrics.Request
+}
+
+// NewRequestCounter resolves the metrics of a route once, it is meant to be called at initialization.
+func NewRequestCounter(route string) RequestCounter {
+	return RequestCounter{route: route, requestChan: metrics.NewRequestCounter(route)}
+}
+
+// Count counts a request in the metrics of the route.
+func (c RequestCounter) Count(ctx context.Context, request any) error {
+	buf, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	dispatchRequest(ctx, c.route, c.requestChan, buf)
+	return nil
+}
+
 // GenericHandler converts a generic handler into a Handler.
 // This allows the user to focus on writing business code, without having to write boilerplate bind code.
 // Errors are generalised and the handler will only be invoked with valid parameters.
@@ -63,7 +131,7 @@ func GenericHandler[Request any, Response any](route, method string, impl Generi
 		operationId: nameOf,
 		path:        route,
 		method:      method,
-		impl: func(c echo.Context) error {
+		impl: func(h Handler, c echo.Context) error {
 			var request Request
 			if err := c.Bind(&request); err != nil {
 				return err
@@ -72,36 +140,182 @@ func GenericHandler[Request any, Response any](route, method string, impl Generi
 			// we serialize the complete request object to JSON
 			// this is different from a JSON request body, as echo allows query, path, and json parameters
 			// through tag reflection
+			buf, err := json.Marshal(request)
+			if err != nil {
+				return err
+			}
+
 			ctx := c.Request().Context()
-			go func() {
-				buf, err := json.Marshal(request)
-				if err != nil {
-					panic(err)
+			if !h.admin {
+				dispatchRequest(ctx, route, requestChan, buf)
+			}
+
+			if cacheable, ok := any(request).(Cacheable); ok && !cacheable.Cacheable() {
+				h.requestETag, h.cacheTTL, h.cacheControl = false, 0, ""
+			}
+
+			// the serialized request also identifies the response, as handlers are expected to be pure
+			var etag string
+			if h.requestETag && isConditional(c) {
+				etag = requestETag(c, h, buf)
+				if etagMatches(c.Request().Header.Get(headerIfNoneMatch), etag) {
+					return writeNotModified(c, h, etag)
 				}
-				select {
-				case <-ctx.Done():
-					return
-				case requestChan <- buf:
-					return
+			}
+
+			var key string
+			var policy cachePolicy
+			if h.cacheTTL > 0 && globalResponseCache != nil {
+				key = cacheKey(c, h, buf)
+				policy = requestCachePolicy(c)
+				if policy.lookup {
+					if cached, ok := globalResponseCache.Get(key); ok {
+						if charger, ok := any(request).(Charger); ok {
+							if err := charger.Charge(ctx); err != nil {
+								return err
+							}
+						}
+						c.Response().Header().Set(headerXCache, cacheHit)
+						return writeResponse(c, h, etag, cached.status, cached.body)
+					}
+					c.Response().Header().Set(headerXCache, cacheMiss)
+				} else {
+					c.Response().Header().Set(headerXCache, cacheBypass)
 				}
-			}()
+			}
+
+			release, err := admit(ctx, request)
+			if err != nil {
+				// requests which can never be admitted are not worth retrying
+				if errorStatus(err) == http.StatusServiceUnavailable {
+					c.Response().Header().Set(headerRetryAfter, "1")
+				}
+				return err
+			}
+			defer release()
 
 			response, err := impl(ctx, request)
 			if err != nil {
 				return err
 			}
 
-			return c.JSON(http.StatusOK, response)
+			status := http.StatusOK
+			if coder, ok := any(response).(StatusCoder); ok {
+				status = coder.StatusCode()
+			}
+			body, err := marshalResponse(c, response)
+			if err != nil {
+				return err
+			}
+			if key != "" && policy.store {
+				globalResponseCache.Set(key, cachedResponse{status: status, body: body}, len(body), h.cacheTTL)
+			}
+			return writeResponse(c, h, etag, status, body)
 		},
 		middlewares: middlewares,
 	}
-	fn := func(reflector openapi3.Reflector) error {
-		return registerOperation[Request, Response](reflector, h)
+	h.call = func(h Handler, ctx context.Context, request any) (any, error) {
+		typed, ok := request.(Request)
+		if !ok {
+			return nil, errors.Errorf("handler '%s' expects a %T request, got %T", nameOf, typed, request)
+		}
+		buf, err := json.Marshal(typed)
+		if err != nil {
+			return nil, err
+		}
+		if !h.admin {
+			dispatchRequest(ctx, route, requestChan, buf)
+		}
+
+		release, err := admit(ctx, typed)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return impl(ctx, typed)
+	}
+	fn := func(reflector openapi3.Reflector, h Handler, security []map[string][]string) error {
+		return registerOperation[Request, Response](reflector, h, security)
 	}
 	h.reflect = fn
 	return h
 }
 
+// WithTimeout returns a copy of the Handler using the given deadline instead of config.Manifest HandlerTimeout.
+// The deadline is applied to the context.Context given to the handler implementation, a negative value disables it.
+func (h Handler) WithTimeout(timeout time.Duration) Handler {
+	h.timeout = timeout
+	return h
+}
+
+// Admin returns a copy of the Handler flagged as an operational endpoint.
+// Admin handlers are served by the admin listener whenever config.Manifest AdminAddr is set, and their requests are
+// not counted in request metrics, so that probes and scrapes do not drown out the API traffic.
+func (h Handler) Admin() Handler {
+	h.admin = true
+	return h
+}
+
+// mutating checks whether the Handler method is expected to change the server state
+func (h Handler) mutating() bool {
+	return h.method != http.MethodGet && h.method != http.MethodHead
+}
+
+// Anonymous returns a copy of the Handler which does not require authentication, such as health probes.
+// Handlers require authentication as soon as at least one Authenticator is enabled through configuration.
+func (h Handler) Anonymous() Handler {
+	h.anonymous = true
+	return h
+}
+
+// WithScopes returns a copy of the Handler requiring every given scope to be granted to the client.
+// Scopes are only restricted by authentication methods which support them, such as JWT bearer tokens.
+func (h Handler) WithScopes(scopes ...string) Handler {
+	h.scopes = append(append([]string{}, h.scopes...), scopes...)
+	return h
+}
+
+// WithRateLimit returns a copy of the Handler whose route is rate limited, independently of the instance and
+// client rate limits configured through config.Manifest.
+func (h Handler) WithRateLimit(limit RateLimit) Handler {
+	h.rateLimit = &limit
+	return h
+}
+
+// WithCache returns a copy of the Handler whose responses are cached for the given time to live, or
+// config.Manifest CacheTTL if it is not positive. Cached responses are keyed by the serialized request, so this
+// should only be used by handlers which are pure functions of their request.
+// Clients can bypass the cache using the `Cache-Control: no-cache` or `Cache-Control: no-store` request headers.
+func (h Handler) WithCache(ttl time.Duration) Handler {
+	if ttl <= 0 {
+		ttl = config.Config.CacheTTL
+	}
+	h.cacheTTL = ttl
+	return h
+}
+
+// WithRequestETag returns a copy of the Handler whose ETags are derived from the serialized request instead of the
+// response body, allowing `If-None-Match` requests to be answered without invoking the handler.
+// This should only be used by handlers which are pure functions of their request.
+func (h Handler) WithRequestETag() Handler {
+	h.requestETag = true
+	return h
+}
+
+// WithCacheControl returns a copy of the Handler sending the given `Cache-Control` response header, such as
+// "public, max-age=3600".
+func (h Handler) WithCacheControl(value string) Handler {
+	h.cacheControl = value
+	return h
+}
+
+// WithoutCompression returns a copy of the Handler whose responses are never compressed, such as endpoints serving
+// already compressed payloads.
+func (h Handler) WithoutCompression() Handler {
+	h.uncompressed = true
+	return h
+}
+
 // Get is a convenience wrapper around GenericHandler
 func Get[Request any, Response any](path string, impl GenericHandlerFunc[Request, Response], middlewares ...echo.MiddlewareFunc) Handler {
 	return GenericHandler[Request, Response](path, http.MethodGet, impl, middlewares...)
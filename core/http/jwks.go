@@ -0,0 +1,145 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+// errUnsupportedKey is returned when parsing a JWK whose type, curve or algorithm cannot be used to verify tokens
+var errUnsupportedKey = errors.New("unsupported JWK")
+
+// minRSAKeyBits is the minimum size of RSA moduli, smaller keys can be factored
+const minRSAKeyBits = 2048
+
+// supportedAlgs maps key types to the only signing algorithm they are used with, see newJwtAuthenticator
+var supportedAlgs = map[string]string{
+	"RSA": "RS256",
+	"EC":  "ES256",
+	"oct": "HS256",
+}
+
+type (
+	// jsonWebKey is a subset of RFC 7517, supporting RSA (2048 bits or more), EC (P-256) and symmetric keys, as they
+	// are verified with RS256, ES256 and HS256
+	jsonWebKey struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		// RSA
+		N string `json:"n"`
+		E string `json:"e"`
+		// EC
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		// symmetric
+		K string `json:"k"`
+	}
+	jsonWebKeySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	// keySet maps key identifiers to their parsed public (or symmetric) key
+	keySet map[string]any
+)
+
+func decodeBase64Url(value, field string) ([]byte, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid base64url encoding of '%s'", field)
+	}
+	return buf, nil
+}
+
+func (k jsonWebKey) parse() (any, error) {
+	alg, ok := supportedAlgs[k.Kty]
+	if !ok {
+		return nil, errors.Wrapf(errUnsupportedKey, "key type '%s'", k.Kty)
+	}
+	if k.Alg != "" && k.Alg != alg {
+		return nil, errors.Wrapf(errUnsupportedKey, "algorithm '%s'", k.Alg)
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64Url(k.N, "n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64Url(k.E, "e")
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.Errorf("invalid RSA exponent")
+		}
+		modulus := new(big.Int).SetBytes(n)
+		if bits := modulus.BitLen(); bits < minRSAKeyBits {
+			return nil, errors.Wrapf(errUnsupportedKey, "%d bits RSA modulus, at least %d bits are required", bits, minRSAKeyBits)
+		}
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+	case "EC":
+		// ES256 verification only accepts P-256 keys
+		if k.Crv != "P-256" {
+			return nil, errors.Wrapf(errUnsupportedKey, "curve '%s'", k.Crv)
+		}
+		curve := elliptic.P256()
+		x, err := decodeBase64Url(k.X, "x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64Url(k.Y, "y")
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.Errorf("EC point is not on curve '%s'", k.Crv)
+		}
+		return key, nil
+	case "oct":
+		return decodeBase64Url(k.K, "k")
+	default:
+		return nil, errors.Wrapf(errUnsupportedKey, "key type '%s'", k.Kty)
+	}
+}
+
+// parseKeySet parses a JSON Web Key Set, ignoring keys which are not meant for signature verification.
+// Unsupported keys are logged and ignored, malformed keys and duplicate key identifiers fail the whole set.
+func parseKeySet(buf []byte) (keySet, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(buf, &set); err != nil {
+		return nil, errors.Wrapf(err, "invalid JWKS")
+	}
+
+	keys := make(keySet, len(set.Keys))
+	kids := make(map[string]struct{}, len(set.Keys))
+	for idx, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if _, ok := kids[jwk.Kid]; ok {
+			return nil, errors.Errorf("duplicate key identifier '%s' of JWK #%d", jwk.Kid, idx)
+		}
+		kids[jwk.Kid] = struct{}{}
+		key, err := jwk.parse()
+		if errors.Is(err, errUnsupportedKey) {
+			logger.New().Warn("JWK ignored", zap.Int("index", idx), zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid JWK #%d '%s'", idx, jwk.Kid)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
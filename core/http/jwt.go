@@ -0,0 +1,191 @@
+package http
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/swaggest/openapi-go/openapi3"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	jwtMethod     = "jwt"
+	jwtSchemeName = "bearerAuth"
+	bearerPrefix  = "Bearer "
+)
+
+// jwtAuthenticator authenticates clients using signed JWT bearer tokens, verified against a local JWKS file.
+// The JWKS file is reloaded whenever its modification time changes, checked at most once per reload interval.
+type jwtAuthenticator struct {
+	lock       sync.RWMutex
+	path       string
+	keys       keySet
+	modTime    time.Time
+	checkedAt  time.Time
+	interval   time.Duration
+	parserOpts []jwt.ParserOption
+}
+
+// newJwtAuthenticator loads the JWKS file from config.Manifest, it returns nil if no JWKS file is configured.
+func newJwtAuthenticator() (*jwtAuthenticator, error) {
+	if config.Config.JwksFile == "" {
+		return nil, nil
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodES256.Alg(),
+		}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.Config.JwtLeeway),
+	}
+	if config.Config.JwtAudience != "" {
+		opts = append(opts, jwt.WithAudience(config.Config.JwtAudience))
+	}
+	if config.Config.JwtIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Config.JwtIssuer))
+	}
+
+	a := &jwtAuthenticator{
+		path:       config.Config.JwksFile,
+		interval:   config.Config.JwksReloadInterval,
+		parserOpts: opts,
+	}
+	if err := a.reload(true); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// reload parses the JWKS file again if it changed, or unconditionally if forced.
+func (a *jwtAuthenticator) reload(force bool) error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return errors.Wrapf(err, "could not stat JWKS file '%s'", a.path)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.checkedAt = time.Now()
+	if !force && info.ModTime().Equal(a.modTime) {
+		return nil
+	}
+
+	buf, err := os.ReadFile(a.path)
+	if err != nil {
+		return errors.Wrapf(err, "could not read JWKS file '%s'", a.path)
+	}
+	keys, err := parseKeySet(buf)
+	if err != nil {
+		return errors.Wrapf(err, "invalid JWKS file '%s'", a.path)
+	}
+	a.keys = keys
+	a.modTime = info.ModTime()
+	return nil
+}
+
+// check is the readiness check of the JWKS file, it reloads the file if it changed and fails as long as it cannot be
+// loaded, the previous keys being kept meanwhile
+func (a *jwtAuthenticator) check(_ context.Context) error {
+	return a.reload(false)
+}
+
+// keySet returns the current keys, reloading them if the reload interval elapsed.
+// Reload failures are logged, and the previous keys are kept.
+func (a *jwtAuthenticator) keySet() keySet {
+	a.lock.RLock()
+	stale := a.interval > 0 && time.Since(a.checkedAt) > a.interval
+	a.lock.RUnlock()
+
+	if stale {
+		if err := a.reload(false); err != nil {
+			logger.New().Error("JWKS reload failed, keeping previous keys", zap.Error(err))
+		}
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.keys
+}
+
+func (a *jwtAuthenticator) keyFunc(token *jwt.Token) (any, error) {
+	keys := a.keySet()
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	key, ok := keys[kid]
+	if !ok {
+		return nil, errors.Errorf("unknown key identifier '%s'", kid)
+	}
+	return key, nil
+}
+
+func (a *jwtAuthenticator) Authenticate(c echo.Context) (identity.Identity, bool, error) {
+	header := c.Request().Header.Get(echo.HeaderAuthorization)
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return identity.Identity{}, false, nil
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(strings.TrimPrefix(header, bearerPrefix), claims, a.keyFunc, a.parserOpts...); err != nil {
+		return identity.Identity{}, false, coreErrors.Unauthorized(err, "invalid bearer token")
+	}
+
+	clientId, _ := claims.GetSubject()
+	if clientId == "" {
+		clientId, _ = claims["client_id"].(string)
+	}
+	if clientId == "" {
+		return identity.Identity{}, false, coreErrors.Unauthorized(nil, "bearer token has no subject")
+	}
+	return identity.Identity{
+		ClientId: clientId,
+		Method:   jwtMethod,
+		Scopes:   scopesOf(claims),
+		Claims:   claims,
+	}, true, nil
+}
+
+func (a *jwtAuthenticator) SecuritySchemes() map[string]openapi3.SecurityScheme {
+	format := "JWT"
+	return map[string]openapi3.SecurityScheme{
+		jwtSchemeName: {HTTPSecurityScheme: &openapi3.HTTPSecurityScheme{
+			Scheme:       "bearer",
+			BearerFormat: &format,
+		}},
+	}
+}
+
+// scopesOf extracts granted scopes from either the space delimited "scope" claim, or the "scp" array claim.
+// It never returns nil, as a nil scope list means unrestricted access.
+func scopesOf(claims jwt.MapClaims) []string {
+	scopes := make([]string, 0)
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = append(scopes, strings.Fields(scope)...)
+	}
+	if scp, ok := claims["scp"].([]any); ok {
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+	}
+	return scopes
+}
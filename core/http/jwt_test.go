@@ -0,0 +1,191 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
+)
+
+func writeJwks(t *testing.T, path string, keys ...jsonWebKey) {
+	buf, err := json.Marshal(jsonWebKeySet{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, buf, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJwtAuthentication(t *testing.T) {
+	a := assert.New(t)
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !a.NoError(err) {
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJwks(t, path,
+		jsonWebKey{Kid: "hs", Kty: "oct", K: b64(secret)},
+		jsonWebKey{Kid: "es", Kty: "EC", Crv: "P-256", X: b64(ecKey.X.Bytes()), Y: b64(ecKey.Y.Bytes())},
+	)
+	config.Config.JwksFile = path
+	config.Config.JwtAudience = "fizzbuzz"
+	defer func() {
+		config.Config.JwksFile = ""
+		config.Config.JwtAudience = ""
+	}()
+
+	authenticator, err := newJwtAuthenticator()
+	if !a.NoError(err) {
+		return
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	valid := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"sub": "dashboard", "aud": "fizzbuzz", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		return claims
+	}
+	authenticate := func(token string) (string, []string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		id, _, err := authenticator.Authenticate(echo.New().NewContext(req, httptest.NewRecorder()))
+		return id.ClientId, id.Scopes, err
+	}
+	assertUnauthorized := func(err error, name string) {
+		httpErr, ok := err.(*errors.Error)
+		if a.Truef(ok, "%s: error is not a valid *errors.Error", name) {
+			a.Equal(http.StatusUnauthorized, httpErr.HttpCode, name)
+		}
+	}
+
+	clientId, scopes, err := authenticate(sign(jwt.SigningMethodHS256, "hs", secret, valid(jwt.MapClaims{"scope": "fizzbuzz:read metrics:read"})))
+	a.NoError(err, "HS256 token should be valid")
+	a.Equal("dashboard", clientId)
+	a.Equal([]string{"fizzbuzz:read", "metrics:read"}, scopes)
+
+	_, _, err = authenticate(sign(jwt.SigningMethodES256, "es", ecKey, valid(nil)))
+	a.NoError(err, "ES256 token should be valid")
+
+	_, _, err = authenticate(sign(jwt.SigningMethodHS256, "hs", secret, valid(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})))
+	assertUnauthorized(err, "expired token")
+	_, _, err = authenticate(sign(jwt.SigningMethodHS256, "hs", secret, valid(jwt.MapClaims{"aud": "other"})))
+	assertUnauthorized(err, "invalid audience")
+	_, _, err = authenticate(sign(jwt.SigningMethodHS256, "hs", []byte("not the secret, not the secret!!"), valid(nil)))
+	assertUnauthorized(err, "invalid signature")
+	_, _, err = authenticate(sign(jwt.SigningMethodHS256, "unknown", secret, valid(nil)))
+	assertUnauthorized(err, "unknown key")
+
+	// rotating keys is picked up on reload
+	writeJwks(t, path, jsonWebKey{Kid: "es", Kty: "EC", Crv: "P-256", X: b64(ecKey.X.Bytes()), Y: b64(ecKey.Y.Bytes())})
+	a.NoError(os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	a.NoError(authenticator.reload(false))
+	_, _, err = authenticate(sign(jwt.SigningMethodHS256, "hs", secret, valid(nil)))
+	assertUnauthorized(err, "removed key")
+}
+
+func TestJwksHealthCheck(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	jwksCheck := func() (health.CheckResult, bool) {
+		for _, result := range health.Run(ctx, health.Readiness).Checks {
+			if result.Name == "jwks" {
+				return result, true
+			}
+		}
+		return health.CheckResult{}, false
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJwks(t, path, jsonWebKey{Kid: "hs", Kty: "oct", K: base64.RawURLEncoding.EncodeToString([]byte("secret"))})
+	config.Config.JwksFile = path
+	defer func() { config.Config.JwksFile = "" }()
+
+	_, err := defaultAuthenticators()
+	a.NoError(err)
+	if result, ok := jwksCheck(); a.True(ok, "the JWKS file is part of the readiness probe") {
+		a.Equal(health.StatusOk, result.Status)
+	}
+
+	a.NoError(os.WriteFile(path, []byte("{"), 0o600))
+	a.NoError(os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	if result, ok := jwksCheck(); a.True(ok) {
+		a.Equal(health.StatusFailing, result.Status, "invalid JWKS files fail readiness")
+	}
+
+	config.Config.JwksFile = ""
+	_, err = defaultAuthenticators()
+	a.NoError(err)
+	_, ok := jwksCheck()
+	a.False(ok)
+}
+
+func TestParseKeySet(t *testing.T) {
+	a := assert.New(t)
+	secret := base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	exponent := base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1})
+	modulus := func(bits int) string {
+		return base64.RawURLEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, bits/8))
+	}
+	parse := func(keys ...jsonWebKey) (keySet, error) {
+		buf, err := json.Marshal(jsonWebKeySet{Keys: keys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parseKeySet(buf)
+	}
+
+	keys, err := parse(
+		jsonWebKey{Kid: "hs", Kty: "oct", Alg: "HS256", K: secret},
+		jsonWebKey{Kid: "okp", Kty: "OKP", Crv: "Ed25519", X: secret},
+		jsonWebKey{Kid: "hs512", Kty: "oct", Alg: "HS512", K: secret},
+		jsonWebKey{Kid: "enc", Kty: "RSA", Use: "enc"},
+		jsonWebKey{Kid: "p384", Kty: "EC", Crv: "P-384", X: secret, Y: secret},
+		jsonWebKey{Kid: "rsa1024", Kty: "RSA", N: modulus(1024), E: exponent},
+		jsonWebKey{Kid: "rsa2048", Kty: "RSA", N: modulus(2048), E: exponent},
+	)
+	if a.NoError(err, "unsupported keys are ignored") {
+		a.Len(keys, 2)
+		a.Contains(keys, "hs")
+		a.Contains(keys, "rsa2048")
+	}
+
+	_, err = parse(jsonWebKey{Kid: "hs", Kty: "oct", K: "not base64!"})
+	a.Error(err, "malformed keys fail the whole set")
+	_, err = parse(
+		jsonWebKey{Kid: "hs", Kty: "oct", K: secret},
+		jsonWebKey{Kid: "hs", Kty: "oct", Alg: "HS512", K: secret},
+	)
+	a.ErrorContains(err, "duplicate key identifier 'hs'")
+}
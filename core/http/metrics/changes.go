@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
+)
+
+type (
+	// TopChange describes how the top request of a route changed since the previous TopChange of this route
+	TopChange struct {
+		// Top is the current top request of the route, without any hit if its metrics have been reset
+		Top TopRequest
+		// Changed reports whether the top request has been replaced, or reset
+		Changed bool
+		// Threshold is the highest threshold crossed by the hit count of the top request, 0 if none
+		Threshold uint
+	}
+	// SubscribeOptions configures a subscription to the changes of top requests
+	SubscribeOptions struct {
+		// Routes are the routes whose changes are sent, every route if empty
+		Routes []string
+		// Thresholds are the hit counts which trigger a TopChange once crossed by a top request
+		Thresholds []uint
+		// Interval is the minimum delay between two changes of a route, changes being coalesced in between
+		Interval time.Duration
+	}
+	// subscription coalesces the changes of the top requests of a subscriber, until they are sent
+	subscription struct {
+		routes     map[string]bool
+		thresholds []uint
+		lock       sync.Mutex
+		// hits is the last known hit count of the top request of every route, to detect threshold crossings
+		hits    map[string]uint
+		pending map[string]TopChange
+		notify  chan struct{}
+	}
+)
+
+// Subscribe sends changes of the top request of routes until the given context.Context is done, the returned
+// channel being closed then. A TopChange is sent whenever the top request of a route is replaced, or its hit count
+// crosses one of the thresholds. Changes are coalesced by route, so that bursts of requests send at most one
+// TopChange per route and interval, describing every change since the previous one.
+func Subscribe(ctx context.Context, options SubscribeOptions) (<-chan TopChange, error) {
+	return globalRegistry.subscribe(ctx, options)
+}
+
+func (r *registry) subscribe(ctx context.Context, options SubscribeOptions) (<-chan TopChange, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s := &subscription{
+		thresholds: append([]uint{}, options.Thresholds...),
+		hits:       make(map[string]uint),
+		pending:    make(map[string]TopChange),
+		notify:     make(chan struct{}, 1),
+	}
+	sort.Slice(s.thresholds, func(i, j int) bool { return s.thresholds[i] < s.thresholds[j] })
+	if len(options.Routes) > 0 {
+		s.routes = make(map[string]bool, len(options.Routes))
+		for _, route := range options.Routes {
+			if !generics.MapHas(r.requestChans, route) {
+				return nil, errors.NotFound()
+			}
+			s.routes[route] = true
+		}
+	}
+	for route, top := range r.tops {
+		s.hits[route] = top.Hits
+	}
+	r.subscribers[s] = struct{}{}
+
+	changes := make(chan TopChange)
+	go func() {
+		defer close(changes)
+		defer r.unsubscribe(s)
+		s.run(ctx, options.Interval, changes)
+	}()
+	return changes, nil
+}
+
+func (r *registry) unsubscribe(s *subscription) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.subscribers, s)
+}
+
+// publish notifies every subscriber of the current top request of a route, it must be called with the lock held
+func (r *registry) publish(top TopRequest, changed bool) {
+	for s := range r.subscribers {
+		s.publish(top, changed)
+	}
+}
+
+func (s *subscription) publish(top TopRequest, changed bool) {
+	if s.routes != nil && !s.routes[top.Route] {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	previous := s.hits[top.Route]
+	s.hits[top.Route] = top.Hits
+	var threshold uint
+	for _, t := range s.thresholds {
+		if previous < t && t <= top.Hits {
+			threshold = t
+		}
+	}
+
+	change, ok := s.pending[top.Route]
+	if !ok && !changed && threshold == 0 {
+		return
+	}
+	change.Top = top
+	change.Changed = change.Changed || changed
+	if threshold > change.Threshold {
+		change.Threshold = threshold
+	}
+	s.pending[top.Route] = change
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run sends pending changes sorted by route, waiting for interval between two batches
+func (s *subscription) run(ctx context.Context, interval time.Duration, changes chan<- TopChange) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.notify:
+		}
+
+		s.lock.Lock()
+		pending := s.pending
+		s.pending = make(map[string]TopChange)
+		s.lock.Unlock()
+
+		routes := generics.MapKeys(pending)
+		sort.Strings(routes)
+		for _, route := range routes {
+			select {
+			case <-ctx.Done():
+				return
+			case changes <- pending[route]:
+			}
+		}
+
+		if interval > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}
+}
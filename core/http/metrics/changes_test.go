@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receive(t *testing.T, changes <-chan TopChange) TopChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("no change received")
+		return TopChange{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	a := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := newRegistry()
+	r.newRequestCounter("/fizz")
+	r.newRequestCounter("/buzz")
+	_, err := r.subscribe(ctx, SubscribeOptions{Routes: []string{"/unknown"}})
+	a.Error(err)
+
+	changes, err := r.subscribe(ctx, SubscribeOptions{
+		Routes:     []string{"/fizz"},
+		Thresholds: []uint{3, 2},
+		Interval:   50 * time.Millisecond,
+	})
+	if !a.NoError(err) {
+		return
+	}
+
+	r.incr("/fizz", "alice", "a")
+	change := receive(t, changes)
+	a.True(change.Changed)
+	a.Equal(TopRequest{Route: "/fizz", Bytes: []byte("a"), Hits: 1}, change.Top)
+
+	// the burst is coalesced into a single change, while other routes are filtered out
+	r.incr("/buzz", "alice", "a")
+	r.incr("/fizz", "alice", "b")
+	r.incr("/fizz", "alice", "b")
+	r.incr("/fizz", "alice", "b")
+	r.incr("/fizz", "alice", "a")
+	change = receive(t, changes)
+	a.True(change.Changed, "the top request has been replaced")
+	a.Equal(uint(3), change.Threshold)
+	a.Equal(TopRequest{Route: "/fizz", Bytes: []byte("b"), Hits: 3}, change.Top)
+
+	// hits which do not cross any threshold are not sent
+	r.incr("/fizz", "alice", "b")
+	a.NoError(r.reset("/fizz"))
+	change = receive(t, changes)
+	a.True(change.Changed)
+	a.Equal(TopRequest{Route: "/fizz"}, change.Top)
+
+	top, err := r.top("/fizz")
+	if a.NoError(err) {
+		a.Equal(uint(0), top["/fizz"].Hits)
+	}
+
+	cancel()
+	a.Eventually(func() bool {
+		_, ok := <-changes
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	a.Len(r.subscribers, 0, "subscription has not been removed")
+}
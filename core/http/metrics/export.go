@@ -7,8 +7,20 @@ func StartAggregator(ctx context.Context) {
 	globalRegistry.start(ctx)
 }
 
+// StopAggregator stops the aggregator started by StartAggregator, flushing pending events into the globalRegistry.
+// It blocks until every internal goroutine has exited, or the given context.Context is done.
+func StopAggregator(ctx context.Context) error {
+	return globalRegistry.stop(ctx)
+}
+
+// IsRunning checks whether the aggregator has been started and not yet stopped.
+func IsRunning() bool {
+	return globalRegistry.running()
+}
+
 // NewRequestCounter allocates a new request counter, this is used internally by the handler wrapper to extract metrics.
-func NewRequestCounter(path string) chan<- []byte {
+// Request counters are allocated once by path, allocating a counter for an already registered path returns it.
+func NewRequestCounter(path string) chan<- Request {
 	return globalRegistry.newRequestCounter(path)
 }
 
@@ -18,6 +30,37 @@ func Top(routes ...string) (map[string]TopRequest, error) {
 	return globalRegistry.top(routes...)
 }
 
+// Stats returns aggregated request statistics for every registered route.
+func Stats() map[string]RouteStats {
+	return globalRegistry.stats()
+}
+
+// Reset clears request metrics of the given routes, or of every route if the input is empty.
+func Reset(routes ...string) error {
+	return globalRegistry.reset(routes...)
+}
+
+// Request is a single request dispatched to a request counter
+type Request struct {
+	// Client is the identifier of the client which issued the request
+	Client string
+	// Payload is the serialized request
+	Payload []byte
+}
+
+// RouteStats represents aggregated request statistics for a given route
+type RouteStats struct {
+	Route string
+	// Requests is the total number of requests
+	Requests uint
+	// Distinct is the number of distinct requests
+	Distinct int
+	// TopHits is the hit count of the top request
+	TopHits uint
+	// Clients is the number of requests by client identifier
+	Clients map[string]uint
+}
+
 // TopRequest represents the current top request for a given route
 type TopRequest struct {
 	Route string
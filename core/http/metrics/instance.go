@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// instanceMetric is an instance wide metric, sampled on every scrape
+type instanceMetric struct {
+	name  string
+	help  string
+	kind  string
+	value func() float64
+}
+
+var (
+	instanceLock    sync.RWMutex
+	instanceMetrics = make(map[string]instanceMetric)
+)
+
+func registerInstanceMetric(name, help, kind string, value func() float64) {
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	instanceMetrics[name] = instanceMetric{name: name, help: help, kind: kind, value: value}
+}
+
+// RegisterGauge registers an instance wide gauge exposed by WritePrometheus, its value being sampled on every scrape.
+// Registering a metric with an already existing name replaces it.
+func RegisterGauge(name, help string, value func() float64) {
+	registerInstanceMetric(name, help, "gauge", value)
+}
+
+// RegisterCounter registers an instance wide counter exposed by WritePrometheus, its value being sampled on every
+// scrape. Registering a metric with an already existing name replaces it.
+func RegisterCounter(name, help string, value func() float64) {
+	registerInstanceMetric(name, help, "counter", value)
+}
+
+// sortedInstanceMetrics returns every registered instance metric, sorted by name
+func sortedInstanceMetrics() []instanceMetric {
+	instanceLock.RLock()
+	defer instanceLock.RUnlock()
+
+	metrics := make([]instanceMetric, 0, len(instanceMetrics))
+	for _, metric := range instanceMetrics {
+		metrics = append(metrics, metric)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name < metrics[j].name
+	})
+	return metrics
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+// PrometheusContentType is the content type of the prometheus text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type prometheusMetric struct {
+	name string
+	help string
+	kind string
+	// samples returns every sample of a given route, as formatted labels associated with their value
+	samples func(route string, stats RouteStats) [][2]string
+}
+
+var prometheusMetrics = []prometheusMetric{
+	{
+		name: semconv.MetricName("route", "requests", "total"),
+		help: "Total number of requests served by route.",
+		kind: "counter",
+		samples: func(route string, stats RouteStats) [][2]string {
+			return [][2]string{{fmt.Sprintf("route=%q", route), fmt.Sprint(stats.Requests)}}
+		},
+	},
+	{
+		name: semconv.MetricName("route", "distinct", "requests"),
+		help: "Number of distinct requests served by route.",
+		kind: "gauge",
+		samples: func(route string, stats RouteStats) [][2]string {
+			return [][2]string{{fmt.Sprintf("route=%q", route), fmt.Sprint(stats.Distinct)}}
+		},
+	},
+	{
+		name: semconv.MetricName("route", "top", "request", "hits"),
+		help: "Hit count of the most common request by route.",
+		kind: "gauge",
+		samples: func(route string, stats RouteStats) [][2]string {
+			return [][2]string{{fmt.Sprintf("route=%q", route), fmt.Sprint(stats.TopHits)}}
+		},
+	},
+	{
+		name: semconv.MetricName("client", "requests", "total"),
+		help: "Total number of requests served by route and client.",
+		kind: "counter",
+		samples: func(route string, stats RouteStats) [][2]string {
+			clients := generics.MapKeys(stats.Clients)
+			sort.Strings(clients)
+			samples := make([][2]string, len(clients))
+			for idx, client := range clients {
+				samples[idx] = [2]string{
+					fmt.Sprintf("route=%q,client=%q", route, client),
+					fmt.Sprint(stats.Clients[client]),
+				}
+			}
+			return samples
+		},
+	},
+}
+
+// WritePrometheus writes every route metric using the prometheus text exposition format.
+func WritePrometheus(w io.Writer) error {
+	stats := Stats()
+	routes := generics.MapKeys(stats)
+	sort.Strings(routes)
+
+	var sb strings.Builder
+	for _, metric := range prometheusMetrics {
+		_, _ = fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, route := range routes {
+			for _, sample := range metric.samples(route, stats[route]) {
+				_, _ = fmt.Fprintf(&sb, "%s{%s} %s\n", metric.name, sample[0], sample[1])
+			}
+		}
+	}
+	for _, metric := range sortedInstanceMetrics() {
+		_, _ = fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		_, _ = fmt.Fprintf(&sb, "%s %s\n", metric.name, strconv.FormatFloat(metric.value(), 'g', -1, 64))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -6,13 +6,25 @@ import (
 
 	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
 	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
-	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
 )
 
+// requestCounterBuffer is the number of requests a request counter holds before the aggregator reads them, requests
+// being dropped once it is full
+const requestCounterBuffer = 256
+
 type registry struct {
 	lock           sync.RWMutex
 	requestBuckets map[string]map[string]uint
-	requestChans   map[string]<-chan []byte
+	clientBuckets  map[string]map[string]uint
+	requestChans   map[string]chan Request
+	// tops tracks the top request of every route, updated along with requestBuckets
+	tops        map[string]TopRequest
+	subscribers map[*subscription]struct{}
+	cancel      context.CancelFunc
+	workers     sync.WaitGroup
+	// ctx and muxedChan are set while the aggregator runs, so that counters allocated meanwhile are read as well
+	ctx       context.Context
+	muxedChan chan innerEvent
 }
 
 var (
@@ -29,7 +41,10 @@ func init() {
 func newRegistry() *registry {
 	return &registry{
 		requestBuckets: make(map[string]map[string]uint),
-		requestChans:   make(map[string]<-chan []byte),
+		clientBuckets:  make(map[string]map[string]uint),
+		requestChans:   make(map[string]chan Request),
+		tops:           make(map[string]TopRequest),
+		subscribers:    make(map[*subscription]struct{}),
 	}
 }
 
@@ -43,11 +58,8 @@ func (r *registry) top(routes ...string) (map[string]TopRequest, error) {
 
 	result := make(map[string]TopRequest)
 	for _, route := range routes {
-		var topRequest TopRequest
-		maxHit := uint(0)
-
 		// absence of requests is normal if the application has juste started and has no traffic
-		if !generics.MapHas(r.requestBuckets, route) {
+		if !generics.MapHas(r.tops, route) {
 			// if we don't have any associated channel for this route, it means it wasn't registered
 			// dispatch a 404
 			if !generics.MapHas(r.requestChans, route) {
@@ -61,36 +73,82 @@ func (r *registry) top(routes ...string) (map[string]TopRequest, error) {
 			}
 			continue
 		}
+		result[route] = r.tops[route]
+	}
+	return result, nil
+}
 
-		// find the most common request
-		for request, hit := range r.requestBuckets[route] {
-			if hit > maxHit {
-				maxHit = hit
-				topRequest = TopRequest{
-					Route: route,
-					Bytes: []byte(request),
-					Hits:  hit,
-				}
+func (r *registry) stats() map[string]RouteStats {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	result := make(map[string]RouteStats, len(r.requestChans))
+	for route := range r.requestChans {
+		stats := RouteStats{Route: route}
+		for _, hit := range r.requestBuckets[route] {
+			stats.Requests += hit
+			stats.Distinct++
+			if hit > stats.TopHits {
+				stats.TopHits = hit
 			}
 		}
-		result[route] = topRequest
+		stats.Clients = make(map[string]uint, len(r.clientBuckets[route]))
+		for client, hit := range r.clientBuckets[route] {
+			stats.Clients[client] = hit
+		}
+		result[route] = stats
 	}
-	return result, nil
+	return result
+}
+
+func (r *registry) reset(routes ...string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(routes) == 0 {
+		routes = generics.MapKeys(r.requestChans)
+	}
+	for _, route := range routes {
+		if !generics.MapHas(r.requestChans, route) {
+			return errors.NotFound()
+		}
+	}
+	for _, route := range routes {
+		delete(r.requestBuckets, route)
+		delete(r.clientBuckets, route)
+		if generics.MapHas(r.tops, route) {
+			delete(r.tops, route)
+			r.publish(TopRequest{Route: route}, true)
+		}
+	}
+	return nil
 }
 
-func (r *registry) newRequestCounter(path string) chan<- []byte {
+func (r *registry) newRequestCounter(path string) chan<- Request {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	requestChan := make(chan []byte)
+	// counters are shared, so that requests of a route can also be counted outside of its handler
+	if requestChan, ok := r.requestChans[path]; ok {
+		return requestChan
+	}
+	requestChan := make(chan Request, requestCounterBuffer)
 	r.requestChans[path] = requestChan
+	if r.cancel != nil {
+		r.fanIn(path, requestChan)
+	}
 	return requestChan
 }
 
-func (r *registry) incr(route, payload string) {
+func (r *registry) incr(route, client, payload string) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	if !generics.MapHas(r.clientBuckets, route) {
+		r.clientBuckets[route] = make(map[string]uint)
+	}
+	r.clientBuckets[route][client] += 1
+
 	if !generics.MapHas(r.requestBuckets, route) {
 		r.requestBuckets[route] = make(map[string]uint)
 	}
@@ -98,54 +156,149 @@ func (r *registry) incr(route, payload string) {
 		r.requestBuckets[route][payload] = 0
 	}
 	r.requestBuckets[route][payload] += 1
+
+	// the first request reaching a hit count becomes the top request
+	hits := r.requestBuckets[route][payload]
+	top, ok := r.tops[route]
+	if ok && hits <= top.Hits {
+		return
+	}
+	changed := !ok || string(top.Bytes) != payload
+	if changed {
+		top = TopRequest{Route: route, Bytes: []byte(payload)}
+	}
+	top.Hits = hits
+	r.tops[route] = top
+	r.publish(top, changed)
 }
 
 type innerEvent struct {
 	route   string
+	client  string
 	payload string
 }
 
 func (r *registry) start(ctx context.Context) {
-	log := logger.FromContext(ctx)
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
-	r.lock.RLock()
-	defer r.lock.RUnlock()
+	ctx, r.cancel = context.WithCancel(ctx)
 
-	// routes * (n * 16) buffer
-	muxedChan := make(chan innerEvent, len(r.requestChans)*16)
+	// routes * (n * 16) buffer, routes registered later on included
+	muxedChan := make(chan innerEvent, (len(r.requestChans)+1)*16)
+	r.ctx, r.muxedChan = ctx, muxedChan
 
 	// fan-in incoming request bodies from handlers
 	for route, requestChan := range r.requestChans {
-		// clone
-		requestChan := requestChan
-		route := route
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case request := <-requestChan:
+		r.fanIn(route, requestChan)
+	}
+
+	// update the inner state
+	r.workers.Add(1)
+	go func() {
+		defer r.workers.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				// flush already multiplexed events before exiting
+				for {
 					select {
-					case muxedChan <- innerEvent{route, string(request)}:
-						continue
-					case <-ctx.Done():
-						log.Warn("context was cancelled before request metrics could be dispatched")
+					case event := <-muxedChan:
+						r.incr(event.route, event.client, event.payload)
+					default:
 						return
 					}
 				}
+			case event := <-muxedChan:
+				r.incr(event.route, event.client, event.payload)
 			}
-		}()
-	}
+		}
+	}()
+}
 
-	// update the inner state
+// fanIn forwards the requests of a route to the aggregator, it expects the lock to be held while the aggregator runs
+func (r *registry) fanIn(route string, requestChan <-chan Request) {
+	ctx, muxedChan := r.ctx, r.muxedChan
+	r.workers.Add(1)
 	go func() {
+		defer r.workers.Done()
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case event := <-muxedChan:
-				r.incr(event.route, event.payload)
+			case request := <-requestChan:
+				select {
+				case muxedChan <- innerEvent{route, request.Client, string(request.Payload)}:
+					continue
+				case <-ctx.Done():
+					// the aggregator may have exited already, the request is counted directly
+					r.incr(route, request.Client, string(request.Payload))
+					return
+				}
+			}
+		}
+	}()
+}
+
+// flush counts the events and requests still buffered once the aggregator stopped
+func (r *registry) flush(muxedChan chan innerEvent) {
+	for pending := true; pending; {
+		select {
+		case event := <-muxedChan:
+			r.incr(event.route, event.client, event.payload)
+		default:
+			pending = false
+		}
+	}
+
+	r.lock.RLock()
+	requestChans := make(map[string]chan Request, len(r.requestChans))
+	for route, requestChan := range r.requestChans {
+		requestChans[route] = requestChan
+	}
+	r.lock.RUnlock()
+
+	for route, requestChan := range requestChans {
+		for pending := true; pending; {
+			select {
+			case request := <-requestChan:
+				r.incr(route, request.Client, string(request.Payload))
+			default:
+				pending = false
 			}
 		}
+	}
+}
+
+func (r *registry) stop(ctx context.Context) error {
+	// no fan-in worker is started once the aggregator is stopping
+	r.lock.Lock()
+	cancel, muxedChan := r.cancel, r.muxedChan
+	r.cancel, r.ctx, r.muxedChan = nil, nil, nil
+	r.lock.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.workers.Wait()
+		close(done)
 	}()
+	select {
+	case <-ctx.Done():
+		return errors.Unavailable(ctx.Err(), "metrics aggregator did not stop in time")
+	case <-done:
+		r.flush(muxedChan)
+		return nil
+	}
+}
+
+func (r *registry) running() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.cancel != nil
 }
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestCounter(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	r := newRegistry()
+	early := r.newRequestCounter("/early")
+	r.start(ctx)
+
+	// routes registered once the aggregator runs are counted as well
+	late := r.newRequestCounter("/late")
+	early <- Request{Client: "alice", Payload: []byte("a")}
+	late <- Request{Client: "alice", Payload: []byte("a")}
+	a.Eventually(func() bool {
+		stats := r.stats()
+		return stats["/early"].Requests == 1 && stats["/late"].Requests == 1
+	}, time.Second, 10*time.Millisecond)
+	a.NoError(r.stop(ctx))
+
+	// requests buffered while the aggregator is stopped are flushed on the next stop
+	late <- Request{Client: "alice", Payload: []byte("b")}
+	r.start(ctx)
+	a.NoError(r.stop(ctx))
+	a.Equal(uint(2), r.stats()["/late"].Requests)
+}
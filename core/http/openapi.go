@@ -2,6 +2,7 @@ package http
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/swaggest/openapi-go/openapi3"
 )
@@ -23,13 +24,30 @@ func openapi() openapi3.Reflector {
 	return reflector
 }
 
-func registerOperation[Request any, Response any](reflector openapi3.Reflector, h Handler) error {
-	op := openapi3.Operation{}
+func registerOperation[Request any, Response any](reflector openapi3.Reflector, h Handler, security []map[string][]string) error {
+	op := openapi3.Operation{
+		Security: security,
+	}
 	if err := reflector.SetRequest(&op, new(Request), h.method); err != nil {
 		return err
 	}
 	if err := reflector.SetJSONResponse(&op, new(Response), http.StatusOK); err != nil {
 		return err
 	}
-	return reflector.Spec.AddOperation(h.method, h.path, op)
+	// openapi 3.0 only allows scopes for oauth2 security schemes, they are documented as an extension instead
+	if len(security) > 0 && len(h.scopes) > 0 {
+		op.WithMapOfAnythingItem("x-required-scopes", h.scopes)
+	}
+	return reflector.Spec.AddOperation(h.method, openapiPath(h.path), op)
+}
+
+// openapiPath converts echo path parameters such as `/rulesets/:id` to the openapi3 notation `/rulesets/{id}`
+func openapiPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
 }
@@ -0,0 +1,251 @@
+package http
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	pkgErrors "github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+const (
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+	headerRetryAfter         = "Retry-After"
+
+	// bucketIdleTimeout is the duration after which an unused client bucket is evicted
+	bucketIdleTimeout = 10 * time.Minute
+)
+
+type (
+	// RateLimit is a token bucket configuration, Rate being the number of requests per second and Burst the number
+	// of requests which can exceed it at once.
+	RateLimit struct {
+		Rate  float64
+		Burst int
+	}
+	clientBucket struct {
+		limiter *rate.Limiter
+		seenAt  time.Time
+	}
+	// clientLimiters holds one token bucket per client, evicting idle ones
+	clientLimiters struct {
+		lock      sync.Mutex
+		fallback  RateLimit
+		overrides map[string]float64
+		buckets   map[string]*clientBucket
+		sweptAt   time.Time
+	}
+	// rateLimiters holds instance wide token buckets, shared by every rate limited handler
+	rateLimiters struct {
+		global  *rate.Limiter
+		clients *clientLimiters
+	}
+)
+
+func (l RateLimit) limiter() *rate.Limiter {
+	burst := l.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(l.Rate))
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// defaultRateLimiters returns instance wide token buckets enabled through configuration.
+func defaultRateLimiters() *rateLimiters {
+	limiters := &rateLimiters{}
+	if config.Config.RateLimit > 0 {
+		limiters.global = RateLimit{Rate: config.Config.RateLimit, Burst: config.Config.RateLimitBurst}.limiter()
+	}
+	if config.Config.ClientRateLimit > 0 || len(config.Config.ClientRateLimits) > 0 {
+		limiters.clients = &clientLimiters{
+			fallback:  RateLimit{Rate: config.Config.ClientRateLimit, Burst: config.Config.ClientRateLimitBurst},
+			overrides: config.Config.ClientRateLimits,
+			buckets:   make(map[string]*clientBucket),
+			sweptAt:   time.Now(),
+		}
+	}
+	return limiters
+}
+
+// get returns the token bucket of a given client, or nil if the client is not rate limited
+func (l *clientLimiters) get(client string, now time.Time) *rate.Limiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.Sub(l.sweptAt) > bucketIdleTimeout {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.seenAt) > bucketIdleTimeout {
+				delete(l.buckets, key)
+			}
+		}
+		l.sweptAt = now
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		limit := l.fallback
+		if override, ok := l.overrides[client]; ok {
+			limit = RateLimit{Rate: override, Burst: int(math.Ceil(override))}
+		}
+		if limit.Rate <= 0 {
+			return nil
+		}
+		bucket = &clientBucket{limiter: limit.limiter()}
+		l.buckets[client] = bucket
+	}
+	bucket.seenAt = now
+	return bucket.limiter
+}
+
+func (l *rateLimiters) enabled() bool {
+	return l.global != nil || l.clients != nil
+}
+
+// setRateLimitHeaders advertises the state of a token bucket, following the IETF RateLimit header fields draft
+func setRateLimitHeaders(c echo.Context, limiter *rate.Limiter, now time.Time) {
+	tokens := math.Max(0, limiter.TokensAt(now))
+	reset := 0.0
+	if limiter.Limit() > 0 {
+		reset = math.Ceil((float64(limiter.Burst()) - tokens) / float64(limiter.Limit()))
+	}
+	header := c.Response().Header()
+	header.Set(headerRateLimitLimit, strconv.Itoa(limiter.Burst()))
+	header.Set(headerRateLimitRemaining, strconv.Itoa(int(math.Floor(tokens))))
+	header.Set(headerRateLimitReset, strconv.Itoa(int(reset)))
+}
+
+// ipExtractor returns the echo.IPExtractor identifying clients by their connection address, or by the
+// X-Forwarded-For header when the connection comes from one of config.Manifest TrustedProxies.
+func ipExtractor() (echo.IPExtractor, error) {
+	if len(config.Config.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	// private networks are not trusted by default, as they also host clients
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range config.Config.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, pkgErrors.Wrapf(err, "invalid trusted proxy '%s'", proxy)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// clientKey identifies the client of a request, through its identity.Identity, or its IP address.
+func clientKey(c echo.Context) string {
+	if id, ok := identity.FromContext(c.Request().Context()); ok {
+		return id.ClientId
+	}
+	return "ip:" + c.RealIP()
+}
+
+// rejectRequest advertises the state of the bucket rejecting a request, and when it can be retried
+func rejectRequest(c echo.Context, bucket *rate.Limiter, now time.Time, retryAfter time.Duration) error {
+	setRateLimitHeaders(c, bucket, now)
+	c.Response().Header().Set(headerRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return errors.TooManyRequests(nil, "rate limit exceeded, retry in %s", retryAfter.Round(time.Millisecond))
+}
+
+// rateLimitMiddleware consumes one token from the route, client and global buckets, every one of them being
+// required to accept the request. Clients are identified through identity.Identity, or their IP address.
+func rateLimitMiddleware(limiters *rateLimiters, route *rate.Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			now := time.Now()
+
+			var buckets []*rate.Limiter
+			if route != nil {
+				buckets = append(buckets, route)
+			}
+			if limiters.clients != nil {
+				if bucket := limiters.clients.get(clientKey(c), now); bucket != nil {
+					buckets = append(buckets, bucket)
+				}
+			}
+			if limiters.global != nil {
+				buckets = append(buckets, limiters.global)
+			}
+			if len(buckets) == 0 {
+				return next(c)
+			}
+
+			reservations := make([]*rate.Reservation, 0, len(buckets))
+			for _, bucket := range buckets {
+				reservation := bucket.ReserveN(now, 1)
+				reservations = append(reservations, reservation)
+				if reservation.OK() && reservation.DelayFrom(now) == 0 {
+					continue
+				}
+
+				// give back tokens already taken from other buckets
+				retryAfter := time.Second
+				if reservation.OK() {
+					retryAfter = reservation.DelayFrom(now)
+				}
+				for _, r := range reservations {
+					r.CancelAt(now)
+				}
+				return rejectRequest(c, bucket, now, retryAfter)
+			}
+
+			// advertise the most restrictive bucket
+			tightest := buckets[0]
+			for _, bucket := range buckets[1:] {
+				if bucket.TokensAt(now) < tightest.TokensAt(now) {
+					tightest = bucket
+				}
+			}
+			setRateLimitHeaders(c, tightest, now)
+			return next(c)
+		}
+	}
+}
+
+// authFailureLimitMiddleware consumes one token from the bucket of the client IP address whenever authentication
+// fails, as rejected requests never reach rateLimitMiddleware. Addresses whose bucket is exhausted are rejected
+// before their credentials are checked.
+func authFailureLimitMiddleware(limiters *rateLimiters) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			now := time.Now()
+			bucket := limiters.clients.get("ip:"+c.RealIP(), now)
+			if bucket == nil {
+				return next(c)
+			}
+			if bucket.TokensAt(now) < 1 {
+				reservation := bucket.ReserveN(now, 1)
+				retryAfter := time.Second
+				if reservation.OK() {
+					retryAfter = reservation.DelayFrom(now)
+				}
+				reservation.CancelAt(now)
+				return rejectRequest(c, bucket, now, retryAfter)
+			}
+			err := next(c)
+			if err != nil && errorStatus(err) == http.StatusUnauthorized {
+				bucket.AllowN(now, 1)
+			}
+			return err
+		}
+	}
+}
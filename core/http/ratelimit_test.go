@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+)
+
+func TestClientAddress(t *testing.T) {
+	a := assert.New(t)
+	defer func() { config.Config.TrustedProxies = nil }()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set("X-Forwarded-For", "203.0.113.7")
+	request.Header.Set("X-Real-Ip", "203.0.113.8")
+
+	extractor, err := ipExtractor()
+	if a.NoError(err) {
+		a.Equal("10.0.0.1", extractor(request), "forwarding headers are ignored by default")
+	}
+	config.Config.TrustedProxies = []string{"10.0.0.0/24"}
+	extractor, err = ipExtractor()
+	if a.NoError(err) {
+		a.Equal("203.0.113.7", extractor(request), "trusted proxies forward the client address")
+	}
+	config.Config.TrustedProxies = []string{"10.0.0.2", "::1"}
+	extractor, err = ipExtractor()
+	if a.NoError(err) {
+		a.Equal("10.0.0.1", extractor(request))
+	}
+	config.Config.TrustedProxies = []string{"10.0.0.0/33"}
+	_, err = ipExtractor()
+	a.Error(err)
+}
+
+func TestAuthFailureRateLimit(t *testing.T) {
+	a := assert.New(t)
+	config.Config.ApiKeys = map[string]string{"loadgen": "secret"}
+	config.Config.ClientRateLimit = 1
+	defer func() { config.Config.ApiKeys, config.Config.ClientRateLimit = nil, 0 }()
+	s := NewServer(Get("/hello", func(context.Context, Empty) (*Empty, error) { return &Empty{}, nil }))
+	serve := func(key, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set(config.Config.ApiKeyHeader, key)
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	a.Equal(http.StatusUnauthorized, serve("guess", "192.0.2.1:1234", "203.0.113.1").Code)
+	response := serve("guess", "192.0.2.1:1234", "203.0.113.2")
+	a.Equal(http.StatusTooManyRequests, response.Code, "failed authentications are rate limited by address")
+	a.NotEmpty(response.Header().Get(headerRetryAfter))
+	a.Equal(http.StatusTooManyRequests, serve("secret", "192.0.2.1:1234", "").Code,
+		"credentials are not checked once the address is rate limited")
+	a.Equal(http.StatusOK, serve("secret", "192.0.2.2:1234", "").Code)
+	a.Equal(http.StatusUnauthorized, serve("guess", "192.0.2.2:1234", "").Code,
+		"successful authentications are not charged to the address")
+}
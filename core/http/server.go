@@ -5,45 +5,146 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/pkg/errors"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 
 	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
 	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
 	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
 )
 
-// Server is a convenience wrapper around echo.Echo and http.Listener lifecycles
-type Server struct {
-	inner *echo.Echo
-}
+// adminWriteTimeoutMargin is the time left to write a profile of config.Manifest ProfilingMaxDuration
+const adminWriteTimeoutMargin = 10 * time.Second
+
+type (
+	// Server is a convenience wrapper around echo.Echo and http.Listener lifecycles
+	Server struct {
+		inner          *echo.Echo
+		admin          *echo.Echo
+		authenticators []Authenticator
+		limiters       *rateLimiters
+		services       []namedService
+		hooks          []namedShutdownHook
+	}
+	// Service is a listener serving requests alongside the Server, such as a gRPC server, see Server.WithService.
+	Service interface {
+		// Serve serves requests until Shutdown is called, it returns a non nil error whenever serving failed.
+		Serve() error
+		// Shutdown gracefully stops serving requests, in-flight ones being interrupted once the given
+		// context.Context is done.
+		Shutdown(ctx context.Context) error
+	}
+	namedService struct {
+		name    string
+		service Service
+	}
+	// ShutdownHook is a function invoked during the Server shutdown sequence, such as flushing buffers or closing
+	// stores. The given context.Context is done once the configured shutdown timeout is exceeded.
+	ShutdownHook      func(ctx context.Context) error
+	namedShutdownHook struct {
+		name string
+		hook ShutdownHook
+	}
+)
 
 // NewServer instantiate a new Server with sane defaults, while also mounting every given Handler, and generating
 // associated oas3 specs.
+// Handlers flagged with Handler.Admin are mounted on a dedicated listener if config.Manifest AdminAddr is set,
+// otherwise they share the public listener. Mutating admin handlers are never served publicly without authentication.
 func NewServer(handlers ...Handler) *Server {
 	log := logger.New()
-	e := echo.New()
+	authenticators, err := defaultAuthenticators()
+	if err != nil {
+		log.Fatal("authentication initialisation failed", zap.Error(err))
+	}
+
+	var public, admin []Handler
+	for _, handler := range handlers {
+		switch {
+		case handler.admin && config.Config.AdminEnabled():
+			admin = append(admin, handler)
+		case handler.admin && handler.mutating() && (len(authenticators) == 0 || handler.anonymous):
+			log.Warn("admin endpoint disabled, it requires an admin listener or authentication",
+				zap.String("path", handler.path), zap.String("method", handler.method))
+		default:
+			public = append(public, handler)
+		}
+	}
 
-	handlers = append(handlers)
+	limiters := defaultRateLimiters()
+
+	s := &Server{
+		inner:          newEcho(public, authenticators, limiters),
+		authenticators: authenticators,
+		limiters:       limiters,
+	}
+	if config.Config.AdminEnabled() {
+		s.admin = newEcho(admin, authenticators, limiters)
+		// profiles are written once captured, and net/http/pprof refuses durations exceeding the write timeout
+		if timeout := config.Config.ProfilingMaxDuration + adminWriteTimeoutMargin; s.admin.Server.WriteTimeout > 0 &&
+			s.admin.Server.WriteTimeout < timeout {
+			s.admin.Server.WriteTimeout = timeout
+		}
+	}
+	return s
+}
+
+// newEcho instantiate an echo.Echo with sane defaults, mounting every given Handler and its oas3 specs.
+// Non anonymous handlers are authenticated using the given authenticators, if any, and non admin handlers are rate
+// limited using the given limiters.
+func newEcho(handlers []Handler, authenticators []Authenticator, limiters *rateLimiters) *echo.Echo {
+	log := logger.New()
+	e := echo.New()
 
 	e.Debug = !config.Config.IsProd()
 	e.HideBanner = true
 	e.HTTPErrorHandler = ErrorHandler()
+	extractor, err := ipExtractor()
+	if err != nil {
+		log.Fatal("client address extraction initialisation failed", zap.Error(err))
+	}
+	e.IPExtractor = extractor
+	e.Server.ReadTimeout = config.Config.ReadTimeout
+	e.Server.ReadHeaderTimeout = config.Config.ReadHeaderTimeout
+	e.Server.WriteTimeout = config.Config.WriteTimeout
+	e.Server.IdleTimeout = config.Config.IdleTimeout
+	// shutting down waits for active connections, event streams have to be interrupted, and WebSocket sessions are
+	// not tracked at all once upgraded
+	e.Server.RegisterOnShutdown(globalStreams.closeAll)
+	e.Server.RegisterOnShutdown(globalSessions.closeAll)
 
 	e.Use(middleware.RequestID())
 	e.Use(logger.HttpMiddleware())
 	if config.Config.CorsEnabled {
 		e.Use(middleware.CORS())
 	}
+	// decompressing first applies the body limit to decompressed request bodies
+	e.Use(decompressionMiddleware())
+	if config.Config.BodyLimit != "" {
+		e.Use(middleware.BodyLimit(config.Config.BodyLimit))
+	}
 
 	oas3 := openapi()
+	var security []map[string][]string
+	if len(authenticators) > 0 {
+		security = securityRequirements(oas3, authenticators)
+	}
 	for _, handler := range handlers {
+		authenticated := len(authenticators) > 0 && !handler.anonymous
 		if handler.reflect != nil {
-			if err := handler.reflect(oas3); err != nil {
+			var handlerSecurity []map[string][]string
+			if authenticated {
+				handlerSecurity = security
+			}
+			if err := handler.reflect(oas3, handler, handlerSecurity); err != nil {
 				log.Fatal("openapi3 reflection error", zap.Error(err), zap.String("path", handler.path))
 			}
 		}
@@ -51,9 +152,37 @@ func NewServer(handlers ...Handler) *Server {
 		log.Debug(
 			"handler registered",
 			zap.String("path", handler.path), zap.String("method", handler.method),
-			zap.String("operationId", handler.operationId),
+			zap.String("operationId", handler.operationId), zap.Bool("admin", handler.admin),
+			zap.Bool("authenticated", authenticated),
 		)
-		e.Add(handler.method, handler.path, handler.impl)
+		var middlewares []echo.MiddlewareFunc
+		if config.Config.CompressionEnabled && !handler.uncompressed {
+			middlewares = append(middlewares, compressionMiddleware(config.Config.CompressionMinSize))
+		}
+		if authenticated {
+			if limiters.clients != nil {
+				middlewares = append(middlewares, authFailureLimitMiddleware(limiters))
+			}
+			middlewares = append(middlewares, authMiddleware(authenticators))
+			if len(handler.scopes) > 0 {
+				middlewares = append(middlewares, scopesMiddleware(handler.scopes))
+			}
+		}
+		if !handler.admin && (limiters.enabled() || handler.rateLimit != nil) {
+			var route *rate.Limiter
+			if handler.rateLimit != nil {
+				route = handler.rateLimit.limiter()
+			}
+			middlewares = append(middlewares, rateLimitMiddleware(limiters, route))
+		}
+		if deadline := deadlineMiddleware(handler.timeout); deadline != nil {
+			middlewares = append(middlewares, deadline)
+		}
+		middlewares = append(middlewares, handler.middlewares...)
+		handler := handler
+		e.Add(handler.method, handler.path, func(c echo.Context) error {
+			return handler.impl(handler, c)
+		}, middlewares...)
 	}
 
 	schemaBytes, err := oas3.Spec.MarshalJSON()
@@ -61,46 +190,138 @@ func NewServer(handlers ...Handler) *Server {
 		log.Fatal("invalid openapi3 JSON schema", zap.Error(err))
 	}
 
+	var openapiMiddlewares []echo.MiddlewareFunc
+	if config.Config.CompressionEnabled {
+		openapiMiddlewares = append(openapiMiddlewares, compressionMiddleware(config.Config.CompressionMinSize))
+	}
 	e.GET("openapi.json", func(c echo.Context) error {
 		return c.Blob(http.StatusOK, "application/json", schemaBytes)
-	})
+	}, openapiMiddlewares...)
+
+	return e
+}
+
+// ServeHTTP serves a request using the handlers of the public listener, allowing handlers to be tested end to end.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.inner.ServeHTTP(w, r)
+}
+
+// WithService registers a Service started along with the Server, and gracefully stopped along with the public
+// listener, before the admin listener and every ShutdownHook.
+func (s *Server) WithService(name string, service Service) *Server {
+	s.services = append(s.services, namedService{name: name, service: service})
+	return s
+}
 
-	return &Server{inner: e}
+// OnShutdown registers a ShutdownHook, hooks are invoked in registration order once the server stopped serving
+// requests, and after the metrics subsystem has been flushed.
+func (s *Server) OnShutdown(name string, hook ShutdownHook) *Server {
+	s.hooks = append(s.hooks, namedShutdownHook{name: name, hook: hook})
+	return s
 }
 
-// Run starts the server and every associated sub-systems, this call blocks and should only be called once in your application
+// Run starts the server and every associated sub-systems, this call blocks and should only be called once in your application.
+// It returns once the server has been shut down, either because of SIGINT, SIGTERM, the parent context cancellation,
+// or the failure of a listener or Service, the shutdown sequence running in every case.
 func (s *Server) Run(ctx context.Context) error {
 	log := logger.New()
 	addr := config.Config.ListenAddr()
 
 	//handlers are registered by this point
 	//we can start the metrics subsystem
-	metrics.StartAggregator(ctx)
+	//it is stopped through the shutdown sequence, not the parent context, so pending metrics can be flushed
+	metrics.StartAggregator(context.Background())
+	health.Register("metrics", health.Liveness, time.Second, func(_ context.Context) error {
+		if !metrics.IsRunning() {
+			return errors.New("metrics aggregator is not running")
+		}
+		return nil
+	})
 
+	serverErr := make(chan error, 2+len(s.services))
 	go func() {
 		log.Info("starting server", zap.String("addr", addr))
 		if err := s.inner.Start(addr); err != nil && err != http.ErrServerClosed {
-			log.Fatal("ungraceful server shutdown", zap.Error(err))
+			serverErr <- err
 		}
 	}()
+	if s.admin != nil {
+		adminAddr := config.Config.AdminAddr
+		go func() {
+			log.Info("starting admin server", zap.String("addr", adminAddr))
+			if err := s.admin.Start(adminAddr); err != nil && err != http.ErrServerClosed {
+				serverErr <- errors.Wrapf(err, "admin server")
+			}
+		}()
+	}
+
+	for _, svc := range s.services {
+		svc := svc
+		go func() {
+			log.Info("starting service", zap.String("service", svc.name))
+			if err := svc.service.Serve(); err != nil {
+				serverErr <- errors.Wrapf(err, "service '%s'", svc.name)
+			}
+		}()
+	}
 
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, os.Kill)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
 
-	// handle parent context cancellation
 	select {
+	case err := <-serverErr:
+		// everything else is still running, and hooks must run all the same
+		log.Error("server failed, shutting down", zap.Error(err))
+		return multierr.Append(errors.Wrapf(err, "ungraceful server shutdown"), s.shutdown())
 	case <-ctx.Done():
-		return ctx.Err()
-	case <-quit:
+		log.Info("parent context cancelled", zap.Error(ctx.Err()))
+	case sig := <-quit:
+		log.Info("received signal", zap.String("signal", sig.String()))
+	}
+
+	return s.shutdown()
+}
+
+// shutdown drains traffic, stops the server, and invokes every ShutdownHook
+func (s *Server) shutdown() error {
+	log := logger.New()
+
+	health.Drain()
+	if drain := config.Config.ShutdownDrainPeriod; drain > 0 {
+		log.Info("draining server", zap.Duration("period", drain))
+		time.Sleep(drain)
 	}
 
 	log.Info("shutting down server")
 
-	cleanupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// the parent context might already be cancelled, shutdown gets its own deadline
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), config.Config.ShutdownTimeout)
 	defer cancel()
 
-	if err := s.inner.Shutdown(cleanupCtx); err != nil {
-		return errors.Wrapf(err, "graceful server shutdown failed")
+	var err error
+	if shutdownErr := s.inner.Shutdown(cleanupCtx); shutdownErr != nil {
+		err = multierr.Append(err, errors.Wrapf(shutdownErr, "graceful server shutdown failed"))
+	}
+	for _, svc := range s.services {
+		if shutdownErr := svc.service.Shutdown(cleanupCtx); shutdownErr != nil {
+			err = multierr.Append(err, errors.Wrapf(shutdownErr, "graceful service '%s' shutdown failed", svc.name))
+		}
+	}
+	// the admin server is stopped last, so probes and metrics stay reachable while public traffic drains
+	if s.admin != nil {
+		if shutdownErr := s.admin.Shutdown(cleanupCtx); shutdownErr != nil {
+			err = multierr.Append(err, errors.Wrapf(shutdownErr, "graceful admin server shutdown failed"))
+		}
+	}
+
+	hooks := append([]namedShutdownHook{{name: "metrics", hook: metrics.StopAggregator}}, s.hooks...)
+	for _, h := range hooks {
+		log.Debug("running shutdown hook", zap.String("hook", h.name))
+		if hookErr := h.hook(cleanupCtx); hookErr != nil {
+			log.Error("shutdown hook failed", zap.String("hook", h.name), zap.Error(hookErr))
+			err = multierr.Append(err, errors.Wrapf(hookErr, "shutdown hook '%s' failed", h.name))
+		}
 	}
-	return nil
+	return err
 }
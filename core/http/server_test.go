@@ -0,0 +1,269 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
+)
+
+// recordingService is a Service recording the shutdown sequence, as observed when it is stopped
+type recordingService struct {
+	stopped chan struct{}
+	onStop  func()
+}
+
+func (s *recordingService) Serve() error {
+	<-s.stopped
+	return nil
+}
+
+func (s *recordingService) Shutdown(_ context.Context) error {
+	s.onStop()
+	close(s.stopped)
+	return nil
+}
+
+// accepting checks whether a listener still accepts connections
+func accepting(addr net.Addr) bool {
+	conn, err := net.DialTimeout("tcp", addr.String(), 100*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func TestServerShutdown(t *testing.T) {
+	a := assert.New(t)
+	addr, adminAddr, drain, timeout := config.Config.Addr, config.Config.AdminAddr,
+		config.Config.ShutdownDrainPeriod, config.Config.ShutdownTimeout
+	config.Config.Addr, config.Config.AdminAddr = "127.0.0.1:0", "127.0.0.1:0"
+	config.Config.ShutdownDrainPeriod, config.Config.ShutdownTimeout = 50*time.Millisecond, 200*time.Millisecond
+	defer func() {
+		config.Config.Addr, config.Config.AdminAddr = addr, adminAddr
+		config.Config.ShutdownDrainPeriod, config.Config.ShutdownTimeout = drain, timeout
+	}()
+
+	var lock sync.Mutex
+	var steps []string
+	record := func(step string) {
+		lock.Lock()
+		defer lock.Unlock()
+		steps = append(steps, step)
+	}
+
+	s := NewServer()
+	var cancelledAt time.Time
+	service := &recordingService{stopped: make(chan struct{}), onStop: func() {
+		a.True(health.IsDraining(), "readiness fails before anything is stopped")
+		a.GreaterOrEqual(time.Since(cancelledAt), config.Config.ShutdownDrainPeriod, "traffic is drained first")
+		a.False(accepting(s.inner.ListenerAddr()), "services are stopped after the public listener")
+		a.True(accepting(s.admin.ListenerAddr()), "services are stopped before the admin listener")
+		record("service")
+	}}
+	s.WithService("recording", service).
+		OnShutdown("first", func(ctx context.Context) error {
+			a.False(accepting(s.admin.ListenerAddr()), "hooks run once every listener is stopped")
+			record("first")
+			return nil
+		}).
+		OnShutdown("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			record("slow")
+			return ctx.Err()
+		}).
+		OnShutdown("last", func(ctx context.Context) error {
+			a.Error(ctx.Err(), "hooks share the shutdown deadline")
+			record("last")
+			return nil
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	a.Eventually(func() bool {
+		return s.inner.ListenerAddr() != nil && s.admin.ListenerAddr() != nil
+	}, time.Second, 5*time.Millisecond)
+
+	cancelledAt = time.Now()
+	cancel()
+	select {
+	case err := <-done:
+		a.ErrorContains(err, "shutdown hook 'slow' failed", "hooks exceeding the shutdown timeout fail the shutdown")
+		a.ErrorIs(err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		a.Fail("server did not shut down")
+	}
+	a.Equal([]string{"service", "first", "slow", "last"}, steps, "every hook runs, in registration order")
+}
+
+// failingService is a Service which cannot be started
+type failingService struct{}
+
+func (failingService) Serve() error {
+	return errors.New("address already in use")
+}
+
+func (failingService) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestServerFailure(t *testing.T) {
+	a := assert.New(t)
+	addr, adminAddr, drain := config.Config.Addr, config.Config.AdminAddr, config.Config.ShutdownDrainPeriod
+	config.Config.Addr, config.Config.AdminAddr, config.Config.ShutdownDrainPeriod = "127.0.0.1:0", "127.0.0.1:0", 0
+	defer func() {
+		config.Config.Addr, config.Config.AdminAddr, config.Config.ShutdownDrainPeriod = addr, adminAddr, drain
+	}()
+
+	hooked := false
+	s := NewServer().
+		WithService("failing", failingService{}).
+		OnShutdown("flush", func(context.Context) error {
+			hooked = true
+			return nil
+		})
+	done := make(chan error)
+	go func() { done <- s.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		a.ErrorContains(err, "ungraceful server shutdown")
+		a.ErrorContains(err, "address already in use")
+	case <-time.After(2 * time.Second):
+		a.Fail("server did not shut down")
+		return
+	}
+	a.True(hooked, "shutdown hooks run when a service fails")
+	if adminAddr := s.admin.ListenerAddr(); adminAddr != nil {
+		a.False(accepting(adminAddr), "the admin listener is stopped")
+	}
+}
+
+func TestServerAdminHandlers(t *testing.T) {
+	a := assert.New(t)
+	impl := func(context.Context, Empty) (*Empty, error) { return &Empty{}, nil }
+	handlers := []Handler{
+		Get("/admin/state", impl).Admin(),
+		Put("/admin/state", impl).Admin(),
+		Put("/admin/open", impl).Admin().Anonymous(),
+	}
+	serve := func(s *Server, method, target string) int {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		return recorder.Code
+	}
+
+	s := NewServer(handlers...)
+	a.Equal(http.StatusOK, serve(s, http.MethodGet, "/admin/state"))
+	a.Equal(http.StatusMethodNotAllowed, serve(s, http.MethodPut, "/admin/state"),
+		"mutating admin handlers are not served publicly without authentication")
+
+	config.Config.ApiKeys = map[string]string{"operator": "secret"}
+	defer func() { config.Config.ApiKeys = nil }()
+	s = NewServer(handlers...)
+	a.Equal(http.StatusUnauthorized, serve(s, http.MethodPut, "/admin/state"))
+	a.Equal(http.StatusNotFound, serve(s, http.MethodPut, "/admin/open"),
+		"anonymous mutating admin handlers require the admin listener")
+}
+
+func TestServerProfiling(t *testing.T) {
+	a := assert.New(t)
+	addr, adminAddr, writeTimeout := config.Config.Addr, config.Config.AdminAddr, config.Config.WriteTimeout
+	config.Config.Addr, config.Config.AdminAddr = "127.0.0.1:0", "127.0.0.1:0"
+	defer func() {
+		config.Config.Addr, config.Config.AdminAddr, config.Config.WriteTimeout = addr, adminAddr, writeTimeout
+	}()
+
+	profile := EchoHandler("/debug/pprof/profile", http.MethodGet, func(c echo.Context) error {
+		pprof.Profile(c.Response(), c.Request())
+		return nil
+	}).Admin().WithTimeout(-1)
+	s := NewServer(profile)
+	a.Greater(s.admin.Server.WriteTimeout, 30*time.Second, "default net/http/pprof profiles last 30s")
+
+	// profiles as long as the write timeout of the public listener are served by the admin one
+	config.Config.WriteTimeout = time.Second
+	s = NewServer(profile)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	a.Eventually(func() bool { return s.admin.ListenerAddr() != nil }, time.Second, 5*time.Millisecond)
+
+	response, err := http.Get("http://" + s.admin.ListenerAddr().String() + "/debug/pprof/profile?seconds=1")
+	if a.NoError(err) {
+		defer func() { _ = response.Body.Close() }()
+		a.Equal(http.StatusOK, response.StatusCode)
+	}
+}
+
+// slowReader reads its content in small chunks, pausing before each of them
+type slowReader struct {
+	*bytes.Reader
+	chunk int
+	pause time.Duration
+}
+
+func (r slowReader) Read(p []byte) (int, error) {
+	time.Sleep(r.pause)
+	if len(p) > r.chunk {
+		p = p[:r.chunk]
+	}
+	return r.Reader.Read(p)
+}
+
+func TestServeContent(t *testing.T) {
+	a := assert.New(t)
+	addr, adminAddr, writeTimeout := config.Config.Addr, config.Config.AdminAddr, config.Config.WriteTimeout
+	config.Config.Addr, config.Config.AdminAddr, config.Config.WriteTimeout = "127.0.0.1:0", "", 500*time.Millisecond
+	defer func() {
+		config.Config.Addr, config.Config.AdminAddr, config.Config.WriteTimeout = addr, adminAddr, writeTimeout
+	}()
+
+	// the content is produced slowly, so that the download lasts several times the write timeout while each write
+	// happens well within it
+	content := bytes.Repeat([]byte("fizzbuzz"), 8<<10)
+	download := EchoHandler("/download", http.MethodGet, func(c echo.Context) error {
+		ServeContent(c, "download.txt", time.Now(), slowReader{bytes.NewReader(content), 2 << 10, 50 * time.Millisecond})
+		return nil
+	}).WithTimeout(-1).WithoutCompression()
+	s := NewServer(download)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	a.Eventually(func() bool { return s.inner.ListenerAddr() != nil }, time.Second, 5*time.Millisecond)
+
+	start := time.Now()
+	response, err := http.Get("http://" + s.inner.ListenerAddr().String() + "/download")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() { _ = response.Body.Close() }()
+	a.Equal(http.StatusOK, response.StatusCode)
+
+	received, err := io.ReadAll(response.Body)
+	a.NoError(err)
+	a.Equal(len(content), len(received), "downloads are not truncated by the write timeout")
+	a.Greater(time.Since(start), 2*config.Config.WriteTimeout)
+}
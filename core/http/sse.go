@@ -0,0 +1,319 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/swaggest/openapi-go/openapi3"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+const (
+	mimeEventStream   = "text/event-stream"
+	headerLastEventID = "Last-Event-ID"
+	// errorEvent is the name of the event sent when a stream fails after it started
+	errorEvent = "error"
+)
+
+type (
+	// Event is a Server-Sent Event, its Data being serialized to JSON.
+	// Clients resume streams from the ID of the last event they received, see EventStream.LastEventID.
+	Event[Data any] struct {
+		ID   string
+		Name string
+		Data Data
+	}
+	// EventStream sends Server-Sent Events to a client, it is safe for concurrent use.
+	// Response headers are only sent along with the first event, so a StreamHandlerFunc can still fail with a regular
+	// error response until then. Streams served outside of echo, see CallStream, hand events over to their caller.
+	EventStream[Data any] struct {
+		lastEventID string
+		send        func(event Event[Data]) error
+	}
+	// StreamHandlerFunc is a type for generic streaming handlers, see Stream.
+	// Implementations send events until they are done, or the context.Context is cancelled because the client
+	// disconnected or the server is shutting down.
+	StreamHandlerFunc[Request any, Data any] func(ctx context.Context, request Request, stream *EventStream[Data]) error
+	// eventWriter writes the `text/event-stream` wire format, interleaving events and heartbeats
+	eventWriter struct {
+		c           echo.Context
+		lastEventID string
+		lock        sync.Mutex
+		committed   bool
+		err         error
+	}
+	// openConnections tracks long-lived connections such as event streams, as http.Server.Shutdown does not interrupt
+	// them
+	openConnections struct {
+		lock    sync.Mutex
+		cancels map[any]context.CancelFunc
+	}
+)
+
+var globalStreams = newOpenConnections()
+
+func init() {
+	metrics.RegisterGauge(semconv.MetricName("http", "streams", "open"),
+		"Number of Server-Sent Events streams currently open.",
+		func() float64 { return float64(globalStreams.count()) })
+}
+
+// Stream converts a generic streaming handler into a GET Handler serving Server-Sent Events.
+// Requests are bound, counted and documented like GenericHandler ones, while the handler implementation sends events
+// through an EventStream. Streams are never compressed nor bound by config.Manifest HandlerTimeout, and heartbeats
+// are sent every config.Manifest StreamHeartbeatInterval so that idle streams are kept open by proxies.
+// Handlers returning without sending any event answer 204 No Content, which tells EventSource clients to stop
+// reconnecting, such as clients resuming a stream which already ended.
+func Stream[Request any, Data any](route string, impl StreamHandlerFunc[Request, Data], middlewares ...echo.MiddlewareFunc) Handler {
+	fullNameOf := runtime.FuncForPC(reflect.ValueOf(impl).Pointer()).Name()
+	nameOf := path.Base(fullNameOf)
+	requestChan := metrics.NewRequestCounter(route)
+
+	h := Handler{
+		operationId: nameOf,
+		path:        route,
+		method:      http.MethodGet,
+		impl: func(h Handler, c echo.Context) error {
+			var request Request
+			if err := c.Bind(&request); err != nil {
+				return err
+			}
+			buf, err := json.Marshal(request)
+			if err != nil {
+				return err
+			}
+			dispatchRequest(c.Request().Context(), route, requestChan, buf)
+
+			w := &eventWriter{c: c, lastEventID: c.Request().Header.Get(headerLastEventID)}
+			ctx, cancel := globalStreams.open(c.Request().Context(), w)
+			heartbeats := make(chan struct{})
+			go func() {
+				defer close(heartbeats)
+				w.heartbeat(ctx, config.Config.StreamHeartbeatInterval)
+			}()
+
+			stream := &EventStream[Data]{
+				lastEventID: w.lastEventID,
+				send:        func(event Event[Data]) error { return sendEvent(w, event) },
+			}
+			err = w.finish(ctx, impl(ctx, request, stream))
+			// the response must not be written once the handler returned
+			globalStreams.close(w, cancel)
+			<-heartbeats
+			if err == nil && !w.committed {
+				return c.NoContent(http.StatusNoContent)
+			}
+			return err
+		},
+		middlewares:  middlewares,
+		timeout:      -1,
+		uncompressed: true,
+	}
+	h.callStream = func(ctx context.Context, request any, stream any) error {
+		typed, ok := request.(Request)
+		if !ok {
+			return errors.Errorf("handler '%s' expects a %T request, got %T", nameOf, typed, request)
+		}
+		events, ok := stream.(*EventStream[Data])
+		if !ok {
+			return errors.Errorf("handler '%s' expects a %T stream, got %T", nameOf, events, stream)
+		}
+		buf, err := json.Marshal(typed)
+		if err != nil {
+			return err
+		}
+		dispatchRequest(ctx, route, requestChan, buf)
+		return impl(ctx, typed, events)
+	}
+	h.reflect = func(reflector openapi3.Reflector, h Handler, security []map[string][]string) error {
+		return registerStreamOperation[Request, Data](reflector, h, security)
+	}
+	return h
+}
+
+func registerStreamOperation[Request any, Data any](reflector openapi3.Reflector, h Handler, security []map[string][]string) error {
+	op := openapi3.Operation{
+		Security: security,
+	}
+	if err := reflector.SetRequest(&op, new(Request), h.method); err != nil {
+		return err
+	}
+	// the schema documents the data of a single event
+	if err := reflector.SetupResponse(openapi3.OperationContext{
+		Operation:       &op,
+		Output:          new(Data),
+		HTTPStatus:      http.StatusOK,
+		RespContentType: mimeEventStream,
+	}); err != nil {
+		return err
+	}
+	if len(security) > 0 && len(h.scopes) > 0 {
+		op.WithMapOfAnythingItem("x-required-scopes", h.scopes)
+	}
+	return reflector.Spec.AddOperation(h.method, openapiPath(h.path), op)
+}
+
+// LastEventID returns the `Last-Event-ID` request header, the ID of the last event received by a reconnecting client.
+func (s *EventStream[Data]) LastEventID() string {
+	return s.lastEventID
+}
+
+// Send sends an Event and flushes it to the client.
+// It fails once the client disconnected, handlers should then return.
+func (s *EventStream[Data]) Send(event Event[Data]) error {
+	return s.send(event)
+}
+
+func newOpenConnections() *openConnections {
+	return &openConnections{cancels: make(map[any]context.CancelFunc)}
+}
+
+// open returns the context.Context of a connection, cancelled by closeAll
+func (s *openConnections) open(parent context.Context, conn any) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cancels[conn] = cancel
+	return ctx, cancel
+}
+
+func (s *openConnections) close(conn any, cancel context.CancelFunc) {
+	cancel()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.cancels, conn)
+}
+
+// closeAll cancels every open connection, it is invoked when a server shuts down
+func (s *openConnections) closeAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+}
+
+// count returns the number of open connections
+func (s *openConnections) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.cancels)
+}
+
+// sendEvent serializes the data of an Event to JSON, then sends it
+func sendEvent[Data any](w *eventWriter, event Event[Data]) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	return w.send(event.ID, event.Name, data)
+}
+
+func (w *eventWriter) send(id, name string, data []byte) error {
+	var buf bytes.Buffer
+	if id != "" {
+		buf.WriteString("id: " + sanitizeEventField(id) + "\n")
+	}
+	if name != "" {
+		buf.WriteString("event: " + sanitizeEventField(name) + "\n")
+	}
+	// serialized JSON never contains new lines, a single data field is enough
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	return w.write(buf.Bytes())
+}
+
+// write commits the response on first use, and flushes every write to the client
+func (w *eventWriter) write(b []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+
+	response := w.c.Response()
+	if !w.committed {
+		response.Header().Set(echo.HeaderContentType, mimeEventStream)
+		response.Header().Set(headerCacheControl, "no-cache")
+		// disables response buffering by nginx based proxies
+		response.Header().Set("X-Accel-Buffering", "no")
+		response.WriteHeader(http.StatusOK)
+		w.committed = true
+	}
+	// streams outlive config.Manifest WriteTimeout, which is applied to every write instead
+	if timeout := config.Config.WriteTimeout; timeout > 0 {
+		_ = http.NewResponseController(response.Writer).SetWriteDeadline(time.Now().Add(timeout))
+	}
+	if _, err := response.Write(b); err != nil {
+		w.err = err
+		return err
+	}
+	if err := http.NewResponseController(response.Writer).Flush(); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// heartbeat sends comments at the given interval until the context.Context is done
+func (w *eventWriter) heartbeat(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.write([]byte(": heartbeat\n\n")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// finish handles the error returned by a stream handler. Errors are returned as is until the response is committed,
+// they are then sent as a final error event instead. Streams interrupted by their context.Context end silently.
+func (w *eventWriter) finish(ctx context.Context, err error) error {
+	w.lock.Lock()
+	committed := w.committed
+	w.lock.Unlock()
+	if err == nil || !committed {
+		return err
+	}
+	// the client is gone, or will reconnect to another instance
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	log := logger.FromContext(ctx)
+	log.Error("stream handler error", zap.String("request.path", w.c.Path()), zap.Error(err))
+	data, _ := json.Marshal(errorBody(errorMessage(err)))
+	if sendErr := w.send("", errorEvent, data); sendErr != nil {
+		log.Warn("could not send stream error", zap.Error(sendErr))
+	}
+	return nil
+}
+
+// sanitizeEventField strips new lines, which would otherwise start a new field
+func sanitizeEventField(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+func TestStream(t *testing.T) {
+	a := assert.New(t)
+	e := echo.New()
+
+	serve := func(lastEventID string, impl StreamHandlerFunc[Empty, string]) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if lastEventID != "" {
+			req.Header.Set(headerLastEventID, lastEventID)
+		}
+		rec := httptest.NewRecorder()
+		h := Stream("/stream", impl)
+		return rec, h.impl(h, e.NewContext(req, rec))
+	}
+
+	rec, err := serve("41", func(_ context.Context, _ Empty, stream *EventStream[string]) error {
+		a.Equal("41", stream.LastEventID())
+		a.NoError(stream.Send(Event[string]{ID: "42", Data: "fizz"}))
+		return stream.Send(Event[string]{ID: "43\n", Name: "word", Data: "buzz"})
+	})
+	if a.NoError(err) {
+		a.Equal(http.StatusOK, rec.Code)
+		a.Equal(mimeEventStream, rec.Header().Get(echo.HeaderContentType))
+		a.Equal("no-cache", rec.Header().Get(headerCacheControl))
+		a.Equal("id: 42\ndata: \"fizz\"\n\nid: 43\nevent: word\ndata: \"buzz\"\n\n", rec.Body.String())
+	}
+
+	_, err = serve("", func(_ context.Context, _ Empty, _ *EventStream[string]) error {
+		return errors.BadRequest(nil, "invalid")
+	})
+	a.Error(err, "errors are returned as is until the first event")
+
+	rec, err = serve("", func(_ context.Context, _ Empty, stream *EventStream[string]) error {
+		a.NoError(stream.Send(Event[string]{Data: "fizz"}))
+		return errors.Unavailable(nil, "generation failed")
+	})
+	if a.NoError(err) {
+		a.Equal("data: \"fizz\"\n\nevent: error\ndata: {\"error\":\"generation failed\"}\n\n", rec.Body.String())
+	}
+
+	rec, err = serve("", func(_ context.Context, _ Empty, _ *EventStream[string]) error {
+		return nil
+	})
+	if a.NoError(err) {
+		a.Equal(http.StatusNoContent, rec.Code, "empty streams must not be resumed")
+	}
+	a.Equal(0, globalStreams.count(), "streams have not been closed")
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	a := assert.New(t)
+	rec := httptest.NewRecorder()
+	w := &eventWriter{c: echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	w.heartbeat(ctx, 10*time.Millisecond)
+	a.Equal(http.StatusOK, rec.Code)
+	a.GreaterOrEqual(strings.Count(rec.Body.String(), ": heartbeat\n\n"), 2)
+}
+
+func TestStreamShutdown(t *testing.T) {
+	a := assert.New(t)
+	e := echo.New()
+	started := make(chan struct{})
+	h := Stream("/stream", func(ctx context.Context, _ Empty, stream *EventStream[string]) error {
+		if err := stream.Send(Event[string]{Data: "fizz"}); err != nil {
+			return err
+		}
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	done := make(chan error)
+	go func() {
+		rec := httptest.NewRecorder()
+		done <- h.impl(h, e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+	}()
+	<-started
+	globalStreams.closeAll()
+	select {
+	case err := <-done:
+		a.NoError(err, "interrupted streams end silently")
+	case <-time.After(time.Second):
+		a.Fail("stream was not interrupted")
+	}
+}
@@ -0,0 +1,263 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/swaggest/openapi-go/openapi3"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+const (
+	// closeWriteTimeout bounds the duration spent sending the close frame of a session
+	closeWriteTimeout = time.Second
+	// maxCloseReasonSize is the maximum size of the reason of a close frame, as control frames are limited to 125 bytes
+	maxCloseReasonSize = 123
+)
+
+type (
+	// Session is a WebSocket connection exchanging JSON messages, it is given to SessionHandlerFunc implementations.
+	// Messages are received in order, and replies can be sent concurrently.
+	// Messages exceeding config.Manifest WebSocketMessageRate, or which cannot be deserialized, are answered with an
+	// error reply and never received.
+	Session[Message any] struct {
+		conn     *websocket.Conn
+		messages chan Message
+		// readErr is the reason the session stopped reading messages, it is set before messages is closed
+		readErr error
+		lock    sync.Mutex
+	}
+	// SessionHandlerFunc is a type for generic WebSocket handlers, see WebSocket.
+	// Implementations receive messages and send replies until they are done, or the session is closed because the
+	// client left, was idle for too long, or the server is shutting down.
+	SessionHandlerFunc[Request any, Message any] func(ctx context.Context, request Request, session *Session[Message]) error
+)
+
+var (
+	globalSessions = newOpenConnections()
+	sessionsTotal  atomic.Uint64
+)
+
+func init() {
+	metrics.RegisterGauge(semconv.MetricName("websocket", "sessions", "open"),
+		"Number of WebSocket sessions currently open.",
+		func() float64 { return float64(globalSessions.count()) })
+	metrics.RegisterCounter(semconv.MetricName("websocket", "sessions", "total"),
+		"Total number of WebSocket sessions opened.",
+		func() float64 { return float64(sessionsTotal.Load()) })
+}
+
+// WebSocket converts a generic WebSocket handler into a GET Handler upgrading connections to sessions.
+// Requests are bound, counted and documented like GenericHandler ones, while the handler implementation exchanges
+// messages through a Session. Sessions are bound by config.Manifest WebSocketIdleTimeout, WebSocketMaxMessageSize
+// and WebSocketMessageRate, but not by config.Manifest HandlerTimeout.
+// Cross origin sessions are only accepted if config.Manifest CorsEnabled is set.
+func WebSocket[Request any, Message any](route string, impl SessionHandlerFunc[Request, Message], middlewares ...echo.MiddlewareFunc) Handler {
+	fullNameOf := runtime.FuncForPC(reflect.ValueOf(impl).Pointer()).Name()
+	nameOf := path.Base(fullNameOf)
+	requestChan := metrics.NewRequestCounter(route)
+	upgrader := newUpgrader()
+
+	h := Handler{
+		operationId: nameOf,
+		path:        route,
+		method:      http.MethodGet,
+		impl: func(h Handler, c echo.Context) error {
+			var request Request
+			if err := c.Bind(&request); err != nil {
+				return err
+			}
+			buf, err := json.Marshal(request)
+			if err != nil {
+				return err
+			}
+			dispatchRequest(c.Request().Context(), route, requestChan, buf)
+
+			// failed upgrades are answered by the upgrader
+			conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+			if err != nil {
+				return nil
+			}
+			sessionsTotal.Add(1)
+			session := &Session[Message]{conn: conn, messages: make(chan Message)}
+			ctx, cancel := globalSessions.open(c.Request().Context(), session)
+
+			limit := RateLimit{Rate: config.Config.WebSocketMessageRate, Burst: config.Config.WebSocketMessageBurst}
+			var limiter *rate.Limiter
+			if limit.Rate > 0 {
+				limiter = limit.limiter()
+			}
+			reading := make(chan struct{})
+			go func() {
+				defer close(reading)
+				session.read(ctx, limiter)
+			}()
+
+			err = impl(ctx, request, session)
+			session.close(ctx, err)
+			globalSessions.close(session, cancel)
+			<-reading
+			return nil
+		},
+		middlewares:  middlewares,
+		timeout:      -1,
+		uncompressed: true,
+	}
+	h.reflect = func(reflector openapi3.Reflector, h Handler, security []map[string][]string) error {
+		return registerSessionOperation[Request](reflector, h, security)
+	}
+	return h
+}
+
+func newUpgrader() websocket.Upgrader {
+	upgrader := websocket.Upgrader{
+		Error: func(w http.ResponseWriter, _ *http.Request, status int, reason error) {
+			w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(errorBody(reason.Error()))
+		},
+	}
+	// the upgrader only accepts same origin sessions by default
+	if config.Config.CorsEnabled {
+		upgrader.CheckOrigin = func(_ *http.Request) bool { return true }
+	}
+	return upgrader
+}
+
+func registerSessionOperation[Request any](reflector openapi3.Reflector, h Handler, security []map[string][]string) error {
+	op := openapi3.Operation{
+		Security: security,
+	}
+	if err := reflector.SetRequest(&op, new(Request), h.method); err != nil {
+		return err
+	}
+	if err := reflector.SetupResponse(openapi3.OperationContext{
+		Operation:  &op,
+		HTTPStatus: http.StatusSwitchingProtocols,
+	}); err != nil {
+		return err
+	}
+	if len(security) > 0 && len(h.scopes) > 0 {
+		op.WithMapOfAnythingItem("x-required-scopes", h.scopes)
+	}
+	return reflector.Spec.AddOperation(h.method, openapiPath(h.path), op)
+}
+
+// Receive returns the next message of the client.
+// It fails once the session is closed, handlers should then return the error.
+func (s *Session[Message]) Receive(ctx context.Context) (Message, error) {
+	var message Message
+	select {
+	case <-ctx.Done():
+		return message, ctx.Err()
+	case message, ok := <-s.messages:
+		if !ok {
+			return message, s.readErr
+		}
+		return message, nil
+	}
+}
+
+// Send sends a reply serialized to JSON.
+func (s *Session[Message]) Send(reply any) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if timeout := config.Config.WriteTimeout; timeout > 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	return s.conn.WriteJSON(reply)
+}
+
+// SendError sends an error reply, using the same `{"error": "message"}` body as error responses.
+// The session is kept open, unlike when a SessionHandlerFunc returns an error.
+func (s *Session[Message]) SendError(err error) error {
+	return s.Send(errorBody(errorMessage(err)))
+}
+
+// read receives messages until the connection fails, the client is idle for too long, or ctx is done
+func (s *Session[Message]) read(ctx context.Context, limiter *rate.Limiter) {
+	defer close(s.messages)
+	s.conn.SetReadLimit(config.Config.WebSocketMaxMessageSize)
+	for {
+		if timeout := config.Config.WebSocketIdleTimeout; timeout > 0 {
+			_ = s.conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.readErr = err
+			return
+		}
+
+		if limiter != nil && !limiter.Allow() {
+			err = coreErrors.TooManyRequests(nil, "message rate limit of %g per second exceeded", float64(limiter.Limit()))
+		} else {
+			var message Message
+			if err = json.Unmarshal(data, &message); err == nil {
+				select {
+				case <-ctx.Done():
+					s.readErr = ctx.Err()
+					return
+				case s.messages <- message:
+				}
+				continue
+			}
+			err = coreErrors.BadRequest(err, "invalid message: %s", err.Error())
+		}
+		if err := s.SendError(err); err != nil {
+			s.readErr = err
+			return
+		}
+	}
+}
+
+// close sends a close frame describing why the handler returned, and closes the connection
+func (s *Session[Message]) close(ctx context.Context, err error) {
+	code, reason := websocket.CloseNormalClosure, ""
+	var closeErr *websocket.CloseError
+	var netErr net.Error
+	var httpErr *coreErrors.Error
+	switch {
+	case err == nil:
+	case errors.As(err, &closeErr) || errors.Is(err, websocket.ErrReadLimit):
+		// the client closed the session, or was already sent a close frame
+		code = 0
+	case errors.As(err, &netErr) && netErr.Timeout():
+		code, reason = websocket.CloseGoingAway, "idle timeout"
+	case ctx.Err() != nil:
+		code, reason = websocket.CloseGoingAway, "server shutting down"
+	case errors.As(err, &httpErr) && httpErr.HttpCode < http.StatusInternalServerError:
+		code, reason = websocket.ClosePolicyViolation, httpErr.Message
+	default:
+		logger.FromContext(ctx).Error("session handler error", zap.Error(err))
+		code, reason = websocket.CloseInternalServerErr, errorMessage(err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if code != 0 {
+		if len(reason) > maxCloseReasonSize {
+			reason = reason[:maxCloseReasonSize]
+		}
+		message := websocket.FormatCloseMessage(code, reason)
+		_ = s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeWriteTimeout))
+	}
+	_ = s.conn.Close()
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+type testMessage struct {
+	Text string `json:"text"`
+}
+
+// dialSession serves the given WebSocket Handler, and opens a session
+func dialSession(t *testing.T, h Handler) *websocket.Conn {
+	e := echo.New()
+	e.GET("/", func(c echo.Context) error {
+		return h.impl(h, c)
+	})
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// echoSession replies to every message with its text, failing on "fail"
+func echoSession(ctx context.Context, _ Empty, session *Session[testMessage]) error {
+	for {
+		message, err := session.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if message.Text == "fail" {
+			return errors.BadRequest(nil, "failed")
+		}
+		if err := session.Send(message); err != nil {
+			return err
+		}
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	a := assert.New(t)
+	conn := dialSession(t, WebSocket("/", echoSession))
+
+	var reply map[string]any
+	a.NoError(conn.WriteJSON(testMessage{Text: "fizz"}))
+	a.NoError(conn.ReadJSON(&reply))
+	a.Equal(map[string]any{"text": "fizz"}, reply)
+
+	a.NoError(conn.WriteMessage(websocket.TextMessage, []byte("{")))
+	a.NoError(conn.ReadJSON(&reply))
+	a.Contains(reply["error"], "invalid message", "invalid messages are answered with an error")
+
+	a.NoError(conn.WriteJSON(testMessage{Text: "fail"}))
+	_, _, err := conn.ReadMessage()
+	a.True(websocket.IsCloseError(err, websocket.ClosePolicyViolation), "%v", err)
+
+	a.Eventually(func() bool { return globalSessions.count() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestWebSocketLimits(t *testing.T) {
+	a := assert.New(t)
+	conn := dialSession(t, WebSocket("/", echoSession))
+
+	// the rate limit is checked before messages are received
+	var reply map[string]any
+	burst := int(config.Config.WebSocketMessageRate)
+	for i := 0; i <= burst; i++ {
+		a.NoError(conn.WriteJSON(testMessage{Text: "fizz"}))
+	}
+	for i := 0; i <= burst; i++ {
+		a.NoError(conn.ReadJSON(&reply))
+	}
+	a.Contains(reply["error"], "rate limit")
+
+	a.NoError(conn.WriteMessage(websocket.TextMessage, make([]byte, config.Config.WebSocketMaxMessageSize+1)))
+	_, _, err := conn.ReadMessage()
+	a.True(websocket.IsCloseError(err, websocket.CloseMessageTooBig), "%v", err)
+}
+
+func TestWebSocketShutdown(t *testing.T) {
+	a := assert.New(t)
+	conn := dialSession(t, WebSocket("/", echoSession))
+
+	var reply map[string]any
+	a.NoError(conn.WriteJSON(testMessage{Text: "fizz"}))
+	a.NoError(conn.ReadJSON(&reply))
+	globalSessions.closeAll()
+	_, _, err := conn.ReadMessage()
+	a.True(websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+}
+
+func TestWebSocketUpgradeError(t *testing.T) {
+	a := assert.New(t)
+	h := WebSocket("/", echoSession)
+	rec := httptest.NewRecorder()
+	a.NoError(h.impl(h, echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)))
+	a.Equal(http.StatusBadRequest, rec.Code, "plain requests cannot be upgraded")
+}
+
+func TestWebSocketIdleTimeout(t *testing.T) {
+	a := assert.New(t)
+	defer func(timeout time.Duration) { config.Config.WebSocketIdleTimeout = timeout }(config.Config.WebSocketIdleTimeout)
+	config.Config.WebSocketIdleTimeout = 50 * time.Millisecond
+	conn := dialSession(t, WebSocket("/", echoSession))
+
+	_, _, err := conn.ReadMessage()
+	if a.True(websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err) {
+		a.Equal("idle timeout", err.(*websocket.CloseError).Text)
+	}
+}
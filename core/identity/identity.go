@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+// Anonymous is the client identifier used whenever a request is not authenticated
+const Anonymous = "anonymous"
+
+var (
+	identityCtxKey = semconv.CtxKey("identity")
+)
+
+// Identity describes an authenticated client
+type Identity struct {
+	// ClientId uniquely identifies the client
+	ClientId string `json:"clientId"`
+	// Method is the authentication method used by the client, such as "apikey" or "jwt"
+	Method string `json:"method"`
+	// Scopes granted to the client, a nil slice means the authentication method does not restrict scopes
+	Scopes []string `json:"scopes,omitempty"`
+	// Claims of the client token, if any
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// HasScopes checks whether every given scope has been granted to the Identity.
+func (i Identity) HasScopes(scopes ...string) bool {
+	if i.Scopes == nil {
+		return true
+	}
+	for _, scope := range scopes {
+		if !generics.SliceContains(i.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Inject stores an Identity into a context.Context, to be used in conjunction with FromContext.
+func Inject(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityCtxKey, identity)
+}
+
+// FromContext fetches the Identity from within the current context.Context, ok is false if the request is anonymous.
+// Use Inject to store an Identity in any given context.Context.
+func FromContext(ctx context.Context) (identity Identity, ok bool) {
+	identity, ok = ctx.Value(identityCtxKey).(Identity)
+	return identity, ok
+}
+
+// ClientId returns the client identifier of the current context.Context, or Anonymous.
+func ClientId(ctx context.Context) string {
+	if identity, ok := FromContext(ctx); ok {
+		return identity.ClientId
+	}
+	return Anonymous
+}
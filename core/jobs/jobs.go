@@ -0,0 +1,128 @@
+// Package jobs runs long tasks in the background, on a bounded pool of workers shared by the whole instance.
+//
+// Jobs are persisted to a local directory along with their results: queued and interrupted jobs are resumed after a
+// restart, and finished jobs are kept until they expire.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+// State is the lifecycle state of a Job
+type State string
+
+const (
+	// Queued jobs are waiting for a worker
+	Queued State = "queued"
+	// Running jobs are being executed by a worker
+	Running State = "running"
+	// Succeeded jobs have a result, until they expire
+	Succeeded State = "succeeded"
+	// Failed jobs have an error message
+	Failed State = "failed"
+	// Cancelled jobs have been cancelled by their client
+	Cancelled State = "cancelled"
+)
+
+type (
+	// Job describes a background task and its progress
+	Job struct {
+		ID    string `json:"id"`
+		Kind  string `json:"kind"`
+		State State  `json:"state" enum:"queued,running,succeeded,failed,cancelled"`
+		// Client is the identifier of the client which submitted the job, the only one allowed to access it
+		Client string `json:"client"`
+		// Params are the serialized parameters of the job, as given to Submit
+		Params json.RawMessage `json:"params"`
+		// Done is the number of units processed so far, out of Total
+		Done     int64   `json:"done"`
+		Total    int64   `json:"total"`
+		Progress float64 `json:"progress"`
+		// Error is set for failed jobs
+		Error string `json:"error,omitempty"`
+		// ResultSize is the size in bytes of the result of succeeded jobs
+		ResultSize int64      `json:"resultSize,omitempty"`
+		CreatedAt  time.Time  `json:"createdAt"`
+		StartedAt  *time.Time `json:"startedAt,omitempty"`
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+		// ExpiresAt is the date after which finished jobs and their result are deleted
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	}
+	// Runner executes jobs of a given kind, writing their result to w and reporting the number of units processed so
+	// far through progress. Runners must honor ctx cancellation, and be deterministic as interrupted jobs are
+	// restarted from scratch.
+	Runner func(ctx context.Context, job Job, w io.Writer, progress func(done int64)) error
+)
+
+var globalManager *manager
+
+func init() {
+	globalManager = newManager(config.Config.JobsDir, config.Config.JobWorkers, config.Config.JobQueueSize, config.Config.JobTTL)
+
+	metrics.RegisterGauge(semconv.MetricName("jobs", "queued"),
+		"Number of jobs waiting for a worker.",
+		func() float64 { return float64(globalManager.count(Queued)) })
+	metrics.RegisterGauge(semconv.MetricName("jobs", "running"),
+		"Number of jobs currently running.",
+		func() float64 { return float64(globalManager.count(Running)) })
+	health.Register("jobs", health.Readiness, time.Second, health.WritableDir(globalManager.dir))
+}
+
+// Finished checks whether the State is final.
+func (s State) Finished() bool {
+	return s == Succeeded || s == Failed || s == Cancelled
+}
+
+// Register declares the Runner of a kind of jobs, it should be called before Start.
+func Register(kind string, runner Runner) {
+	globalManager.register(kind, runner)
+}
+
+// Start loads persisted jobs, resuming the ones which did not finish, and starts the workers.
+// Workers are stopped through Stop, not the given context.Context.
+func Start(ctx context.Context) error {
+	return globalManager.start(ctx)
+}
+
+// Stop stops the workers, jobs interrupted by a shutdown being resumed by the next Start.
+// It blocks until every worker has exited, or the given context.Context is done.
+func Stop(ctx context.Context) error {
+	return globalManager.stop(ctx)
+}
+
+// Submit queues a new Job of a registered kind on behalf of the client of the context.Context.
+// Params are serialized to JSON, and total is the number of units the Job has to process.
+func Submit(ctx context.Context, kind string, params any, total int64) (Job, error) {
+	return globalManager.submit(ctx, kind, params, total)
+}
+
+// Get returns a Job submitted by the client of the context.Context.
+func Get(ctx context.Context, id string) (Job, error) {
+	return globalManager.get(ctx, id)
+}
+
+// Cancel cancels a queued or running Job submitted by the client of the context.Context.
+// Running jobs are cancelled asynchronously, and returned as such until their Runner returns.
+func Cancel(ctx context.Context, id string) (Job, error) {
+	return globalManager.cancelJob(ctx, id)
+}
+
+// Delete removes a finished Job submitted by the client of the context.Context, along with its result.
+func Delete(ctx context.Context, id string) error {
+	return globalManager.delete(ctx, id)
+}
+
+// Open opens the result of a succeeded Job submitted by the client of the context.Context.
+// The caller is responsible for closing the returned file.
+func Open(ctx context.Context, id string) (*os.File, Job, error) {
+	return globalManager.open(ctx, id)
+}
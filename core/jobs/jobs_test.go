@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+// countRunner writes the numbers from 1 to the Total of the job, blocking on the given channel before each number
+func countRunner(step <-chan struct{}) Runner {
+	return func(ctx context.Context, job Job, w io.Writer, progress func(done int64)) error {
+		for i := int64(1); i <= job.Total; i++ {
+			if step != nil {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-step:
+				}
+			}
+			if _, err := fmt.Fprintln(w, i); err != nil {
+				return err
+			}
+			progress(i)
+		}
+		return nil
+	}
+}
+
+func statusOf(err error) int {
+	if httpErr, ok := err.(*errors.Error); ok {
+		return httpErr.HttpCode
+	}
+	return 0
+}
+
+func waitFor(t *testing.T, m *manager, id string, state State) Job {
+	var job Job
+	assert.Eventually(t, func() bool {
+		var err error
+		job, err = m.get(context.Background(), id)
+		return err == nil && job.State == state
+	}, 5*time.Second, 5*time.Millisecond, "job never reached state %s", state)
+	return job
+}
+
+func TestJobs(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	m := newManager(t.TempDir(), 1, 1, time.Hour)
+	m.register("count", countRunner(nil))
+	step := make(chan struct{})
+	m.register("blocking", countRunner(step))
+	a.NoError(m.start(ctx))
+	defer func() { a.NoError(m.stop(ctx)) }()
+
+	job, err := m.submit(ctx, "count", nil, 3)
+	if a.NoError(err) {
+		job = waitFor(t, m, job.ID, Succeeded)
+		a.Equal(int64(3), job.Done)
+		a.Equal(1.0, job.Progress)
+		a.NotNil(job.ExpiresAt)
+
+		f, _, err := m.open(ctx, job.ID)
+		if a.NoError(err) {
+			buf, _ := io.ReadAll(f)
+			a.Equal("1\n2\n3\n", string(buf))
+			a.NoError(f.Close())
+		}
+		_, err = m.cancelJob(ctx, job.ID)
+		a.Equal(http.StatusConflict, statusOf(err))
+	}
+
+	running, err := m.submit(ctx, "blocking", nil, 10)
+	a.NoError(err)
+	step <- struct{}{}
+	// the worker is busy, so this job stays queued and fills the queue
+	queued, err := m.submit(ctx, "count", nil, 1)
+	a.NoError(err)
+	_, err = m.submit(ctx, "count", nil, 1)
+	a.Equal(http.StatusServiceUnavailable, statusOf(err), "the queue is full")
+
+	job = waitFor(t, m, running.ID, Running)
+	a.Equal(int64(1), job.Done)
+	_, _, err = m.open(ctx, running.ID)
+	a.Equal(http.StatusConflict, statusOf(err))
+	a.Equal(http.StatusConflict, statusOf(m.delete(ctx, running.ID)))
+
+	job, err = m.cancelJob(ctx, queued.ID)
+	if a.NoError(err) {
+		a.Equal(Cancelled, job.State)
+	}
+	_, err = m.cancelJob(ctx, running.ID)
+	a.NoError(err)
+	waitFor(t, m, running.ID, Cancelled)
+
+	a.NoError(m.delete(ctx, running.ID))
+	_, err = m.get(ctx, running.ID)
+	a.Equal(http.StatusNotFound, statusOf(err))
+}
+
+func TestJobsRestart(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	step := make(chan struct{})
+	m := newManager(dir, 1, 10, time.Hour)
+	m.register("count", countRunner(step))
+	a.NoError(m.start(ctx))
+
+	interrupted, err := m.submit(ctx, "count", nil, 2)
+	a.NoError(err)
+	queued, err := m.submit(ctx, "count", nil, 2)
+	a.NoError(err)
+	step <- struct{}{}
+	waitFor(t, m, interrupted.ID, Running)
+	a.NoError(m.stop(ctx))
+
+	// both jobs are resumed from scratch by the next instance
+	m = newManager(dir, 1, 10, time.Hour)
+	m.register("count", countRunner(nil))
+	a.NoError(m.start(ctx))
+	defer func() { a.NoError(m.stop(ctx)) }()
+	for _, id := range []string{interrupted.ID, queued.ID} {
+		job := waitFor(t, m, id, Succeeded)
+		a.Equal(int64(len("1\n2\n")), job.ResultSize)
+	}
+
+	// expired jobs are deleted along with their result
+	m.lock.Lock()
+	m.ttl = -time.Second
+	m.lock.Unlock()
+	job, err := m.submit(ctx, "count", nil, 1)
+	a.NoError(err)
+	waitFor(t, m, job.ID, Succeeded)
+	m.expire()
+	_, err = m.get(ctx, job.ID)
+	a.Equal(http.StatusNotFound, statusOf(err))
+	_, err = m.get(ctx, queued.ID)
+	a.NoError(err, "jobs which did not expire are kept")
+}
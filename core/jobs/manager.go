@@ -0,0 +1,494 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	// idBytes is the number of random bytes of job identifiers
+	idBytes = 16
+	// janitorInterval is the interval between two deletions of expired jobs
+	janitorInterval = time.Minute
+	// resultBufferSize is the size of the buffer results are written through
+	resultBufferSize = 256 * 1024
+
+	stateExt  = ".json"
+	resultExt = ".result"
+	tmpExt    = ".tmp"
+)
+
+type (
+	// entry is a Job known by the manager, its progress being updated without holding the manager lock
+	entry struct {
+		job    Job
+		done   atomic.Int64
+		cancel context.CancelFunc
+	}
+	manager struct {
+		dir     string
+		workers int
+		ttl     time.Duration
+
+		lock    sync.Mutex
+		runners map[string]Runner
+		entries map[string]*entry
+		queue   chan string
+		cancel  context.CancelFunc
+		group   sync.WaitGroup
+	}
+)
+
+func newManager(dir string, workers, queueSize int, ttl time.Duration) *manager {
+	return &manager{
+		dir:     dir,
+		workers: workers,
+		ttl:     ttl,
+		runners: make(map[string]Runner),
+		entries: make(map[string]*entry),
+		queue:   make(chan string, queueSize),
+	}
+}
+
+func newID() (string, error) {
+	buf := make([]byte, idBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// snapshot returns a copy of the Job along with its current progress, it expects the lock to be held
+func (e *entry) snapshot() Job {
+	job := e.job
+	if job.State == Running {
+		job.Done = e.done.Load()
+	}
+	if job.Total > 0 {
+		job.Progress = float64(job.Done) / float64(job.Total)
+	} else if job.State == Succeeded {
+		job.Progress = 1
+	}
+	return job
+}
+
+func (m *manager) path(id, ext string) string {
+	return filepath.Join(m.dir, id+ext)
+}
+
+// persist atomically replaces the state file of a Job
+func (m *manager) persist(job Job) error {
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	tmp := m.path(job.ID, stateExt+tmpExt)
+	if err := os.WriteFile(tmp, buf, 0o640); err != nil {
+		return errors.Wrapf(err, "could not write job '%s'", job.ID)
+	}
+	if err := os.Rename(tmp, m.path(job.ID, stateExt)); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrapf(err, "could not write job '%s'", job.ID)
+	}
+	return nil
+}
+
+// remove deletes every file of a Job
+func (m *manager) remove(id string) {
+	for _, ext := range []string{stateExt, resultExt, resultExt + tmpExt} {
+		if err := os.Remove(m.path(id, ext)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.New().Warn("could not delete job file", zap.String("job.id", id), zap.Error(err))
+		}
+	}
+}
+
+func (m *manager) register(kind string, runner Runner) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.runners[kind] = runner
+}
+
+func (m *manager) count(state State) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	count := 0
+	for _, e := range m.entries {
+		if e.job.State == state {
+			count++
+		}
+	}
+	return count
+}
+
+// load reads persisted jobs, deleting expired ones and queuing the ones which did not finish
+func (m *manager) load(log *zap.Logger) error {
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return errors.Wrapf(err, "could not create jobs directory '%s'", m.dir)
+	}
+	files, err := os.ReadDir(m.dir)
+	if err != nil {
+		return errors.Wrapf(err, "could not read jobs directory '%s'", m.dir)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	resumed := 0
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, tmpExt) {
+			_ = os.Remove(filepath.Join(m.dir, name))
+			continue
+		}
+		id, ok := strings.CutSuffix(name, stateExt)
+		if !ok {
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(m.dir, name))
+		var job Job
+		if err == nil {
+			err = json.Unmarshal(buf, &job)
+		}
+		if err != nil || job.ID != id {
+			log.Warn("invalid job file", zap.String("file", name), zap.Error(err))
+			continue
+		}
+
+		if job.ExpiresAt != nil && job.ExpiresAt.Before(now) {
+			m.remove(id)
+			continue
+		}
+		e := &entry{job: job}
+		if !job.State.Finished() {
+			e.job.State, e.job.StartedAt, e.job.Done = Queued, nil, 0
+			select {
+			case m.queue <- id:
+				resumed++
+			default:
+				m.finish(e, Failed, "the job queue was full when the job was resumed")
+			}
+		}
+		m.entries[id] = e
+	}
+	log.Info("jobs loaded", zap.Int("jobs", len(m.entries)), zap.Int("resumed", resumed))
+	return nil
+}
+
+func (m *manager) start(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	if err := m.load(log); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	for i := 0; i < m.workers; i++ {
+		m.group.Add(1)
+		go func() {
+			defer m.group.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-m.queue:
+					m.run(ctx, id)
+				}
+			}
+		}()
+	}
+
+	m.group.Add(1)
+	go func() {
+		defer m.group.Done()
+		ticker := time.NewTicker(janitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.expire()
+			}
+		}
+	}()
+	return nil
+}
+
+func (m *manager) stop(ctx context.Context) error {
+	m.lock.Lock()
+	cancel := m.cancel
+	m.lock.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.group.Wait()
+		close(done)
+	}()
+	select {
+	case <-ctx.Done():
+		return coreErrors.Unavailable(ctx.Err(), "job workers did not stop in time")
+	case <-done:
+		m.lock.Lock()
+		m.cancel = nil
+		m.lock.Unlock()
+		return nil
+	}
+}
+
+// expire deletes finished jobs whose time to live is exceeded
+func (m *manager) expire() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	for id, e := range m.entries {
+		if e.job.ExpiresAt != nil && e.job.ExpiresAt.Before(now) {
+			m.remove(id)
+			delete(m.entries, id)
+		}
+	}
+}
+
+// finish moves a Job to a final state and persists it, it expects the lock to be held
+func (m *manager) finish(e *entry, state State, message string) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(m.ttl)
+	e.job.State, e.job.Error = state, message
+	e.job.Done = e.done.Load()
+	e.job.FinishedAt, e.job.ExpiresAt = &now, &expiresAt
+	if err := m.persist(e.job); err != nil {
+		logger.New().Error("could not persist job", zap.String("job.id", e.job.ID), zap.Error(err))
+	}
+}
+
+// run executes a queued Job, jobs interrupted by stop are queued again so that they are resumed on restart
+func (m *manager) run(ctx context.Context, id string) {
+	m.lock.Lock()
+	e, ok := m.entries[id]
+	if !ok || e.job.State != Queued {
+		m.lock.Unlock()
+		return
+	}
+	runner := m.runners[e.job.Kind]
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	now := time.Now().UTC()
+	e.cancel = cancel
+	e.job.State, e.job.StartedAt = Running, &now
+	e.done.Store(0)
+	job := e.job
+	if err := m.persist(job); err != nil {
+		logger.New().Error("could not persist job", zap.String("job.id", id), zap.Error(err))
+	}
+	m.lock.Unlock()
+
+	log := logger.New().With(zap.String("job.id", id), zap.String("job.kind", job.Kind))
+	log.Info("job started")
+
+	var size int64
+	err := errors.Errorf("unknown job kind '%s'", job.Kind)
+	if runner != nil {
+		size, err = m.execute(jobCtx, runner, job, e)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	e.cancel = nil
+
+	switch {
+	case err == nil:
+		e.job.ResultSize = size
+		m.finish(e, Succeeded, "")
+		log.Info("job succeeded", zap.Int64("job.size", size))
+	case ctx.Err() != nil:
+		e.job.State, e.job.StartedAt = Queued, nil
+		if err := m.persist(e.job); err != nil {
+			log.Error("could not persist job", zap.Error(err))
+		}
+		log.Info("job interrupted by shutdown")
+	case jobCtx.Err() != nil:
+		m.finish(e, Cancelled, "")
+		log.Info("job cancelled")
+	default:
+		message := "internal server error"
+		if httpErr, ok := err.(*coreErrors.Error); ok {
+			message = httpErr.Message
+		}
+		m.finish(e, Failed, message)
+		log.Error("job failed", zap.Error(err))
+	}
+}
+
+// execute runs a Job, writing its result to a temporary file which is renamed once complete
+func (m *manager) execute(ctx context.Context, runner Runner, job Job, e *entry) (int64, error) {
+	tmp := m.path(job.ID, resultExt+tmpExt)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not create result of job '%s'", job.ID)
+	}
+	w := bufio.NewWriterSize(f, resultBufferSize)
+
+	err = runner(ctx, job, w, e.done.Store)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	var size int64
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(tmp); err == nil {
+			size = info.Size()
+			err = os.Rename(tmp, m.path(job.ID, resultExt))
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+	return size, nil
+}
+
+func (m *manager) submit(ctx context.Context, kind string, params any, total int64) (Job, error) {
+	buf, err := json.Marshal(params)
+	if err != nil {
+		return Job{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.runners[kind]; !ok {
+		return Job{}, errors.Errorf("unknown job kind '%s'", kind)
+	}
+	if len(m.queue) == cap(m.queue) {
+		return Job{}, coreErrors.Unavailable(nil, "the job queue is full, try again later")
+	}
+	e := &entry{job: Job{
+		ID:        id,
+		Kind:      kind,
+		State:     Queued,
+		Client:    identity.ClientId(ctx),
+		Params:    buf,
+		Total:     total,
+		CreatedAt: time.Now().UTC(),
+	}}
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return Job{}, errors.Wrapf(err, "could not create jobs directory '%s'", m.dir)
+	}
+	if err := m.persist(e.job); err != nil {
+		return Job{}, err
+	}
+	// the queue cannot be full, as it is only filled while holding the lock
+	m.queue <- id
+	m.entries[id] = e
+	return e.snapshot(), nil
+}
+
+// lookup returns an entry of the client of the context.Context, it expects the lock to be held
+func (m *manager) lookup(ctx context.Context, id string) (*entry, error) {
+	e, ok := m.entries[id]
+	if !ok || e.job.Client != identity.ClientId(ctx) {
+		return nil, coreErrors.NotFound()
+	}
+	return e, nil
+}
+
+func (m *manager) get(ctx context.Context, id string) (Job, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, err := m.lookup(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	return e.snapshot(), nil
+}
+
+func (m *manager) cancelJob(ctx context.Context, id string) (Job, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, err := m.lookup(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	switch e.job.State {
+	case Queued:
+		// the job is skipped once dequeued
+		m.finish(e, Cancelled, "")
+	case Running:
+		e.cancel()
+	default:
+		return Job{}, coreErrors.Conflict(nil, "job '%s' is already %s", id, e.job.State)
+	}
+	return e.snapshot(), nil
+}
+
+func (m *manager) delete(ctx context.Context, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, err := m.lookup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !e.job.State.Finished() {
+		return coreErrors.Conflict(nil, "job '%s' is %s, it must be cancelled first", id, e.job.State)
+	}
+	m.remove(id)
+	delete(m.entries, id)
+	return nil
+}
+
+func (m *manager) open(ctx context.Context, id string) (*os.File, Job, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, err := m.lookup(ctx, id)
+	if err != nil {
+		return nil, Job{}, err
+	}
+	if e.job.State != Succeeded {
+		return nil, Job{}, coreErrors.Conflict(nil, "job '%s' is %s, its result is not available", id, e.job.State)
+	}
+	f, err := os.Open(m.path(id, resultExt))
+	if err != nil {
+		return nil, Job{}, errors.Wrapf(err, "could not open result of job '%s'", id)
+	}
+	return f, e.snapshot(), nil
+}
@@ -1,13 +1,52 @@
 package logger
 
 import (
+	"net/url"
+	"strings"
+
 	"github.com/brpaz/echozap"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
 )
 
 // HttpMiddleware is a thin layer around `github.com/brpaz/echozap`, allowing us to use the application
 // logger layer `zap` instead of labstack's `gommon`.
+// Request URIs are logged through RedactURI, the redacted URI replaces the request one for the rest of the chain.
 func HttpMiddleware(opts ...zap.Option) echo.MiddlewareFunc {
-	return echozap.ZapLogger(New(opts...))
+	accessLog := echozap.ZapLogger(New(opts...))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		logged := accessLog(next)
+		return func(c echo.Context) error {
+			// handlers read query parameters from the request URL, the raw URI is only used for logging
+			c.Request().RequestURI = RedactURI(c.Request().RequestURI)
+			return logged(c)
+		}
+	}
+}
+
+// RedactURI masks the value of the config.Manifest ApiKeyQuery parameter of a request URI, so that API keys are not
+// leaked through logs.
+func RedactURI(uri string) string {
+	path, query, ok := strings.Cut(uri, "?")
+	if !ok || config.Config.ApiKeyQuery == "" {
+		return uri
+	}
+	params := strings.Split(query, "&")
+	redacted := false
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if key == config.Config.ApiKeyQuery {
+			params[i] = key + "=<redacted>"
+			redacted = true
+		}
+	}
+	if !redacted {
+		return uri
+	}
+	return path + "?" + strings.Join(params, "&")
 }
@@ -0,0 +1,21 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactURI(t *testing.T) {
+	a := assert.New(t)
+	for uri, expected := range map[string]string{
+		"/api/v1/fizzbuzz":                          "/api/v1/fizzbuzz",
+		"/api/v1/fizzbuzz?int1=3":                   "/api/v1/fizzbuzz?int1=3",
+		"/api/v1/fizzbuzz?api_key=secret":           "/api/v1/fizzbuzz?api_key=<redacted>",
+		"/api/v1/fizzbuzz?int1=3&api_key=s&limit=5": "/api/v1/fizzbuzz?int1=3&api_key=<redacted>&limit=5",
+		"/api/v1/fizzbuzz?api%5Fkey=secret":         "/api/v1/fizzbuzz?api_key=<redacted>",
+		"/api/v1/fizzbuzz?api_key=a&api_key=b":      "/api/v1/fizzbuzz?api_key=<redacted>&api_key=<redacted>",
+	} {
+		a.Equal(expected, RedactURI(uri), uri)
+	}
+}
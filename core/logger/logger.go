@@ -5,6 +5,7 @@ import (
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/Raphy42/industrial-fizz-buzz/core/config"
 	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
@@ -12,10 +13,28 @@ import (
 
 var (
 	zapOptionsCtxKey = semconv.CtxKey("zap", "options")
+	// level is shared by every logger, so it can be changed at runtime through SetLevel
+	level = zap.NewAtomicLevelAt(defaultLevel())
 )
 
+func defaultLevel() zapcore.Level {
+	if config.Config.LogLevel != "" {
+		lvl, err := zapcore.ParseLevel(config.Config.LogLevel)
+		if err != nil {
+			panic(errors.Wrapf(err, "invalid log level '%s'", config.Config.LogLevel))
+		}
+		return lvl
+	}
+	if config.Config.IsProd() {
+		return zapcore.InfoLevel
+	}
+	return zapcore.DebugLevel
+}
+
 func developmentLogger(opts ...zap.Option) *zap.Logger {
-	log, err := zap.NewDevelopment(opts...)
+	cfg := zap.NewDevelopmentConfig()
+	cfg.Level = level
+	log, err := cfg.Build(opts...)
 	if err != nil {
 		panic(errors.Wrapf(err, "development logger initialisation failed"))
 	}
@@ -23,13 +42,25 @@ func developmentLogger(opts ...zap.Option) *zap.Logger {
 }
 
 func productionLogger(opts ...zap.Option) *zap.Logger {
-	log, err := zap.NewProduction(opts...)
+	cfg := zap.NewProductionConfig()
+	cfg.Level = level
+	log, err := cfg.Build(opts...)
 	if err != nil {
 		panic(errors.Wrapf(err, "production logger initialisation failed"))
 	}
 	return log
 }
 
+// Level returns the current minimum enabled level of every logger.
+func Level() zapcore.Level {
+	return level.Level()
+}
+
+// SetLevel changes the minimum enabled level of every logger, including already instantiated ones.
+func SetLevel(lvl zapcore.Level) {
+	level.SetLevel(lvl)
+}
+
 // New returns a preconfigured zap.Logger using production or development config, depending on the `mode` environment
 // variable.
 // See also config.Config
@@ -41,7 +72,11 @@ func New(opts ...zap.Option) *zap.Logger {
 }
 
 // Inject stores zap.Option into a context.Context, to be used in conjunction with FromContext.
+// Options already stored in the context.Context are preserved.
 func Inject(ctx context.Context, opts ...zap.Option) context.Context {
+	if previous, ok := ctx.Value(zapOptionsCtxKey).([]zap.Option); ok {
+		opts = append(append([]zap.Option{}, previous...), opts...)
+	}
 	return context.WithValue(ctx, zapOptionsCtxKey, opts)
 }
 
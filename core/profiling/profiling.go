@@ -0,0 +1,160 @@
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+type (
+	// Kind is the kind of profile which can be captured
+	Kind string
+	// Profile describes a captured profile file
+	Profile struct {
+		Kind      Kind      `json:"kind"`
+		Path      string    `json:"path"`
+		Size      int64     `json:"size"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+)
+
+const (
+	// CPU profiles are sampled for a given duration
+	CPU Kind = "cpu"
+	// Heap profiles are a snapshot of live heap allocations
+	Heap Kind = "heap"
+	// Goroutine profiles are a snapshot of every goroutine stack
+	Goroutine Kind = "goroutine"
+)
+
+// cpuLock guards CPU profiling, as the runtime only supports one CPU profile at a time
+var cpuLock sync.Mutex
+
+// Capture writes a new profile of the given Kind in the configured profiling directory.
+// CPU profiles are sampled for the given duration, which is ignored for snapshot profiles.
+// The capture runs in the background, and the returned Profile is complete once the done channel is closed.
+func Capture(kind Kind, duration time.Duration) (Profile, <-chan struct{}, error) {
+	if err := os.MkdirAll(config.Config.ProfilingDir, 0o750); err != nil {
+		return Profile{}, nil, errors.Wrapf(err, "could not create profiling directory '%s'", config.Config.ProfilingDir)
+	}
+
+	profile := Profile{
+		Kind:      kind,
+		CreatedAt: time.Now().UTC(),
+	}
+	name := fmt.Sprintf("%s-%s.pprof", kind, profile.CreatedAt.Format("20060102T150405.000000000"))
+	profile.Path = filepath.Join(config.Config.ProfilingDir, name)
+
+	var capture func(f *os.File) error
+	switch kind {
+	case CPU:
+		if duration <= 0 || duration > config.Config.ProfilingMaxDuration {
+			return Profile{}, nil, coreErrors.BadRequest(nil, "cpu profile duration must be within ]0, %s]", config.Config.ProfilingMaxDuration)
+		}
+		if !cpuLock.TryLock() {
+			return Profile{}, nil, coreErrors.Unavailable(nil, "a cpu profile is already being captured")
+		}
+		capture = func(f *os.File) error {
+			defer cpuLock.Unlock()
+			if err := pprof.StartCPUProfile(f); err != nil {
+				return err
+			}
+			time.Sleep(duration)
+			pprof.StopCPUProfile()
+			return nil
+		}
+	case Heap:
+		capture = func(f *os.File) error {
+			runtime.GC()
+			return pprof.Lookup("heap").WriteTo(f, 0)
+		}
+	case Goroutine:
+		capture = func(f *os.File) error {
+			return pprof.Lookup("goroutine").WriteTo(f, 0)
+		}
+	default:
+		return Profile{}, nil, coreErrors.BadRequest(nil, "unknown profile kind '%s'", kind)
+	}
+
+	f, err := os.Create(profile.Path)
+	if err != nil {
+		if kind == CPU {
+			cpuLock.Unlock()
+		}
+		return Profile{}, nil, errors.Wrapf(err, "could not create profile file '%s'", profile.Path)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		log := logger.New().With(zap.String("profile.kind", string(kind)), zap.String("profile.path", profile.Path))
+
+		err := capture(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Error("profile capture failed", zap.Error(err))
+			_ = os.Remove(profile.Path)
+			return
+		}
+		log.Info("profile captured")
+	}()
+	return profile, done, nil
+}
+
+// List returns every profile found in the configured profiling directory, sorted from newest to oldest.
+func List(_ context.Context) ([]Profile, error) {
+	entries, err := os.ReadDir(config.Config.ProfilingDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Profile{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read profiling directory '%s'", config.Config.ProfilingDir)
+	}
+
+	profiles := make([]Profile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pprof" {
+			continue
+		}
+		kind, _, ok := strings.Cut(entry.Name(), "-")
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		profiles = append(profiles, Profile{
+			Kind:      Kind(kind),
+			Path:      filepath.Join(config.Config.ProfilingDir, entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime().UTC(),
+		})
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].CreatedAt.After(profiles[j].CreatedAt)
+	})
+	return profiles, nil
+}
+
+// WriteGoroutines writes a human-readable dump of every goroutine stack.
+func WriteGoroutines(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
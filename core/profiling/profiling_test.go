@@ -0,0 +1,37 @@
+package profiling
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+func TestCapture(t *testing.T) {
+	a := assert.New(t)
+	config.Config.ProfilingDir = t.TempDir()
+
+	profile, done, err := Capture(Heap, 0)
+	if !a.NoError(err) {
+		return
+	}
+	<-done
+
+	profiles, err := List(context.Background())
+	if a.NoError(err) && a.Len(profiles, 1) {
+		a.Equal(Heap, profiles[0].Kind)
+		a.Equal(profile.Path, profiles[0].Path)
+		a.NotZero(profiles[0].Size, "profile file is empty")
+	}
+
+	_, _, err = Capture(CPU, time.Hour)
+	httpErr, ok := err.(*errors.Error)
+	if a.Truef(ok, "error is not a valid *errors.Error") {
+		a.Equal(http.StatusBadRequest, httpErr.HttpCode, "out of bounds cpu profile duration should be rejected")
+	}
+}
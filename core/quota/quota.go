@@ -0,0 +1,136 @@
+package quota
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+type (
+	// Quota is a daily budget of units consumed by clients, reset every day at midnight UTC.
+	// A limit of 0 means unlimited, usage is still tracked.
+	Quota struct {
+		lock      sync.Mutex
+		name      string
+		limit     uint64
+		overrides map[string]uint64
+		day       time.Time
+		usage     map[string]uint64
+	}
+	// Status represents the current state of a Quota for a given client
+	Status struct {
+		Name string `json:"name"`
+		// Limit is the daily budget, 0 means unlimited
+		Limit     uint64    `json:"limit"`
+		Used      uint64    `json:"used"`
+		Remaining uint64    `json:"remaining"`
+		ResetsAt  time.Time `json:"resetsAt"`
+	}
+)
+
+var (
+	lock   sync.RWMutex
+	quotas = make(map[string]*Quota)
+)
+
+// now is overridden in tests
+var now = time.Now
+
+// New declares a named Quota with a default daily limit, and optional limits by client identifier.
+// Declaring a Quota with an already existing name replaces it.
+func New(name string, limit uint64, overrides map[string]uint64) *Quota {
+	q := &Quota{
+		name:      name,
+		limit:     limit,
+		overrides: overrides,
+		usage:     make(map[string]uint64),
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	quotas[name] = q
+	return q
+}
+
+// StatusOf returns the Status of every declared Quota for the client of the current context.Context, sorted by name.
+func StatusOf(ctx context.Context) []Status {
+	lock.RLock()
+	declared := generics.MapValues(quotas)
+	lock.RUnlock()
+	sort.Slice(declared, func(i, j int) bool {
+		return declared[i].name < declared[j].name
+	})
+
+	client := identity.ClientId(ctx)
+	statuses := make([]Status, len(declared))
+	for idx, q := range declared {
+		statuses[idx] = q.Status(client)
+	}
+	return statuses
+}
+
+// rollover resets usage whenever a new UTC day started, it expects the lock to be held
+func (q *Quota) rollover() {
+	today := now().UTC().Truncate(24 * time.Hour)
+	if !today.Equal(q.day) {
+		q.day = today
+		q.usage = make(map[string]uint64)
+	}
+}
+
+func (q *Quota) limitOf(client string) uint64 {
+	if limit, ok := q.overrides[client]; ok {
+		return limit
+	}
+	return q.limit
+}
+
+func (q *Quota) status(client string) Status {
+	limit := q.limitOf(client)
+	used := q.usage[client]
+	remaining := uint64(0)
+	if limit > used {
+		remaining = limit - used
+	}
+	return Status{
+		Name:      q.name,
+		Limit:     limit,
+		Used:      used,
+		Remaining: remaining,
+		ResetsAt:  q.day.Add(24 * time.Hour),
+	}
+}
+
+// Consume takes amount units from the budget of the client of the current context.Context.
+// Nothing is consumed if the remaining budget is insufficient, and a 429 error is returned instead.
+func (q *Quota) Consume(ctx context.Context, amount uint64) error {
+	client := identity.ClientId(ctx)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.rollover()
+
+	if limit := q.limitOf(client); limit > 0 && q.usage[client]+amount > limit {
+		status := q.status(client)
+		return errors.TooManyRequests(nil,
+			"daily '%s' quota exceeded: %d requested, %d remaining out of %d, resets at %s",
+			q.name, amount, status.Remaining, status.Limit, status.ResetsAt.Format(time.RFC3339),
+		)
+	}
+	q.usage[client] += amount
+	return nil
+}
+
+// Status returns the current Status of the Quota for a given client identifier.
+func (q *Quota) Status(client string) Status {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.rollover()
+
+	return q.status(client)
+}
@@ -0,0 +1,44 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+)
+
+func TestQuotaConsume(t *testing.T) {
+	a := assert.New(t)
+	today := time.Date(2023, 5, 1, 23, 0, 0, 0, time.UTC)
+	now = func() time.Time { return today }
+	defer func() { now = time.Now }()
+
+	q := New("test.items", 10, map[string]uint64{"vip": 0})
+	ctx := identity.Inject(context.Background(), identity.Identity{ClientId: "loadgen"})
+	vip := identity.Inject(context.Background(), identity.Identity{ClientId: "vip"})
+
+	a.NoError(q.Consume(ctx, 6))
+	err := q.Consume(ctx, 6)
+	httpErr, ok := err.(*errors.Error)
+	if a.Truef(ok, "error is not a valid *errors.Error") {
+		a.Equal(http.StatusTooManyRequests, httpErr.HttpCode)
+	}
+	a.Equal(Status{
+		Name:      "test.items",
+		Limit:     10,
+		Used:      6,
+		Remaining: 4,
+		ResetsAt:  time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC),
+	}, q.Status("loadgen"), "rejected consumption should not be counted")
+
+	a.NoError(q.Consume(vip, 1000), "overridden unlimited quota should not be enforced")
+	a.Equal(uint64(0), q.Status("anonymous").Used, "quotas are tracked by client")
+
+	today = today.Add(2 * time.Hour)
+	a.NoError(q.Consume(ctx, 10), "quota should be reset the next day")
+}
@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"context"
+	nethttp "net/http"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+// statusCodes maps the HTTP status of core errors to gRPC codes, following the gRPC HTTP mapping
+var statusCodes = map[int]codes.Code{
+	nethttp.StatusBadRequest:            codes.InvalidArgument,
+	nethttp.StatusUnauthorized:          codes.Unauthenticated,
+	nethttp.StatusForbidden:             codes.PermissionDenied,
+	nethttp.StatusNotFound:              codes.NotFound,
+	nethttp.StatusConflict:              codes.Aborted,
+	nethttp.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	nethttp.StatusUnsupportedMediaType:  codes.InvalidArgument,
+	nethttp.StatusTooManyRequests:       codes.ResourceExhausted,
+	nethttp.StatusNotImplemented:        codes.Unimplemented,
+	nethttp.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// toStatus converts an error returned by a service into a gRPC status error.
+// Errors of the core/errors package keep their message, while the details of internal errors are hidden from clients
+// in production, like http.ErrorHandler does.
+func toStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var httpErr *coreErrors.Error
+	if errors.As(err, &httpErr) {
+		code, ok := statusCodes[httpErr.HttpCode]
+		if !ok {
+			code = codes.Internal
+		}
+		return status.Error(code, httpErr.Message)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		return status.Error(codes.Canceled, err.Error())
+	}
+	if config.Config.IsProd() {
+		return status.Error(codes.Internal, "internal server error")
+	}
+	return status.Error(codes.Internal, err.Error())
+}
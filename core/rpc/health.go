@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
+)
+
+const (
+	// LivenessService is the name of the health checking service following the health.Liveness probe, every other
+	// service following the health.Readiness probe
+	LivenessService = "liveness"
+	// healthWatchInterval is the interval between two probes of a health watch
+	healthWatchInterval = time.Second
+)
+
+// healthServer implements the gRPC health checking protocol on top of health probes. The overall status, named by
+// an empty service, and the status of every registered service follow the readiness probe.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	probes map[string]health.Probe
+}
+
+func newHealthServer(services map[string]grpc.ServiceInfo) *healthServer {
+	probes := map[string]health.Probe{
+		"":              health.Readiness,
+		LivenessService: health.Liveness,
+	}
+	for service := range services {
+		probes[service] = health.Readiness
+	}
+	return &healthServer{probes: probes}
+}
+
+// Check runs the probe of a service
+func (h *healthServer) Check(ctx context.Context, request *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	probe, ok := h.probes[request.GetService()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service '%s'", request.GetService())
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: runProbe(ctx, probe)}, nil
+}
+
+// Watch runs the probe of a service every healthWatchInterval, sending its status whenever it changes.
+// Unknown services are reported as such and the stream stays open, as they could be registered later on according to
+// the protocol.
+func (h *healthServer) Watch(request *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	ctx := stream.Context()
+	probe, ok := h.probes[request.GetService()]
+	if !ok {
+		if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN}); err != nil {
+			return err
+		}
+		// services are registered once and for all, the status of an unknown service never changes
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+	last := grpc_health_v1.HealthCheckResponse_UNKNOWN
+	for {
+		if current := runProbe(ctx, probe); current != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func runProbe(ctx context.Context, probe health.Probe) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if health.Run(ctx, probe).Status == health.StatusOk {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
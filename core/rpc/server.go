@@ -0,0 +1,308 @@
+// Package rpc serves gRPC services alongside the HTTP API.
+//
+// Calls are authenticated and rate limited by the http.Server they share the process with, see http.Server.Guard,
+// and services are expected to invoke the HTTP handlers through http.Call and http.CallStream so that requests are
+// validated, authorized and counted the same way whatever the transport. Errors of the core/errors package are
+// converted to their gRPC status code counterparts.
+//
+// The Server implements http.Service, it is started and gracefully stopped along with the http.Server. It also
+// implements the gRPC health checking protocol on top of the health probes.
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	nethttp "net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+const (
+	// headerRequestID carries the identifier of a call, generated unless set by the client
+	headerRequestID = "x-request-id"
+	// requestIDBytes is the number of random bytes of generated request identifiers
+	requestIDBytes = 16
+)
+
+// unguardedServices are operational services, which are neither authenticated nor rate limited
+var unguardedServices = []string{
+	"/" + grpc_health_v1.Health_ServiceDesc.ServiceName + "/",
+	"/grpc.reflection.",
+}
+
+var (
+	callsTotal  atomic.Uint64
+	openStreams atomic.Int64
+)
+
+func init() {
+	metrics.RegisterCounter(semconv.MetricName("grpc", "calls", "total"),
+		"Total number of gRPC calls.",
+		func() float64 { return float64(callsTotal.Load()) })
+	metrics.RegisterGauge(semconv.MetricName("grpc", "streams", "open"),
+		"Number of gRPC streams currently open.",
+		func() float64 { return float64(openStreams.Load()) })
+}
+
+type (
+	// Registration registers gRPC services, such as the RegisterXServer functions generated by protoc-gen-go-grpc
+	Registration func(registrar grpc.ServiceRegistrar)
+	// Server is a convenience wrapper around grpc.Server lifecycle, guarded by a http.Server
+	Server struct {
+		inner *grpc.Server
+		guard *http.Server
+		addr  string
+		log   *zap.Logger
+		// streams tracks the cancellation of open streams, as grpc.Server.GracefulStop waits for them
+		lock    sync.Mutex
+		streams map[grpc.ServerStream]context.CancelFunc
+		closed  bool
+	}
+)
+
+// NewServer instantiates a Server listening on config.Manifest GrpcAddr, whose calls are guarded by the given
+// http.Server, or not at all if it is nil. Services are registered through the given Registration functions, along
+// with the gRPC health checking service and, if config.Manifest GrpcReflectionEnabled is set, the server reflection
+// service.
+func NewServer(guard *http.Server, registrations ...Registration) *Server {
+	s := &Server{
+		guard:   guard,
+		addr:    config.Config.GrpcAddr,
+		log:     logger.New(),
+		streams: make(map[grpc.ServerStream]context.CancelFunc),
+	}
+	s.inner = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	for _, register := range registrations {
+		register(s.inner)
+	}
+	grpc_health_v1.RegisterHealthServer(s.inner, newHealthServer(s.inner.GetServiceInfo()))
+	if config.Config.GrpcReflectionEnabled {
+		reflection.Register(s.inner)
+	}
+	return s
+}
+
+// Serve listens on config.Manifest GrpcAddr and serves calls until Shutdown is called.
+func (s *Server) Serve() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return errors.Wrapf(err, "grpc listener on '%s'", s.addr)
+	}
+	s.log.Info("starting grpc server", zap.String("addr", s.addr))
+	return s.serve(listener)
+}
+
+func (s *Server) serve(listener net.Listener) error {
+	if err := s.inner.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown interrupts open streams, then waits for in-flight calls to complete. Calls are aborted once the given
+// context.Context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	s.closed = true
+	for _, cancel := range s.streams {
+		cancel()
+	}
+	s.lock.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.inner.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.inner.Stop()
+		return errors.Wrapf(ctx.Err(), "in-flight grpc calls aborted")
+	}
+}
+
+// openStream returns the context.Context of a stream, cancelled once the Server shuts down
+func (s *Server) openStream(stream grpc.ServerStream) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(stream.Context())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		cancel()
+	}
+	s.streams[stream] = cancel
+	openStreams.Add(1)
+	return ctx, func() {
+		cancel()
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		delete(s.streams, stream)
+		openStreams.Add(-1)
+	}
+}
+
+// begin prepares the context.Context of a call: it is guarded unless the call targets an operational service, and
+// carries a logger identifying the call. Response headers are sent through setHeader.
+func (s *Server) begin(ctx context.Context, method string, setHeader func(metadata.MD) error) (context.Context, error) {
+	callsTotal.Add(1)
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := firstValue(md, headerRequestID)
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	ctx = logger.Inject(ctx, zap.Fields(
+		zap.String("grpc.method", method),
+		zap.String("request.id", requestID),
+	))
+	header := metadata.Pairs(headerRequestID, requestID)
+	defer func() { _ = setHeader(header) }()
+
+	if s.guard == nil {
+		return ctx, nil
+	}
+	for _, prefix := range unguardedServices {
+		if strings.HasPrefix(method, prefix) {
+			return ctx, nil
+		}
+	}
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	guarded, limits, err := s.guard.Guard(ctx, toHeader(md), remoteAddr)
+	for key, values := range limits {
+		header.Append(strings.ToLower(key), values...)
+	}
+	return guarded, err
+}
+
+// end logs a finished call, and converts its error to a gRPC status
+func (s *Server) end(ctx context.Context, start time.Time, err error) error {
+	statusErr := toStatus(ctx, err)
+	code := status.Code(statusErr)
+	log := logger.FromContext(ctx).With(
+		zap.String("grpc.code", code.String()),
+		zap.Duration("latency", time.Since(start)),
+	)
+	switch code {
+	case codes.OK:
+		log.Info("grpc call")
+	case codes.Internal, codes.Unknown:
+		log.Error("grpc call failed", zap.Error(err))
+	default:
+		log.Info("grpc call failed", zap.Error(err))
+	}
+	return statusErr
+}
+
+// recovered converts a handler panic into an error, so that a single call does not crash the process
+func (s *Server) recovered(ctx context.Context, value any) error {
+	logger.FromContext(ctx).Error("grpc handler panic", zap.Any("panic", value), zap.StackSkip("stack", 2))
+	return errors.Errorf("panic: %v", value)
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response any, err error) {
+	start := time.Now()
+	ctx, err = s.begin(ctx, info.FullMethod, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) })
+	defer func() {
+		if value := recover(); value != nil {
+			response, err = nil, s.recovered(ctx, value)
+		}
+		err = s.end(ctx, start, err)
+	}()
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	ctx, closeStream := s.openStream(stream)
+	defer closeStream()
+	ctx, err = s.begin(ctx, info.FullMethod, stream.SetHeader)
+	defer func() {
+		if value := recover(); value != nil {
+			err = s.recovered(ctx, value)
+		}
+		err = s.end(ctx, start, err)
+	}()
+	if err != nil {
+		return err
+	}
+	err = handler(srv, &guardedStream{ServerStream: stream, ctx: ctx})
+	// interrupted clients are expected to resume their stream on another instance
+	if err != nil && errors.Is(err, context.Canceled) && s.isClosed() {
+		err = status.Error(codes.Unavailable, "server is shutting down")
+	}
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+// guardedStream overrides the context.Context of a grpc.ServerStream with the guarded one
+type guardedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *guardedStream) Context() context.Context {
+	return s.ctx
+}
+
+// toHeader converts incoming metadata to HTTP headers, skipping pseudo headers and binary values. Forwarding headers
+// such as x-forwarded-for are kept, Guard only honoring them from config.Manifest TrustedProxies like for HTTP requests.
+func toHeader(md metadata.MD) nethttp.Header {
+	header := make(nethttp.Header, len(md))
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") || strings.HasSuffix(key, "-bin") {
+			continue
+		}
+		header[textproto.CanonicalMIMEHeaderKey(key)] = values
+	}
+	return header
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func newRequestID() string {
+	buf := make([]byte, requestIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	nethttp "net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+)
+
+// serveBuffered serves the Server through an in-memory listener, returning a connected client
+func serveBuffered(t *testing.T, s *Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = s.serve(listener) }()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestStatus(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	a.NoError(toStatus(ctx, nil))
+	for err, code := range map[error]codes.Code{
+		errors.BadRequest(nil, "invalid"):             codes.InvalidArgument,
+		errors.Unauthorized(nil, "missing"):           codes.Unauthenticated,
+		errors.Forbidden(nil, "missing scopes"):       codes.PermissionDenied,
+		errors.NotFound():                             codes.NotFound,
+		errors.TooManyRequests(nil, "slow down"):      codes.ResourceExhausted,
+		errors.Unavailable(context.Canceled, "busy"):  codes.Unavailable,
+		context.DeadlineExceeded:                      codes.DeadlineExceeded,
+		status.Error(codes.AlreadyExists, "conflict"): codes.AlreadyExists,
+		errors.NotImplemented():                       codes.Unimplemented,
+		net.UnknownNetworkError("internal failure"):   codes.Internal,
+	} {
+		a.Equal(code, status.Code(toStatus(ctx, err)), err.Error())
+	}
+	a.Equal("invalid", status.Convert(toStatus(ctx, errors.BadRequest(nil, "invalid"))).Message())
+}
+
+func TestHealth(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	client := grpc_health_v1.NewHealthClient(serveBuffered(t, NewServer(nil)))
+
+	var header metadata.MD
+	response, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header))
+	if a.NoError(err) {
+		a.Equal(grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+		a.Len(header.Get(headerRequestID), 1, "calls are identified")
+	}
+	response, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: LivenessService})
+	if a.NoError(err) {
+		a.Equal(grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+	}
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	a.Equal(codes.NotFound, status.Code(err))
+
+	watchCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	watch, err := client.Watch(watchCtx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	if a.NoError(err) {
+		response, err = watch.Recv()
+		if a.NoError(err) {
+			a.Equal(grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, response.Status)
+		}
+		_, err = watch.Recv()
+		a.Equal(codes.DeadlineExceeded, status.Code(err), "watches of unknown services stay open")
+	}
+}
+
+func TestToHeader(t *testing.T) {
+	a := assert.New(t)
+	header := toHeader(metadata.Pairs(
+		"x-api-key", "secret",
+		"x-forwarded-for", "203.0.113.7",
+		"trace-bin", "binary",
+	))
+	a.Equal(nethttp.Header{"X-Api-Key": {"secret"}, "X-Forwarded-For": {"203.0.113.7"}}, header)
+}
+
+func TestShutdown(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	s := NewServer(nil)
+	client := grpc_health_v1.NewHealthClient(serveBuffered(t, s))
+
+	watch, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if !a.NoError(err) {
+		return
+	}
+	response, err := watch.Recv()
+	if a.NoError(err) {
+		a.Equal(grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	a.NoError(s.Shutdown(shutdownCtx), "open streams are interrupted")
+	_, err = watch.Recv()
+	a.Equal(codes.Unavailable, status.Code(err), "interrupted streams can be resumed elsewhere")
+	a.Zero(openStreams.Load())
+}
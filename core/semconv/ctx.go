@@ -9,3 +9,9 @@ func CtxKey(fields ...string) string {
 	fields = append([]string{namespace}, fields...)
 	return strings.Join(fields, ".")
 }
+
+// MetricName returns a prometheus compatible metric name
+func MetricName(fields ...string) string {
+	fields = append([]string{namespace}, fields...)
+	return strings.Join(fields, "_")
+}
@@ -0,0 +1,787 @@
+package webhooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	coreErrors "github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/generics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+const (
+	// idBytes is the number of random bytes of event and delivery identifiers
+	idBytes = 16
+	// webhookIDBytes is the number of random bytes of generated webhook identifiers
+	webhookIDBytes = 8
+	// maxWebhookIDLength bounds the identifiers of webhooks registered through configuration
+	maxWebhookIDLength = 64
+	// secretBytes is the number of random bytes of generated secrets
+	secretBytes = 32
+	// minSecretLength is the minimum length of secrets chosen by clients
+	minSecretLength = 16
+	// maxResponseSize bounds the part of webhook responses read before closing them, so connections can be reused
+	maxResponseSize = 64 * 1024
+	userAgent       = "fizzbuzz-webhooks"
+
+	webhooksFile    = "webhooks.json"
+	deadLettersFile = "dead-letters.jsonl"
+	tmpExt          = ".tmp"
+)
+
+type (
+	// hook is a Webhook known by the manager, along with its latest delivery attempts
+	hook struct {
+		webhook Webhook
+		history []Delivery
+	}
+	// delivery is an Event being delivered to a Webhook
+	delivery struct {
+		id        string
+		webhookID string
+		event     Event
+		payload   []byte
+		attempts  int
+	}
+	manager struct {
+		dir          string
+		staticFile   string
+		policy       *targetPolicy
+		client       *nethttp.Client
+		workers      int
+		maxWebhooks  int
+		maxPerClient int
+		maxAttempts  int
+		historySize  int
+		timeout      time.Duration
+		backoff      time.Duration
+		maxBackoff   time.Duration
+
+		lock    sync.Mutex
+		hooks   map[string]*hook
+		queue   chan *delivery
+		retries map[*delivery]*time.Timer
+		cancel  context.CancelFunc
+		group   sync.WaitGroup
+
+		// deadLetterLock serializes appends to the dead-letter log
+		deadLetterLock sync.Mutex
+		delivered      atomic.Uint64
+		deadLetters    atomic.Uint64
+	}
+)
+
+func newManager(dir string) *manager {
+	policy := newTargetPolicy(config.Config.WebhookAllowedHosts)
+	transport := nethttp.DefaultTransport.(*nethttp.Transport).Clone()
+	// deliveries are not proxied, so that their addresses are the ones checked by the policy
+	transport.Proxy = nil
+	transport.DialContext = policy.dialContext
+	return &manager{
+		dir:        dir,
+		staticFile: config.Config.WebhooksFile,
+		policy:     policy,
+		client: &nethttp.Client{
+			Transport: transport,
+			// redirects are not followed, as they would turn deliveries into GET requests
+			CheckRedirect: func(*nethttp.Request, []*nethttp.Request) error {
+				return nethttp.ErrUseLastResponse
+			},
+		},
+		workers:      config.Config.WebhookWorkers,
+		maxWebhooks:  config.Config.MaxWebhooks,
+		maxPerClient: config.Config.MaxClientWebhooks,
+		maxAttempts:  config.Config.WebhookMaxAttempts,
+		historySize:  config.Config.WebhookHistorySize,
+		timeout:      config.Config.WebhookTimeout,
+		backoff:      config.Config.WebhookRetryBackoff,
+		maxBackoff:   config.Config.WebhookMaxBackoff,
+		hooks:        make(map[string]*hook),
+		queue:        make(chan *delivery, config.Config.WebhookQueueSize),
+		retries:      make(map[*delivery]*time.Timer),
+	}
+}
+
+func newID(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validID checks that the identifier of a webhook registered through configuration is usable in URLs
+func validID(id string) bool {
+	if id == "" || len(id) > maxWebhookIDLength {
+		return false
+	}
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks the URL and events of a Webhook
+func validate(webhook Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return coreErrors.BadRequest(err, "`url` must be an absolute http or https URL")
+	}
+	for _, event := range webhook.Events {
+		if !generics.SliceContains(EventTypes, event) {
+			return coreErrors.BadRequest(nil, "unknown event type '%s'", event)
+		}
+	}
+	return nil
+}
+
+// redacted returns a copy of the Webhook without its secret
+func (w Webhook) redacted() Webhook {
+	w.Secret = ""
+	return w
+}
+
+// ownedBy checks whether the Webhook has been registered by the given client, webhooks registered through
+// configuration without `createdBy` belonging to no client
+func (w Webhook) ownedBy(client string) bool {
+	return w.CreatedBy != "" && w.CreatedBy == client
+}
+
+// subscribed checks whether an Event of the given type and route should be sent to the Webhook
+func (w Webhook) subscribed(eventType, route string) bool {
+	if len(w.Events) > 0 && !generics.SliceContains(w.Events, eventType) {
+		return false
+	}
+	return len(w.Routes) == 0 || route == "" || generics.SliceContains(w.Routes, route)
+}
+
+// persist atomically replaces the file of the webhooks registered through the API, it expects the lock to be held
+func (m *manager) persist() error {
+	webhooks := make([]Webhook, 0, len(m.hooks))
+	for _, h := range m.hooks {
+		if !h.webhook.Static {
+			webhooks = append(webhooks, h.webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	buf, err := json.Marshal(webhooks)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return errors.Wrapf(err, "could not create webhooks directory '%s'", m.dir)
+	}
+	// the file holds secrets, it is only readable by its owner
+	path := filepath.Join(m.dir, webhooksFile)
+	if err := os.WriteFile(path+tmpExt, buf, 0o600); err != nil {
+		return errors.Wrap(err, "could not write webhooks")
+	}
+	if err := os.Rename(path+tmpExt, path); err != nil {
+		_ = os.Remove(path + tmpExt)
+		return errors.Wrap(err, "could not write webhooks")
+	}
+	return nil
+}
+
+// readWebhooks reads a JSON array of webhooks, a missing file being empty
+func readWebhooks(path string) ([]Webhook, error) {
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read webhooks file '%s'", path)
+	}
+	var webhooks []Webhook
+	if err := json.Unmarshal(buf, &webhooks); err != nil {
+		return nil, errors.Wrapf(err, "invalid webhooks file '%s'", path)
+	}
+	return webhooks, nil
+}
+
+// load reads the webhooks registered through the API, then the ones registered through configuration, which must be
+// valid as they cannot be fixed at runtime
+func (m *manager) load(log *zap.Logger) error {
+	persisted, err := readWebhooks(filepath.Join(m.dir, webhooksFile))
+	if err != nil {
+		return err
+	}
+	var static []Webhook
+	if m.staticFile != "" {
+		if static, err = readWebhooks(m.staticFile); err != nil {
+			return err
+		}
+		if static == nil {
+			return errors.Errorf("webhooks file '%s' does not exist", m.staticFile)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, webhook := range persisted {
+		if err := validate(webhook); err != nil || webhook.ID == "" {
+			log.Warn("invalid webhook", zap.String("webhook.id", webhook.ID), zap.Error(err))
+			continue
+		}
+		webhook.Static = false
+		m.hooks[webhook.ID] = &hook{webhook: webhook}
+	}
+	now := time.Now().UTC()
+	for _, webhook := range static {
+		if !validID(webhook.ID) {
+			return errors.Errorf("invalid webhook identifier '%s' in '%s'", webhook.ID, m.staticFile)
+		}
+		if err := validate(webhook); err != nil {
+			return errors.Wrapf(err, "invalid webhook '%s' in '%s'", webhook.ID, m.staticFile)
+		}
+		if webhook.Secret == "" {
+			return errors.Errorf("empty secret for webhook '%s' in '%s'", webhook.ID, m.staticFile)
+		}
+		if h, ok := m.hooks[webhook.ID]; ok && h.webhook.Static {
+			return errors.Errorf("duplicate webhook '%s' in '%s'", webhook.ID, m.staticFile)
+		}
+		webhook.Static = true
+		if webhook.CreatedAt.IsZero() {
+			webhook.CreatedAt = now
+		}
+		// webhooks registered through configuration supersede the ones registered through the API
+		m.hooks[webhook.ID] = &hook{webhook: webhook}
+	}
+	log.Info("webhooks loaded", zap.Int("webhooks", len(m.hooks)), zap.Int("static", len(static)))
+	return nil
+}
+
+func (m *manager) start(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	if err := m.load(log); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	changes, err := metrics.Subscribe(ctx, metrics.SubscribeOptions{
+		Thresholds: config.Config.MetricsFeedThresholds,
+		Interval:   config.Config.MetricsFeedInterval,
+	})
+	if err != nil {
+		m.cancel()
+		return err
+	}
+	m.group.Add(1)
+	go func() {
+		defer m.group.Done()
+		for change := range changes {
+			m.notify(ctx, change)
+		}
+	}()
+
+	for i := 0; i < m.workers; i++ {
+		m.group.Add(1)
+		go func() {
+			defer m.group.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d := <-m.queue:
+					m.attempt(ctx, d)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+func (m *manager) stop(ctx context.Context) error {
+	m.lock.Lock()
+	cancel := m.cancel
+	m.lock.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.group.Wait()
+		close(done)
+	}()
+	select {
+	case <-ctx.Done():
+		return coreErrors.Unavailable(ctx.Err(), "webhook workers did not stop in time")
+	case <-done:
+	}
+
+	// deliveries are not persisted, the ones which did not succeed yet are dead-lettered so that they are not lost
+	m.lock.Lock()
+	m.cancel = nil
+	var interrupted []*delivery
+	for d, timer := range m.retries {
+		timer.Stop()
+		interrupted = append(interrupted, d)
+	}
+	m.retries = make(map[*delivery]*time.Timer)
+	for len(m.queue) > 0 {
+		interrupted = append(interrupted, <-m.queue)
+	}
+	m.lock.Unlock()
+
+	for _, d := range interrupted {
+		m.deadLetter(d, "interrupted by shutdown")
+	}
+	return nil
+}
+
+func (m *manager) pending() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.queue) + len(m.retries)
+}
+
+// notify publishes an Event describing a metrics.TopChange
+func (m *manager) notify(ctx context.Context, change metrics.TopChange) {
+	eventType := ThresholdCrossed
+	if change.Changed {
+		eventType = TopChanged
+	}
+	data := TopRequestData{
+		Request:   change.Top.Bytes,
+		Hits:      change.Top.Hits,
+		Threshold: change.Threshold,
+	}
+	if _, err := m.publish(ctx, "", eventType, change.Top.Route, data); err != nil {
+		logger.New().Error("could not publish metrics event", zap.String("event.type", eventType), zap.Error(err))
+	}
+}
+
+// publish queues deliveries of a new Event to every subscribed Webhook, or to the given Webhook only
+func (m *manager) publish(_ context.Context, webhookID, eventType, route string, data any) (Event, error) {
+	id, err := newID(idBytes)
+	if err != nil {
+		return Event{}, err
+	}
+	event := Event{
+		ID:        id,
+		Type:      eventType,
+		Route:     route,
+		CreatedAt: time.Now().UTC(),
+	}
+	if data != nil {
+		if event.Data, err = json.Marshal(data); err != nil {
+			return Event{}, err
+		}
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return Event{}, err
+	}
+
+	m.lock.Lock()
+	var deliveries []*delivery
+	for _, h := range m.hooks {
+		if webhookID == h.webhook.ID || (webhookID == "" && h.webhook.subscribed(eventType, route)) {
+			deliveries = append(deliveries, &delivery{webhookID: h.webhook.ID, event: event, payload: payload})
+		}
+	}
+	m.lock.Unlock()
+
+	for _, d := range deliveries {
+		if d.id, err = newID(idBytes); err != nil {
+			return Event{}, err
+		}
+		m.enqueue(d)
+	}
+	return event, nil
+}
+
+// enqueue queues a delivery for a worker, dead-lettering it if the queue is full
+func (m *manager) enqueue(d *delivery) {
+	select {
+	case m.queue <- d:
+	default:
+		m.deadLetter(d, "the delivery queue is full")
+	}
+}
+
+// delay returns the delay before the next attempt of a delivery, doubling on every attempt
+func (m *manager) delay(attempts int) time.Duration {
+	delay := m.backoff
+	for i := 1; i < attempts && delay < m.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > m.maxBackoff {
+		delay = m.maxBackoff
+	}
+	return delay
+}
+
+// retryable checks whether a failed attempt, answered with the given status code, may succeed later
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode >= nethttp.StatusInternalServerError ||
+		statusCode == nethttp.StatusRequestTimeout ||
+		statusCode == nethttp.StatusTooManyRequests
+}
+
+// attempt delivers an Event to its Webhook, scheduling a retry or dead-lettering it if it fails
+func (m *manager) attempt(ctx context.Context, d *delivery) {
+	m.lock.Lock()
+	h, ok := m.hooks[d.webhookID]
+	var webhook Webhook
+	if ok {
+		webhook = h.webhook
+	}
+	m.lock.Unlock()
+	// deliveries of deleted webhooks are dropped
+	if !ok {
+		return
+	}
+
+	d.attempts++
+	start := time.Now()
+	record := Delivery{
+		ID:        d.id,
+		WebhookID: webhook.ID,
+		EventID:   d.event.ID,
+		EventType: d.event.Type,
+		Attempt:   d.attempts,
+		AttemptAt: start.UTC(),
+	}
+	statusCode, err := m.post(ctx, webhook, d)
+	record.StatusCode = statusCode
+	record.Duration = time.Since(start).Milliseconds()
+	log := logger.New().With(
+		zap.String("webhook.id", webhook.ID),
+		zap.String("delivery.id", d.id),
+		zap.String("event.type", d.event.Type),
+		zap.Int("delivery.attempt", d.attempts),
+	)
+
+	switch {
+	case err == nil:
+		record.Succeeded = true
+		m.record(record)
+		m.delivered.Add(1)
+		log.Debug("webhook delivered", zap.Int("http.status_code", statusCode))
+	case ctx.Err() != nil:
+		record.Error = "interrupted by shutdown"
+		m.record(record)
+		m.deadLetter(d, record.Error)
+	case retryable(statusCode) && d.attempts < m.maxAttempts:
+		delay := m.delay(d.attempts)
+		retryAt := start.Add(delay).UTC()
+		record.Error, record.RetryAt = err.Error(), &retryAt
+		m.record(record)
+		m.retry(d, delay)
+		log.Info("webhook delivery failed, retrying", zap.Duration("delivery.delay", delay), zap.Error(err))
+	default:
+		record.Error = err.Error()
+		m.record(record)
+		m.deadLetter(d, record.Error)
+	}
+}
+
+// post sends the payload of a delivery, the Event being delivered if the webhook answers with a 2xx status code
+func (m *manager) post(ctx context.Context, webhook Webhook, d *delivery) (int, error) {
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodPost, webhook.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, d.event.Type)
+	req.Header.Set(DeliveryHeader, d.id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, d.payload))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < nethttp.StatusOK || resp.StatusCode >= nethttp.StatusMultipleChoices {
+		return resp.StatusCode, errors.Errorf("webhook answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retry queues a delivery again once the given delay elapsed, unless the manager is stopped in between
+func (m *manager) retry(d *delivery, delay time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.retries[d] = time.AfterFunc(delay, func() {
+		m.lock.Lock()
+		_, ok := m.retries[d]
+		delete(m.retries, d)
+		m.lock.Unlock()
+
+		if ok {
+			m.enqueue(d)
+		}
+	})
+}
+
+// record adds a delivery attempt to the history of its Webhook, dropping the oldest one once the history is full
+func (m *manager) record(record Delivery) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	h, ok := m.hooks[record.WebhookID]
+	if !ok || m.historySize <= 0 {
+		return
+	}
+	if len(h.history) >= m.historySize {
+		copy(h.history, h.history[len(h.history)-m.historySize+1:])
+		h.history = h.history[:m.historySize-1]
+	}
+	h.history = append(h.history, record)
+}
+
+// deadLetter appends a delivery which did not succeed to the dead-letter log
+func (m *manager) deadLetter(d *delivery, reason string) {
+	m.deadLetters.Add(1)
+	m.lock.Lock()
+	var target, owner string
+	if h, ok := m.hooks[d.webhookID]; ok {
+		target, owner = h.webhook.URL, h.webhook.CreatedBy
+	}
+	m.lock.Unlock()
+
+	log := logger.New().With(zap.String("webhook.id", d.webhookID), zap.String("delivery.id", d.id))
+	log.Warn("webhook delivery dead-lettered", zap.String("event.type", d.event.Type), zap.String("reason", reason))
+	buf, err := json.Marshal(DeadLetter{
+		DeliveryID: d.id,
+		WebhookID:  d.webhookID,
+		CreatedBy:  owner,
+		URL:        target,
+		Event:      d.event,
+		Attempts:   d.attempts,
+		Error:      reason,
+		FailedAt:   time.Now().UTC(),
+	})
+	if err == nil {
+		err = m.appendDeadLetter(append(buf, '\n'))
+	}
+	if err != nil {
+		log.Error("could not write dead letter", zap.Error(err))
+	}
+}
+
+func (m *manager) appendDeadLetter(line []byte) error {
+	m.deadLetterLock.Lock()
+	defer m.deadLetterLock.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return errors.Wrapf(err, "could not create webhooks directory '%s'", m.dir)
+	}
+	f, err := os.OpenFile(filepath.Join(m.dir, deadLettersFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (m *manager) readDeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	client := identity.ClientId(ctx)
+
+	m.deadLetterLock.Lock()
+	defer m.deadLetterLock.Unlock()
+
+	f, err := os.Open(filepath.Join(m.dir, deadLettersFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return []DeadLetter{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read dead letters")
+	}
+	defer func() { _ = f.Close() }()
+
+	var deadLetters []DeadLetter
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var deadLetter DeadLetter
+			if json.Unmarshal(line, &deadLetter) == nil && deadLetter.CreatedBy == client {
+				deadLetters = append(deadLetters, deadLetter)
+			}
+			if limit > 0 && len(deadLetters) > limit {
+				deadLetters = deadLetters[1:]
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read dead letters")
+		}
+	}
+
+	newest := make([]DeadLetter, 0, len(deadLetters))
+	for i := len(deadLetters) - 1; i >= 0; i-- {
+		newest = append(newest, deadLetters[i])
+	}
+	return newest, nil
+}
+
+func (m *manager) create(ctx context.Context, webhook Webhook) (Webhook, error) {
+	if err := validate(webhook); err != nil {
+		return Webhook{}, err
+	}
+	// the host is checked again on every delivery attempt, but rejecting it now reports the mistake to the client
+	if u, _ := url.Parse(webhook.URL); u != nil {
+		if err := m.policy.check(ctx, u.Hostname()); err != nil {
+			return Webhook{}, coreErrors.BadRequest(err, "`url` host '%s' is not allowed", u.Hostname())
+		}
+	}
+	var err error
+	switch {
+	case webhook.Secret == "":
+		if webhook.Secret, err = newID(secretBytes); err != nil {
+			return Webhook{}, err
+		}
+	case len(webhook.Secret) < minSecretLength:
+		return Webhook{}, coreErrors.BadRequest(nil, "`secret` must be at least %d bytes long", minSecretLength)
+	}
+	if webhook.ID, err = newID(webhookIDBytes); err != nil {
+		return Webhook{}, err
+	}
+	webhook.Static = false
+	webhook.CreatedAt = time.Now().UTC()
+	webhook.CreatedBy = identity.ClientId(ctx)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	count, clientCount := 0, 0
+	for _, h := range m.hooks {
+		if !h.webhook.Static {
+			count++
+			if h.webhook.CreatedBy == webhook.CreatedBy {
+				clientCount++
+			}
+		}
+	}
+	if clientCount >= m.maxPerClient {
+		return Webhook{}, coreErrors.BadRequest(nil, "no more than %d webhooks can be registered by a client", m.maxPerClient)
+	}
+	if count >= m.maxWebhooks {
+		return Webhook{}, coreErrors.BadRequest(nil, "no more than %d webhooks can be registered", m.maxWebhooks)
+	}
+	m.hooks[webhook.ID] = &hook{webhook: webhook}
+	if err := m.persist(); err != nil {
+		delete(m.hooks, webhook.ID)
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// lookup returns a Webhook registered by the client of the context.Context, it expects the lock to be held
+func (m *manager) lookup(ctx context.Context, id string) (*hook, error) {
+	h, ok := m.hooks[id]
+	if !ok || !h.webhook.ownedBy(identity.ClientId(ctx)) {
+		return nil, coreErrors.NotFound()
+	}
+	return h, nil
+}
+
+func (m *manager) list(ctx context.Context) []Webhook {
+	client := identity.ClientId(ctx)
+	m.lock.Lock()
+	webhooks := make([]Webhook, 0, len(m.hooks))
+	for _, h := range m.hooks {
+		if h.webhook.ownedBy(client) {
+			webhooks = append(webhooks, h.webhook.redacted())
+		}
+	}
+	m.lock.Unlock()
+
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks
+}
+
+func (m *manager) get(ctx context.Context, id string) (Webhook, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	h, err := m.lookup(ctx, id)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return h.webhook.redacted(), nil
+}
+
+func (m *manager) delete(ctx context.Context, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	h, err := m.lookup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if h.webhook.Static {
+		return coreErrors.Conflict(nil, "webhook '%s' is registered through configuration, it cannot be deleted", id)
+	}
+	delete(m.hooks, id)
+	if err := m.persist(); err != nil {
+		m.hooks[id] = h
+		return err
+	}
+	return nil
+}
+
+func (m *manager) deliveries(ctx context.Context, id string) ([]Delivery, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	h, err := m.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]Delivery, 0, len(h.history))
+	for i := len(h.history) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, h.history[i])
+	}
+	return deliveries, nil
+}
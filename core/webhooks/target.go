@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which is not routable on the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// errForbiddenTarget is returned when a webhook host resolves to an address which is not allowed
+var errForbiddenTarget = errors.New("forbidden webhook address")
+
+// targetPolicy restricts the addresses webhooks are delivered to, so that clients cannot use them to reach the
+// network of the server, such as loopback, private or link-local addresses, unless allowed through configuration
+type targetPolicy struct {
+	// hosts are the allowed host names, whatever their addresses
+	hosts map[string]struct{}
+	// networks are the allowed address ranges
+	networks []*net.IPNet
+	resolver *net.Resolver
+	dialer   *net.Dialer
+}
+
+// newTargetPolicy parses allowed host names, addresses and CIDR ranges, see config.Manifest WebhookAllowedHosts
+func newTargetPolicy(allowed []string) *targetPolicy {
+	p := &targetPolicy{
+		hosts:    make(map[string]struct{}),
+		resolver: net.DefaultResolver,
+		dialer:   &net.Dialer{},
+	}
+	for _, entry := range allowed {
+		entry = strings.TrimSpace(entry)
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			p.networks = append(p.networks, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			p.networks = append(p.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		} else if entry != "" {
+			p.hosts[strings.ToLower(entry)] = struct{}{}
+		}
+	}
+	return p
+}
+
+// internal checks whether an address belongs to the network of the server rather than to the internet
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+func (p *targetPolicy) allowedHost(host string) bool {
+	_, ok := p.hosts[strings.ToLower(host)]
+	return ok
+}
+
+func (p *targetPolicy) allowedIP(ip net.IP) bool {
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return !internal(ip)
+}
+
+// resolve returns the addresses of a host, failing if any of them is not allowed
+func (p *targetPolicy) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := p.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !p.allowedIP(ip) {
+			return nil, errors.Wrapf(errForbiddenTarget, "'%s' resolves to a forbidden address", host)
+		}
+	}
+	return ips, nil
+}
+
+// check validates the host of a webhook, as it resolves when the webhook is registered
+func (p *targetPolicy) check(ctx context.Context, host string) error {
+	if p.allowedHost(host) {
+		return nil
+	}
+	_, err := p.resolve(ctx, host)
+	return err
+}
+
+// dialContext connects to one of the allowed addresses of a webhook host. Hosts are resolved again on every attempt
+// and the checked address is the one dialed, so that a host cannot change its addresses once it has been registered.
+func (p *targetPolicy) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if p.allowedHost(host) {
+		return p.dialer.DialContext(ctx, network, address)
+	}
+	ips, err := p.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	for _, ip := range ips {
+		if conn, err = p.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,220 @@
+// Package webhooks notifies other systems of metrics events, such as top request changes, through HTTP callbacks.
+//
+// Webhooks are registered either through the API, being persisted to a local directory, or through configuration.
+// They are only visible to the client which registered them, and cannot be delivered to the network of the server
+// unless allowed through configuration.
+// Every payload is signed with the secret of its webhook. Failed deliveries are retried with an exponential backoff,
+// and end up in a dead-letter log once every attempt failed. The latest attempts of every webhook are kept in memory.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/health"
+	"github.com/Raphy42/industrial-fizz-buzz/core/http/metrics"
+	"github.com/Raphy42/industrial-fizz-buzz/core/semconv"
+)
+
+const (
+	// TopChanged events are sent when the top request of a route is replaced, or reset
+	TopChanged = "top.changed"
+	// ThresholdCrossed events are sent when the hit count of the top request of a route crosses a threshold
+	ThresholdCrossed = "threshold.crossed"
+	// Ping events are only sent on demand, to check that a webhook is reachable
+	Ping = "ping"
+)
+
+const (
+	// EventHeader carries the type of the delivered Event
+	EventHeader = "X-Fizzbuzz-Event"
+	// DeliveryHeader carries the identifier of the delivery, shared by every attempt
+	DeliveryHeader = "X-Fizzbuzz-Delivery"
+	// TimestampHeader carries the Unix time at which the payload has been signed
+	TimestampHeader = "X-Fizzbuzz-Timestamp"
+	// SignatureHeader carries the signature of the payload, see Sign
+	SignatureHeader = "X-Fizzbuzz-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// EventTypes are the types of events webhooks can subscribe to
+var EventTypes = []string{TopChanged, ThresholdCrossed, Ping}
+
+type (
+	// Webhook is an HTTP endpoint notified of events
+	Webhook struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+		// Events are the types of events sent to the webhook, every type if empty
+		Events []string `json:"events,omitempty"`
+		// Routes are the routes whose events are sent to the webhook, every route if empty
+		Routes []string `json:"routes,omitempty"`
+		// Secret is the key payloads are signed with, it is only returned when the webhook is created
+		Secret string `json:"secret,omitempty"`
+		// Static webhooks are registered through config.Manifest WebhooksFile, and cannot be deleted
+		Static    bool      `json:"static,omitempty"`
+		CreatedAt time.Time `json:"createdAt"`
+		// CreatedBy is the identifier of the client which registered the webhook, the only one it is visible to
+		CreatedBy string `json:"createdBy,omitempty"`
+	}
+	// Event is the payload POSTed to webhooks
+	Event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		// Route is the route the event is about, if any
+		Route     string          `json:"route,omitempty"`
+		CreatedAt time.Time       `json:"createdAt"`
+		Data      json.RawMessage `json:"data,omitempty"`
+	}
+	// TopRequestData is the data of TopChanged and ThresholdCrossed events
+	TopRequestData struct {
+		// Request is the JSON body of the top request, null once the metrics of the route have been reset
+		Request json.RawMessage `json:"request"`
+		Hits    uint            `json:"hits"`
+		// Threshold is the highest threshold crossed by Hits since the previous event of the route, 0 if none
+		Threshold uint `json:"threshold,omitempty"`
+	}
+	// Delivery is an attempt to deliver an Event to a Webhook
+	Delivery struct {
+		ID        string `json:"id"`
+		WebhookID string `json:"webhookId"`
+		EventID   string `json:"eventId"`
+		EventType string `json:"eventType"`
+		Attempt   int    `json:"attempt"`
+		// StatusCode is the status of the response, 0 if none was received
+		StatusCode int    `json:"statusCode,omitempty"`
+		Error      string `json:"error,omitempty"`
+		Succeeded  bool   `json:"succeeded"`
+		// Duration is the duration of the attempt, in milliseconds
+		Duration  int64     `json:"durationMs"`
+		AttemptAt time.Time `json:"attemptAt"`
+		// RetryAt is set for failed attempts which will be retried
+		RetryAt *time.Time `json:"retryAt,omitempty"`
+	}
+	// DeadLetter is an Event which could not be delivered to a Webhook
+	DeadLetter struct {
+		DeliveryID string    `json:"deliveryId"`
+		WebhookID  string    `json:"webhookId"`
+		CreatedBy  string    `json:"createdBy,omitempty"`
+		URL        string    `json:"url"`
+		Event      Event     `json:"event"`
+		Attempts   int       `json:"attempts"`
+		Error      string    `json:"error"`
+		FailedAt   time.Time `json:"failedAt"`
+	}
+)
+
+var globalManager *manager
+
+func init() {
+	globalManager = newManager(config.Config.WebhooksDir)
+
+	metrics.RegisterGauge(semconv.MetricName("webhooks", "pending"),
+		"Number of webhook deliveries queued or waiting for a retry.",
+		func() float64 { return float64(globalManager.pending()) })
+	metrics.RegisterCounter(semconv.MetricName("webhooks", "delivered_total"),
+		"Number of events delivered to webhooks.",
+		func() float64 { return float64(globalManager.delivered.Load()) })
+	metrics.RegisterCounter(semconv.MetricName("webhooks", "dead_letters_total"),
+		"Number of events which could not be delivered to webhooks.",
+		func() float64 { return float64(globalManager.deadLetters.Load()) })
+	health.Register("webhooks", health.Readiness, time.Second, health.WritableDir(globalManager.dir))
+}
+
+// Sign returns the signature of a payload signed at the given Unix time: the hex encoded HMAC-SHA256 of
+// "{timestamp}.{payload}" keyed by the secret of the webhook, prefixed by "sha256=".
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivered payload, as received by webhooks. Payloads signed more than tolerance
+// ago are rejected, preventing replays, unless tolerance is 0.
+func Verify(secret string, header nethttp.Header, payload []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return errors.Unauthorized(err, "invalid %s header", TimestampHeader)
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+			return errors.Unauthorized(nil, "payload signed %s ago", age.Round(time.Second))
+		}
+	}
+	signature := header.Get(SignatureHeader)
+	if !strings.HasPrefix(signature, signaturePrefix) || !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, payload))) {
+		return errors.Unauthorized(nil, "invalid %s header", SignatureHeader)
+	}
+	return nil
+}
+
+// Start loads webhooks, starts the delivery workers, and sends metrics events to webhooks.
+// Workers are stopped through Stop, not the given context.Context.
+func Start(ctx context.Context) error {
+	return globalManager.start(ctx)
+}
+
+// Stop stops the delivery workers, deliveries which did not succeed yet being dead-lettered.
+// It blocks until every worker has exited, or the given context.Context is done.
+func Stop(ctx context.Context) error {
+	return globalManager.stop(ctx)
+}
+
+// Create registers a new Webhook on behalf of the client of the context.Context, a secret being generated if none
+// is set. The returned Webhook is the only one carrying its secret.
+func Create(ctx context.Context, webhook Webhook) (Webhook, error) {
+	return globalManager.create(ctx, webhook)
+}
+
+// List returns every Webhook registered by the client of the context.Context, sorted by identifier.
+func List(ctx context.Context) []Webhook {
+	return globalManager.list(ctx)
+}
+
+// Get returns a Webhook registered by the client of the context.Context.
+func Get(ctx context.Context, id string) (Webhook, error) {
+	return globalManager.get(ctx, id)
+}
+
+// Delete removes a Webhook registered through the API by the client of the context.Context, its pending deliveries
+// being dropped.
+func Delete(ctx context.Context, id string) error {
+	return globalManager.delete(ctx, id)
+}
+
+// Deliveries returns the latest delivery attempts of a Webhook registered by the client of the context.Context, from
+// newest to oldest.
+func Deliveries(ctx context.Context, id string) ([]Delivery, error) {
+	return globalManager.deliveries(ctx, id)
+}
+
+// DeadLetters returns at most limit of the latest dead letters of the webhooks registered by the client of the
+// context.Context, from newest to oldest, every one of them if limit is not positive.
+func DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	return globalManager.readDeadLetters(ctx, limit)
+}
+
+// Publish sends an Event to every Webhook subscribed to its type and route, its data being serialized to JSON.
+func Publish(ctx context.Context, eventType, route string, data any) (Event, error) {
+	return globalManager.publish(ctx, "", eventType, route, data)
+}
+
+// SendPing sends a Ping Event to a single Webhook registered by the client of the context.Context.
+func SendPing(ctx context.Context, id string) (Event, error) {
+	if _, err := globalManager.get(ctx, id); err != nil {
+		return Event{}, err
+	}
+	return globalManager.publish(ctx, id, Ping, "", nil)
+}
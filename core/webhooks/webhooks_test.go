@@ -0,0 +1,266 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raphy42/industrial-fizz-buzz/core/config"
+	"github.com/Raphy42/industrial-fizz-buzz/core/errors"
+	"github.com/Raphy42/industrial-fizz-buzz/core/identity"
+	"github.com/Raphy42/industrial-fizz-buzz/core/logger"
+)
+
+// receiver is a stand-in webhook endpoint, answering deliveries with the given status codes in turn, then 200
+type receiver struct {
+	*httptest.Server
+	lock     sync.Mutex
+	statuses []int
+	received []Event
+}
+
+func newReceiver(t *testing.T, secret string, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		payload, _ := io.ReadAll(req.Body)
+		if err := Verify(secret, req.Header, payload, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		if status == http.StatusOK {
+			var event Event
+			_ = json.Unmarshal(payload, &event)
+			r.received = append(r.received, event)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) events() []Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Event{}, r.received...)
+}
+
+// allowLoopback allows webhooks to be delivered to receivers, which listen on the loopback address
+func allowLoopback(t *testing.T) {
+	allowed := config.Config.WebhookAllowedHosts
+	config.Config.WebhookAllowedHosts = []string{"127.0.0.1"}
+	t.Cleanup(func() { config.Config.WebhookAllowedHosts = allowed })
+}
+
+func statusOf(err error) int {
+	if httpErr, ok := err.(*errors.Error); ok {
+		return httpErr.HttpCode
+	}
+	return 0
+}
+
+func TestSignature(t *testing.T) {
+	a := assert.New(t)
+	payload := []byte(`{"type":"ping"}`)
+	now := time.Now().Unix()
+
+	header := http.Header{}
+	header.Set(TimestampHeader, strconv.FormatInt(now, 10))
+	header.Set(SignatureHeader, Sign("secret", now, payload))
+	a.NoError(Verify("secret", header, payload, time.Minute))
+	a.Error(Verify("other", header, payload, time.Minute))
+	a.Error(Verify("secret", header, []byte(`{"type":"top.changed"}`), time.Minute))
+
+	old := now - 3600
+	header.Set(TimestampHeader, strconv.FormatInt(old, 10))
+	header.Set(SignatureHeader, Sign("secret", old, payload))
+	a.Error(Verify("secret", header, payload, time.Minute), "stale payloads are replays")
+	a.NoError(Verify("secret", header, payload, 0))
+}
+
+func TestDeliveries(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	allowLoopback(t)
+
+	m := newManager(dir)
+	m.backoff, m.maxAttempts = 5*time.Millisecond, 3
+	a.NoError(m.start(ctx))
+	defer func() { a.NoError(m.stop(ctx)) }()
+
+	// the first attempts fail, the delivery being retried until it succeeds
+	flaky := newReceiver(t, "flaky-secret-0123456789", http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	webhook, err := m.create(ctx, Webhook{URL: flaky.URL, Secret: "flaky-secret-0123456789", Routes: []string{"/fizz"}})
+	if !a.NoError(err) {
+		return
+	}
+	_, err = m.create(ctx, Webhook{URL: flaky.URL, Secret: "short"})
+	a.Equal(http.StatusBadRequest, statusOf(err))
+	_, err = m.create(ctx, Webhook{URL: "ftp://" + flaky.Listener.Addr().String()})
+	a.Equal(http.StatusBadRequest, statusOf(err))
+
+	event, err := m.publish(ctx, "", TopChanged, "/fizz", TopRequestData{Request: []byte(`{"limit":10}`), Hits: 1})
+	a.NoError(err)
+	_, err = m.publish(ctx, "", TopChanged, "/buzz", nil)
+	a.NoError(err)
+	a.Eventually(func() bool { return len(flaky.events()) == 1 }, time.Second, 5*time.Millisecond)
+	if received := flaky.events(); a.Len(received, 1, "events of other routes are filtered out") {
+		a.Equal(event.ID, received[0].ID)
+		a.JSONEq(`{"request":{"limit":10},"hits":1}`, string(received[0].Data))
+	}
+	deliveries, err := m.deliveries(ctx, webhook.ID)
+	if a.NoError(err) && a.Len(deliveries, 3) {
+		a.True(deliveries[0].Succeeded)
+		a.Equal(3, deliveries[0].Attempt)
+		a.Equal(http.StatusTooManyRequests, deliveries[1].StatusCode)
+		a.NotNil(deliveries[1].RetryAt)
+		a.Equal(deliveries[0].ID, deliveries[2].ID, "attempts share the delivery identifier")
+	}
+
+	// client errors are not retried, while server errors are until every attempt failed
+	generated, err := m.create(ctx, Webhook{URL: flaky.URL})
+	if a.NoError(err) {
+		a.NotEmpty(generated.Secret, "a secret is generated")
+		a.NoError(m.delete(ctx, generated.ID))
+	}
+	a.Equal(http.StatusNotFound, statusOf(m.delete(ctx, "unknown")))
+	broken := newReceiver(t, "broken-secret-0123456789",
+		http.StatusBadRequest, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	rejected, err := m.create(ctx, Webhook{URL: broken.URL, Secret: "broken-secret-0123456789", Events: []string{ThresholdCrossed}})
+	if !a.NoError(err) {
+		return
+	}
+	deadLettered := func(count int) bool {
+		deadLetters, err := m.readDeadLetters(ctx, 0)
+		return err == nil && len(deadLetters) == count
+	}
+	_, err = m.publish(ctx, "", ThresholdCrossed, "/buzz", nil)
+	a.NoError(err)
+	a.Eventually(func() bool { return deadLettered(1) }, time.Second, 5*time.Millisecond)
+	_, err = m.publish(ctx, "", ThresholdCrossed, "/buzz", nil)
+	a.NoError(err)
+	a.Eventually(func() bool { return deadLettered(2) }, time.Second, 5*time.Millisecond)
+	deadLetters, err := m.readDeadLetters(ctx, 1)
+	if a.NoError(err) && a.Len(deadLetters, 1) {
+		a.Equal(rejected.ID, deadLetters[0].WebhookID)
+		a.Equal(3, deadLetters[0].Attempts)
+		a.Equal(ThresholdCrossed, deadLetters[0].Event.Type)
+	}
+	a.Empty(broken.events())
+
+	// webhooks are only visible to the client which registered them
+	other := identity.Inject(ctx, identity.Identity{ClientId: "other"})
+	a.Empty(m.list(other))
+	_, err = m.get(other, webhook.ID)
+	a.Equal(http.StatusNotFound, statusOf(err))
+	_, err = m.deliveries(other, webhook.ID)
+	a.Equal(http.StatusNotFound, statusOf(err))
+	a.Equal(http.StatusNotFound, statusOf(m.delete(other, webhook.ID)))
+	deadLetters, err = m.readDeadLetters(other, 0)
+	if a.NoError(err) {
+		a.Empty(deadLetters)
+	}
+
+	// webhooks survive a restart, along with their secret
+	restarted := newManager(dir)
+	a.NoError(restarted.load(logger.New()))
+	if webhooks := restarted.list(ctx); a.Len(webhooks, 2) {
+		a.Empty(webhooks[0].Secret, "secrets are not listed")
+	}
+	a.Equal("flaky-secret-0123456789", restarted.hooks[webhook.ID].webhook.Secret)
+}
+
+func TestWebhookLimits(t *testing.T) {
+	a := assert.New(t)
+	allowLoopback(t)
+	alice := identity.Inject(context.Background(), identity.Identity{ClientId: "alice"})
+	bob := identity.Inject(context.Background(), identity.Identity{ClientId: "bob"})
+	carol := identity.Inject(context.Background(), identity.Identity{ClientId: "carol"})
+
+	m := newManager(t.TempDir())
+	m.maxWebhooks, m.maxPerClient = 3, 2
+	for i := 0; i < 2; i++ {
+		_, err := m.create(alice, Webhook{URL: "http://127.0.0.1/hooks"})
+		a.NoError(err)
+	}
+	_, err := m.create(alice, Webhook{URL: "http://127.0.0.1/hooks"})
+	a.Equal(http.StatusBadRequest, statusOf(err), "clients cannot exceed their own limit")
+	_, err = m.create(bob, Webhook{URL: "http://127.0.0.1/hooks"})
+	a.NoError(err, "the limit of a client does not depend on other clients")
+	_, err = m.create(carol, Webhook{URL: "http://127.0.0.1/hooks"})
+	a.Equal(http.StatusBadRequest, statusOf(err), "the instance wide limit still applies")
+}
+
+func TestStaticWebhooks(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "static.json")
+
+	m := newManager(dir)
+	m.staticFile = file
+	a.Error(m.load(logger.New()), "the webhooks file must exist")
+
+	a.NoError(os.WriteFile(file, []byte(`[{"id": "alerting", "url": "http://localhost/hooks"}]`), 0o600))
+	a.Error(m.load(logger.New()), "static webhooks need a secret")
+
+	a.NoError(os.WriteFile(file, []byte(`[{"id": "alerting", "url": "http://localhost/hooks", "secret": "s", "createdBy": "ops"}]`), 0o600))
+	if a.NoError(m.load(logger.New())) {
+		ops := identity.Inject(ctx, identity.Identity{ClientId: "ops"})
+		webhook, err := m.get(ops, "alerting")
+		a.NoError(err)
+		a.True(webhook.Static)
+		a.Equal(http.StatusConflict, statusOf(m.delete(ops, "alerting")))
+		_, err = m.get(ctx, "alerting")
+		a.Equal(http.StatusNotFound, statusOf(err), "static webhooks belong to their createdBy client")
+	}
+}
+
+func TestTargetPolicy(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	p := newTargetPolicy(nil)
+	for _, host := range []string{"127.0.0.1", "::1", "10.1.2.3", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::ffff:127.0.0.1", "localhost"} {
+		a.ErrorIs(p.check(ctx, host), errForbiddenTarget, host)
+	}
+	a.NoError(p.check(ctx, "93.184.216.34"))
+
+	p = newTargetPolicy([]string{"10.1.0.0/16", "169.254.169.254", "LocalHost"})
+	a.NoError(p.check(ctx, "10.1.2.3"))
+	a.ErrorIs(p.check(ctx, "10.2.0.1"), errForbiddenTarget)
+	a.NoError(p.check(ctx, "169.254.169.254"))
+	a.NoError(p.check(ctx, "localhost"))
+
+	// addresses are checked again when delivering, whatever the webhook passed when it was registered
+	receiver := newReceiver(t, "receiver-secret-0123456789")
+	_, err := newTargetPolicy(nil).dialContext(ctx, "tcp", receiver.Listener.Addr().String())
+	a.ErrorIs(err, errForbiddenTarget)
+	conn, err := newTargetPolicy([]string{"127.0.0.0/8"}).dialContext(ctx, "tcp", receiver.Listener.Addr().String())
+	if a.NoError(err) {
+		a.NoError(conn.Close())
+	}
+
+	m := newManager(t.TempDir())
+	_, err = m.create(ctx, Webhook{URL: receiver.URL})
+	a.Equal(http.StatusBadRequest, statusOf(err), "webhooks cannot target the network of the server")
+	code, err := m.post(ctx, Webhook{URL: receiver.URL, Secret: "receiver-secret-0123456789"}, &delivery{payload: []byte("{}")})
+	a.Zero(code)
+	a.ErrorIs(err, errForbiddenTarget)
+}